@@ -0,0 +1,212 @@
+// diff compare semantics, ttl, rdata normalize, ignore and view mapping
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itoolkits/toolkit/collect"
+	"github.com/itoolkits/toolkit/dnt"
+	"github.com/itoolkits/toolkit/ipt"
+)
+
+// noneView view name of records without view, e.g. zone file without @VIEW
+const noneView = "None"
+
+// domainFields index of domain name fields in rdata by type, -1 last field
+// only domain name fields compared case insensitive, e.g. CAA value kept
+var domainFields = map[string][]int{
+	dnt.TypeNS:    {0},
+	dnt.TypeCNAME: {0},
+	dnt.TypePTR:   {0},
+	"DNAME":       {0},
+	dnt.TypeSOA:   {0, 1},
+	dnt.TypeMX:    {1},
+	"SRV":         {3},
+	"NAPTR":       {-1},
+	"AFSDB":       {1},
+	"RP":          {0, 1},
+	"KX":          {1},
+	"CAA":         {1}, // tag
+}
+
+type CompareOption struct {
+	TTL       bool // compare ttl
+	Normalize bool // normalize rdata, case, trailing dot, txt quoting, ipv6
+
+	IgnoreZones *collect.HashSet[string]
+	IgnoreViews *collect.HashSet[string]
+	IgnoreTypes *collect.HashSet[string]
+
+	ViewMap map[string]string // b view name -> a view name
+}
+
+// NewCompareOption create default compare option
+func NewCompareOption() *CompareOption {
+	return &CompareOption{
+		IgnoreZones: collect.NewHashSetAllowNilVal[string](),
+		IgnoreViews: collect.NewHashSetAllowNilVal[string](),
+		IgnoreTypes: collect.NewHashSetAllowNilVal[string](),
+		ViewMap:     make(map[string]string),
+	}
+}
+
+// SetIgnoreZones set ignore zones, comma separated
+func (o *CompareOption) SetIgnoreZones(s string) {
+	for _, z := range splitList(s) {
+		o.IgnoreZones.Add(dnt.FixDomain(z))
+	}
+}
+
+// SetIgnoreViews set ignore views, comma separated
+func (o *CompareOption) SetIgnoreViews(s string) {
+	o.IgnoreViews.Add(splitList(s)...)
+}
+
+// SetIgnoreTypes set ignore types, comma separated
+func (o *CompareOption) SetIgnoreTypes(s string) {
+	for _, t := range splitList(s) {
+		o.IgnoreTypes.Add(strings.ToUpper(t))
+	}
+}
+
+// SetViewMap set view map, format: a1=b1,a2=b2
+func (o *CompareOption) SetViewMap(s string) error {
+	for _, kv := range splitList(s) {
+		seg := strings.SplitN(kv, "=", 2)
+		if len(seg) != 2 || seg[0] == "" || seg[1] == "" {
+			return fmt.Errorf("view map format error, %s", kv)
+		}
+		o.ViewMap[seg[1]] = seg[0]
+	}
+	return nil
+}
+
+// Apply filter ignored zone/view/type, rename b side views when mapB
+func (o *CompareOption) Apply(zvrList map[string]map[string][]*dnt.RR, mapB bool) map[string]map[string][]*dnt.RR {
+	rst := make(map[string]map[string][]*dnt.RR, len(zvrList))
+	for z, vrList := range zvrList {
+		vrSet := make(map[string][]*dnt.RR, len(vrList))
+		for v, rList := range vrList {
+			for _, rr := range rList {
+				mv, ok := o.Accept(z, v, rr, mapB)
+				if !ok {
+					continue
+				}
+				vrSet[mv] = append(vrSet[mv], rr)
+			}
+		}
+		if len(vrSet) > 0 {
+			rst[z] = vrSet
+		}
+	}
+	return rst
+}
+
+// Accept check record not ignored, return view name after blank view normalized and b side mapping
+func (o *CompareOption) Accept(zone, view string, rr *dnt.RR, mapB bool) (string, bool) {
+	if view == "" {
+		view = noneView
+	}
+	if o.IgnoreZones.Contains(dnt.FixDomain(zone)) {
+		return view, false
+	}
+	if mapB {
+		if av, h := o.ViewMap[view]; h {
+			view = av
+		}
+	}
+	if o.IgnoreViews.Contains(view) {
+		return view, false
+	}
+	if o.IgnoreTypes.Contains(strings.ToUpper(rr.RType)) {
+		return view, false
+	}
+	return view, true
+}
+
+// Record2Str record to compare string
+func (o *CompareOption) Record2Str(r *dnt.RR) string {
+	if !o.Normalize && !o.TTL {
+		return Record2Str(r)
+	}
+	domain, rType, rData := r.Domain, r.RType, r.RData
+	if o.Normalize {
+		domain = dnt.FixDomain(domain)
+		rType = strings.ToUpper(rType)
+		rData = NormalizeRData(rType, rData)
+	}
+	if o.TTL {
+		return fmt.Sprintf("%s %d %s %s", domain, r.TTL, rType, rData)
+	}
+	return fmt.Sprintf("%s %s %s", domain, rType, rData)
+}
+
+// NormalizeRData normalize rdata, whitespace, txt quoting, ipv6
+// case and trailing dot ignored of domain name fields only
+func NormalizeRData(rType, rData string) string {
+	switch rType {
+	case dnt.TypeTXT:
+		return strings.Join(txtSegments(rData), "")
+	case dnt.TypeAAAA:
+		if ip := ipt.IPFullFmt(strings.TrimSpace(rData)); ip != "" {
+			return ip
+		}
+	}
+	seg := strings.Fields(rData)
+	for _, idx := range domainFields[rType] {
+		if idx < 0 {
+			idx += len(seg)
+		}
+		if idx < 0 || idx >= len(seg) {
+			continue
+		}
+		seg[idx] = strings.TrimSuffix(strings.ToLower(seg[idx]), ".")
+	}
+	return strings.Join(seg, " ")
+}
+
+// txtSegments split txt rdata, support quoted strings and line separated
+func txtSegments(rData string) []string {
+	if !strings.Contains(rData, "\"") {
+		return strings.Split(rData, "\n")
+	}
+	rst := make([]string, 0)
+	var sb strings.Builder
+	quoted := false
+	escaped := false
+	for _, c := range rData {
+		switch {
+		case escaped:
+			sb.WriteRune(c)
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			if quoted {
+				rst = append(rst, sb.String())
+				sb.Reset()
+			}
+			quoted = !quoted
+		case quoted:
+			sb.WriteRune(c)
+		}
+	}
+	if quoted {
+		rst = append(rst, sb.String())
+	}
+	return rst
+}
+
+// splitList split comma separated list
+func splitList(s string) []string {
+	rst := make([]string, 0)
+	for _, ele := range strings.Split(s, ",") {
+		ele = strings.TrimSpace(ele)
+		if ele != "" {
+			rst = append(rst, ele)
+		}
+	}
+	return rst
+}
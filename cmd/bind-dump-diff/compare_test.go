@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/itoolkits/toolkit/dnt"
+)
+
+func TestCompareIgnoreNoneView(t *testing.T) {
+	opt := NewCompareOption()
+	opt.SetIgnoreViews(noneView)
+	rr := &dnt.RR{Domain: "www.example.com.", RType: dnt.TypeA, RData: "192.0.2.1"}
+	if view, ok := opt.Accept("example.com.", "", rr, false); ok || view != noneView {
+		t.Fatalf("blank view should be ignored as %s, %s %v", noneView, view, ok)
+	}
+	if _, ok := opt.Accept("example.com.", "v1", rr, false); !ok {
+		t.Fatal("view v1 should be accepted")
+	}
+}
+
+func TestNormalizeRData(t *testing.T) {
+	cases := []struct {
+		rType, rData, want string
+	}{
+		{dnt.TypeCNAME, "WWW.Example.COM.", "www.example.com"},
+		{dnt.TypeMX, "10  Mail.Example.com.", "10 mail.example.com"},
+		{"SRV", "0 5 5060 SIP.Example.com.", "0 5 5060 sip.example.com"},
+		{"CAA", `0 ISSUE "LetsEncrypt.org"`, `0 issue "LetsEncrypt.org"`},
+		{dnt.TypeTXT, `"Hello" "World"`, "HelloWorld"},
+		{dnt.TypeAAAA, "2001:DB8::1", "2001:0db8:0000:0000:0000:0000:0000:0001"},
+	}
+	for _, c := range cases {
+		if got := NormalizeRData(c.rType, c.rData); got != c.want {
+			t.Errorf("normalize %s %q = %q, want %q", c.rType, c.rData, got, c.want)
+		}
+	}
+}
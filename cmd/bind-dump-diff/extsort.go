@@ -0,0 +1,331 @@
+// external sort, sort chunk in memory and spill to temp run files, k-way merge runs
+// runs over max fan-in merged in passes, open files bounded by fan-in
+// run file entry: uvarint length + key bytes
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"slices"
+)
+
+const (
+	defaultChunkSize = 1000000
+	defaultFanIn     = 64
+	runFilePattern   = "bind-dump-diff-*.run"
+)
+
+type ExtSorter struct {
+	dir       string
+	chunkSize int
+	fanIn     int // max runs merged at once
+
+	buf  []string
+	runs []string
+
+	num int
+}
+
+// NewExtSorter create external sorter, dir is temp dir, chunkSize max keys in memory
+func NewExtSorter(dir string, chunkSize int) *ExtSorter {
+	if chunkSize < 1 {
+		chunkSize = defaultChunkSize
+	}
+	return &ExtSorter{
+		dir:       dir,
+		chunkSize: chunkSize,
+		fanIn:     defaultFanIn,
+		buf:       make([]string, 0, min(chunkSize, defaultChunkSize)),
+	}
+}
+
+// Add add key, spill to run file when chunk full
+func (s *ExtSorter) Add(key string) error {
+	s.buf = append(s.buf, key)
+	s.num++
+	if len(s.buf) >= s.chunkSize {
+		return s.spill()
+	}
+	return nil
+}
+
+// Num added key number
+func (s *ExtSorter) Num() int {
+	return s.num
+}
+
+// Runs spilled run file number
+func (s *ExtSorter) Runs() int {
+	return len(s.runs)
+}
+
+// spill sort chunk and write to run file
+func (s *ExtSorter) spill() error {
+	if len(s.buf) < 1 {
+		return nil
+	}
+	slices.Sort(s.buf)
+	s.buf = slices.Compact(s.buf)
+
+	path, err := s.writeRun(&sliceIterator{keys: s.buf})
+	if path != "" {
+		s.runs = append(s.runs, path)
+	}
+
+	clear(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// writeRun write keys of iterator to new run file, path returned when file created
+func (s *ExtSorter) writeRun(it KeyIterator) (string, error) {
+	f, err := os.CreateTemp(s.dir, runFilePattern)
+	if err != nil {
+		return "", err
+	}
+
+	w := bufio.NewWriter(f)
+	lb := make([]byte, binary.MaxVarintLen64)
+	for {
+		key, ok, nerr := it.Next()
+		if nerr != nil || !ok {
+			err = nerr
+			break
+		}
+		n := binary.PutUvarint(lb, uint64(len(key)))
+		if _, err = w.Write(lb[:n]); err != nil {
+			break
+		}
+		if _, err = w.WriteString(key); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return f.Name(), err
+}
+
+// Iterator sorted and distinct key iterator, only in memory when no chunk spilled
+func (s *ExtSorter) Iterator() (KeyIterator, error) {
+	if len(s.runs) < 1 {
+		slices.Sort(s.buf)
+		s.buf = slices.Compact(s.buf)
+		return &sliceIterator{keys: s.buf}, nil
+	}
+	if err := s.spill(); err != nil {
+		return nil, err
+	}
+	for len(s.runs) > max(s.fanIn, 2) {
+		if err := s.mergePass(); err != nil {
+			return nil, err
+		}
+	}
+	return openMerge(s.runs)
+}
+
+// mergePass merge runs by group of fan-in into fewer runs, merged runs removed
+func (s *ExtSorter) mergePass() error {
+	fanIn := max(s.fanIn, 2)
+	merged := make([]string, 0, len(s.runs)/fanIn+1)
+	for i := 0; i < len(s.runs); i += fanIn {
+		group := s.runs[i:min(i+fanIn, len(s.runs))]
+		if len(group) == 1 {
+			merged = append(merged, group[0])
+			continue
+		}
+		path, err := s.mergeRuns(group)
+		if path != "" {
+			merged = append(merged, path)
+		}
+		if err != nil {
+			// keep not removed runs for Close
+			s.runs = append(merged, s.runs[i:]...)
+			return err
+		}
+		for j, p := range group {
+			if err = os.Remove(p); err != nil {
+				s.runs = append(merged, s.runs[i+j:]...)
+				return err
+			}
+		}
+	}
+	s.runs = merged
+	return nil
+}
+
+// mergeRuns merge runs into one run file
+func (s *ExtSorter) mergeRuns(paths []string) (string, error) {
+	it, err := openMerge(paths)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	return s.writeRun(it)
+}
+
+// openMerge k-way merge iterator of run files, all files opened
+func openMerge(paths []string) (KeyIterator, error) {
+	mh := &mergeHeap{}
+	for _, path := range paths {
+		r, err := openRunReader(path)
+		if err != nil {
+			mh.close()
+			return nil, err
+		}
+		ok, err := r.next()
+		if err != nil {
+			_ = r.close()
+			mh.close()
+			return nil, err
+		}
+		if !ok {
+			_ = r.close()
+			continue
+		}
+		*mh = append(*mh, r)
+	}
+	heap.Init(mh)
+	return &mergeIterator{h: mh}, nil
+}
+
+// Close remove run files
+func (s *ExtSorter) Close() error {
+	var err error
+	for _, path := range s.runs {
+		err = errors.Join(err, os.Remove(path))
+	}
+	s.runs = nil
+	s.buf = nil
+	return err
+}
+
+// KeyIterator sorted key iterator
+type KeyIterator interface {
+	// Next return next key, false when end
+	Next() (string, bool, error)
+	Close() error
+}
+
+type sliceIterator struct {
+	keys []string
+	idx  int
+}
+
+// Next implements KeyIterator
+func (it *sliceIterator) Next() (string, bool, error) {
+	if it.idx >= len(it.keys) {
+		return "", false, nil
+	}
+	it.idx++
+	return it.keys[it.idx-1], true, nil
+}
+
+// Close implements KeyIterator
+func (it *sliceIterator) Close() error {
+	return nil
+}
+
+type mergeIterator struct {
+	h    *mergeHeap
+	last string
+	has  bool
+}
+
+// Next implements KeyIterator, skip duplicate keys between runs
+func (it *mergeIterator) Next() (string, bool, error) {
+	for it.h.Len() > 0 {
+		r := (*it.h)[0]
+		key := r.key
+		ok, err := r.next()
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			heap.Fix(it.h, 0)
+		} else {
+			heap.Pop(it.h)
+			_ = r.close()
+		}
+		if it.has && key == it.last {
+			continue
+		}
+		it.last = key
+		it.has = true
+		return key, true, nil
+	}
+	return "", false, nil
+}
+
+// Close implements KeyIterator
+func (it *mergeIterator) Close() error {
+	it.h.close()
+	return nil
+}
+
+type runReader struct {
+	f   *os.File
+	r   *bufio.Reader
+	key string
+}
+
+// openRunReader open run file
+func openRunReader(path string) (*runReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &runReader{f: f, r: bufio.NewReader(f)}, nil
+}
+
+// next read next key, false when end
+func (r *runReader) next() (bool, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	b := make([]byte, n)
+	if _, err = io.ReadFull(r.r, b); err != nil {
+		return false, err
+	}
+	r.key = string(b)
+	return true, nil
+}
+
+// close close run file
+func (r *runReader) close() error {
+	return r.f.Close()
+}
+
+type mergeHeap []*runReader
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)        { *h = append(*h, x.(*runReader)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// close close all run readers
+func (h *mergeHeap) close() {
+	for _, r := range *h {
+		_ = r.close()
+	}
+	*h = (*h)[:0]
+}
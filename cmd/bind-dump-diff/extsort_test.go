@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestExtSorterMergePasses(t *testing.T) {
+	dir := t.TempDir()
+	s := NewExtSorter(dir, 10)
+	s.fanIn = 3
+
+	rnd := rand.New(rand.NewSource(1))
+	want := make([]string, 0)
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(rnd.Intn(300))
+		want = append(want, key)
+		if err := s.Add(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	slices.Sort(want)
+	want = slices.Compact(want)
+
+	it, err := s.Iterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Runs() > s.fanIn {
+		t.Fatalf("runs %d over fan in %d after merge passes", s.Runs(), s.fanIn)
+	}
+	got := make([]string, 0, len(want))
+	for {
+		key, ok, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, key)
+	}
+	_ = it.Close()
+	if !slices.Equal(got, want) {
+		t.Fatalf("sorted keys differ, got %d keys, want %d", len(got), len(want))
+	}
+
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := filepath.Glob(filepath.Join(dir, runFilePattern))
+	if len(runs) != 0 {
+		t.Fatalf("run files left, %v", runs)
+	}
+}
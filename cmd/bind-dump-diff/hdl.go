@@ -4,20 +4,31 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"sort"
-	"strings"
-
-	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/jedib0t/go-pretty/v6/text"
 
 	"github.com/itoolkits/toolkit/collect"
 	"github.com/itoolkits/toolkit/dnt"
 )
 
+const (
+	scopeZone   = "zone"
+	scopeView   = "view"
+	scopeRecord = "record"
+
+	sideA = "a"
+	sideB = "b"
+)
+
 type ZoneDiff struct {
-	A []string
-	B []string
+	Zone      string   `json:"zone"`
+	View      string   `json:"view,omitempty"`
+	Scope     string   `json:"scope"`                // zone, view or record
+	MissingIn string   `json:"missing_in,omitempty"` // side which zone or view not exist
+	Added     []string `json:"added"`                // records only in B
+	Removed   []string `json:"removed"`              // records only in A
+
+	A []string `json:"-"`
+	B []string `json:"-"`
 }
 
 type DiffHandler struct {
@@ -37,15 +48,21 @@ type DiffHandler struct {
 	diffMap map[string]*ZoneDiff
 
 	diffRNum int
+
+	opt *CompareOption
 }
 
 // NewDiffHandler create diff handler
-func NewDiffHandler(a, b map[string]map[string][]*dnt.RR) *DiffHandler {
+func NewDiffHandler(a, b map[string]map[string][]*dnt.RR, opt *CompareOption) *DiffHandler {
+	if opt == nil {
+		opt = NewCompareOption()
+	}
 	h := &DiffHandler{
-		a:  a,
-		b:  b,
-		as: make(map[string]map[string]*collect.HashSet[string]),
-		bs: make(map[string]map[string]*collect.HashSet[string]),
+		a:   opt.Apply(a, false),
+		b:   opt.Apply(b, true),
+		opt: opt,
+		as:  make(map[string]map[string]*collect.HashSet[string]),
+		bs:  make(map[string]map[string]*collect.HashSet[string]),
 
 		diffSet: collect.NewHashSetAllowNilVal[string](),
 		diffMap: make(map[string]*ZoneDiff),
@@ -79,14 +96,14 @@ func (h *DiffHandler) convertSet(
 		h.zSet.Add(z)
 		for v, rList := range vrList {
 			if v == "" {
-				v = "None"
+				v = noneView
 			}
 
 			h.vSet.Add(v)
 
 			rSet := collect.NewHashSet[string]()
 			for _, rr := range rList {
-				rSet.Add(Record2Str(rr))
+				rSet.Add(h.opt.Record2Str(rr))
 				num++
 			}
 			vrSet[v] = rSet
@@ -152,8 +169,13 @@ func (h *DiffHandler) collectDiff() {
 			k := fmt.Sprintf("Zone: %s", z)
 			h.diffSet.Add(k)
 			h.diffMap[k] = &ZoneDiff{
-				A: []string{fmt.Sprintf("Has %d Records", num)},
-				B: []string{"Zone Not Exist"},
+				Zone:      z,
+				Scope:     scopeZone,
+				MissingIn: sideB,
+				Added:     []string{},
+				Removed:   sortedRecords(avrSet),
+				A:         []string{fmt.Sprintf("Has %d Records", num)},
+				B:         []string{"Zone Not Exist"},
 			}
 
 			h.diffRNum += num
@@ -167,8 +189,13 @@ func (h *DiffHandler) collectDiff() {
 			k := fmt.Sprintf("Zone: %s", z)
 			h.diffSet.Add(k)
 			h.diffMap[k] = &ZoneDiff{
-				A: []string{"Zone Not Exist"},
-				B: []string{fmt.Sprintf("Has %d Records", num)},
+				Zone:      z,
+				Scope:     scopeZone,
+				MissingIn: sideA,
+				Added:     sortedRecords(bvrSet),
+				Removed:   []string{},
+				A:         []string{"Zone Not Exist"},
+				B:         []string{fmt.Sprintf("Has %d Records", num)},
 			}
 			h.diffRNum += num
 			return true
@@ -190,8 +217,14 @@ func (h *DiffHandler) collectDiff() {
 				k := fmt.Sprintf("Zone: %s, View: %s", z, v)
 				h.diffSet.Add(k)
 				h.diffMap[k] = &ZoneDiff{
-					A: []string{fmt.Sprintf("Has %d Records", arSet.Size())},
-					B: []string{"View Not Exist"},
+					Zone:      z,
+					View:      v,
+					Scope:     scopeView,
+					MissingIn: sideB,
+					Added:     []string{},
+					Removed:   sortedSlice(arSet),
+					A:         []string{fmt.Sprintf("Has %d Records", arSet.Size())},
+					B:         []string{"View Not Exist"},
 				}
 				h.diffRNum += arSet.Size()
 				return true
@@ -200,22 +233,31 @@ func (h *DiffHandler) collectDiff() {
 				k := fmt.Sprintf("Zone: %s, View: %s", z, v)
 				h.diffSet.Add(k)
 				h.diffMap[k] = &ZoneDiff{
-					A: []string{"View Not Exist"},
-					B: []string{fmt.Sprintf("Has %d Records", brSet.Size())},
+					Zone:      z,
+					View:      v,
+					Scope:     scopeView,
+					MissingIn: sideA,
+					Added:     sortedSlice(brSet),
+					Removed:   []string{},
+					A:         []string{"View Not Exist"},
+					B:         []string{fmt.Sprintf("Has %d Records", brSet.Size())},
 				}
 				h.diffRNum += brSet.Size()
 				return true
 			}
-			arList := arSet.ToSlice()
-			brList := brSet.ToSlice()
-			sort.Strings(arList)
-			sort.Strings(brList)
+			arList := sortedSlice(arSet)
+			brList := sortedSlice(brSet)
 
 			k := fmt.Sprintf("Zone: %s, View: %s", z, v)
 			h.diffSet.Add(k)
 			h.diffMap[k] = &ZoneDiff{
-				A: arList,
-				B: brList,
+				Zone:    z,
+				View:    v,
+				Scope:   scopeRecord,
+				Added:   brList,
+				Removed: arList,
+				A:       arList,
+				B:       brList,
 			}
 			h.diffRNum += len(arList) + len(brList)
 			return true
@@ -225,37 +267,44 @@ func (h *DiffHandler) collectDiff() {
 	})
 }
 
-// PrintDiff print diff
-func (h *DiffHandler) PrintDiff(a, b string) {
-	rows := make([]table.Row, 0)
-
+// Report build diff report, diff list ordered by zone & view
+func (h *DiffHandler) Report(a, b string) *DiffReport {
+	rpt := &DiffReport{
+		A:     a,
+		B:     b,
+		Diffs: make([]*ZoneDiff, 0, h.diffSet.Size()),
+	}
 	zvList := h.diffSet.ToSlice()
 	sort.Strings(zvList)
-	for i, zv := range zvList {
-		diffList := h.diffMap[zv]
-		rows = append(rows, table.Row{i + 1, zv,
-			strings.Join(diffList.A, "\n"), strings.Join(diffList.B, "\n")})
-		rows = append(rows, table.Row{"", "", "", ""})
+	for _, zv := range zvList {
+		zd := h.diffMap[zv]
+		rpt.Summary.Added += len(zd.Added)
+		rpt.Summary.Removed += len(zd.Removed)
+		rpt.Diffs = append(rpt.Diffs, zd)
 	}
+	rpt.Summary.ZoneDiff = h.diffSet.Size()
+	rpt.Summary.RecordDiff = h.diffRNum
+	rpt.Summary.ANum = h.aNum
+	rpt.Summary.BNum = h.bNum
+	rpt.Identical = h.diffSet.Size() == 0
+	return rpt
+}
 
-	tbl := table.NewWriter()
-	tbl.SetOutputMirror(os.Stdout)
-	tbl.AppendHeader(table.Row{"#", "Zone & View", a, b})
-	tbl.AppendRows(rows)
-	tbl.AppendSeparator()
-	tbl.SetStyle(table.StyleDefault)
-	tbl.SetColumnConfigs([]table.ColumnConfig{
-		//{Name: "#", Colors: text.Colors{text.FgHiYellow}, ColorsHeader: text.Colors{text.BgWhite, text.FgHiYellow}},
-		{Name: "Zone & View", Colors: text.Colors{text.FgHiMagenta}, ColorsHeader: text.Colors{text.FgHiMagenta}},
-		{Name: a, Colors: text.Colors{text.FgHiYellow}, ColorsHeader: text.Colors{text.FgHiYellow}},
-		{Name: b, Colors: text.Colors{text.FgHiCyan}, ColorsHeader: text.Colors{text.FgHiCyan}},
-	})
-	tbl.AppendFooter(table.Row{"SUMMARY",
-		fmt.Sprintf("ZONE DIF:%d, RECORD DIFF:%d", h.diffSet.Size(), h.diffRNum),
-		fmt.Sprintf("CHECK NU:%d", h.aNum),
-		fmt.Sprintf("CHECK NU:%d", h.bNum)})
-	tbl.Render()
-	fmt.Println()
+// sortedSlice set to sorted slice
+func sortedSlice(s *collect.HashSet[string]) []string {
+	rst := s.ToSlice()
+	sort.Strings(rst)
+	return rst
+}
+
+// sortedRecords all view records to sorted slice
+func sortedRecords(vrSet map[string]*collect.HashSet[string]) []string {
+	rst := make([]string, 0)
+	for _, rSet := range vrSet {
+		rst = append(rst, rSet.ToSlice()...)
+	}
+	sort.Strings(rst)
+	return rst
 }
 
 // Record2Str record to string
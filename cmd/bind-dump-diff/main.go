@@ -1,37 +1,201 @@
 // bind dump db diff
 // ./rndc dumpdb -zones
-// print diff table, use go-pretty
+// print diff table, use go-pretty, or stream differences with sort engine for large input
+// each side is a dumpdb file, zone file, zone file dir or live axfr, see source.go
+// exit code: 0 - identical, 1 - different, 2 - error
 
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+)
 
-	"github.com/itoolkits/toolkit/dnt"
+const (
+	exitIdentical = 0
+	exitDifferent = 1
+	exitError     = 2
 )
 
 // process main function
 func main() {
-	args := os.Args[1:]
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	format := fs.String("format", "", "output format, table|json|ndjson|csv|markdown|html, default table, ndjson of sort engine")
+	ttl := fs.Bool("ttl", false, "compare record ttl")
+	normalize := fs.Bool("normalize", false, "normalize rdata, case, trailing dot, txt quoting, ipv6")
+	ignoreZones := fs.String("ignore-zones", "", "ignore zones, comma separated")
+	ignoreViews := fs.String("ignore-views", "", "ignore views, comma separated")
+	ignoreTypes := fs.String("ignore-types", "", "ignore record types, comma separated, e.g. SOA,RRSIG,NSEC")
+	viewMap := fs.String("view-map", "", "map view names between sides, format: a1=b1,a2=b2")
+	tsigA := fs.String("tsig-a", "", "tsig key of side a axfr, format: [algo:]name:secret")
+	tsigB := fs.String("tsig-b", "", "tsig key of side b axfr, format: [algo:]name:secret")
+	engine := fs.String("engine", engineMemory, "diff engine, memory|sort, sort engine external sort large input and stream differences, support ndjson|csv|markdown|html format")
+	tmpDir := fs.String("tmp-dir", "", "sort engine temp dir, default os temp dir")
+	chunkSize := fs.Int("chunk-size", defaultChunkSize, "sort engine max records in memory per side")
+	progress := fs.Bool("progress", false, "sort engine report progress to stderr")
+	nway := fs.Bool("nway", false, "n-way diff, default when more than 2 sources, memory engine only, tsig-a for baseline, tsig-b for others without -tsig")
+	tsigs := make(map[int]string)
+	fs.Func("tsig", "n-way diff tsig key of source by index, repeatable, format: INDEX=[algo:]name:secret", func(s string) error {
+		idx, key, h := strings.Cut(s, "=")
+		i, err := strconv.Atoi(idx)
+		if !h || err != nil || i < 0 || key == "" {
+			return fmt.Errorf("tsig format error, INDEX=[algo:]name:secret")
+		}
+		tsigs[i] = key
+		return nil
+	})
+	baseline := fs.Int("baseline", 0, "n-way diff baseline source index, start from 0")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] <source a> <source b> [source ...]\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "source: dumpdb:PATH | zone:[ZONE=]PATH[@VIEW] | dir:PATH[@VIEW] | axfr:SERVER[:PORT]/ZONE[,ZONE...][@VIEW] | PATH\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		os.Exit(exitError)
+	}
+	args := fs.Args()
+
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "at least 2 sources\n")
+		os.Exit(exitError)
+	}
+
+	if *format == "" {
+		*format = formatTable
+		if *engine == engineSort {
+			*format = formatNDJSON
+		}
+	}
+
+	opt := NewCompareOption()
+	opt.TTL = *ttl
+	opt.Normalize = *normalize
+	opt.SetIgnoreZones(*ignoreZones)
+	opt.SetIgnoreViews(*ignoreViews)
+	opt.SetIgnoreTypes(*ignoreTypes)
+	if err := opt.SetViewMap(*viewMap); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	if *nway || len(args) > 2 {
+		if *engine != engineMemory {
+			fmt.Fprintf(os.Stderr, "n-way diff support memory engine only, %s\n", *engine)
+			os.Exit(exitError)
+		}
+		identical, err := nwayDiff(args, *baseline, nwayTSIGs(len(args), *baseline, tsigs, *tsigA, *tsigB), opt, *format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diff error, %v\n", err)
+			os.Exit(exitError)
+		}
+		if !identical {
+			os.Exit(exitDifferent)
+		}
+		os.Exit(exitIdentical)
+	}
+
+	srcA, err := ParseSource(args[0], *tsigA)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse source error,%s %v\n", args[0], err)
+		os.Exit(exitError)
+	}
+	srcB, err := ParseSource(args[1], *tsigB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse source error,%s %v\n", args[1], err)
+		os.Exit(exitError)
+	}
 
-	if len(args) != 2 {
-		fmt.Printf("only support 2 args")
-		os.Exit(1)
+	var rpt *DiffReport
+	switch *engine {
+	case engineMemory:
+		rpt, err = memoryDiff(srcA, srcB, opt, *format)
+	case engineSort:
+		e := &SortDiffEngine{
+			Opt:       opt,
+			TmpDir:    *tmpDir,
+			ChunkSize: *chunkSize,
+		}
+		if *progress {
+			e.Progress = os.Stderr
+		}
+		var out DiffStream
+		out, err = NewDiffStream(os.Stdout, *format)
+		if err == nil {
+			rpt, err = e.Diff(srcA, srcB, out)
+		}
+	default:
+		err = fmt.Errorf("engine not support, %s", *engine)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff error, %v\n", err)
+		os.Exit(exitError)
+	}
+	if !rpt.Identical {
+		os.Exit(exitDifferent)
 	}
+	os.Exit(exitIdentical)
+}
 
-	A, err := dnt.ParseDumpDB(args[0])
+// memoryDiff load both sides into memory and diff, support table output
+func memoryDiff(srcA, srcB *Source, opt *CompareOption, format string) (*DiffReport, error) {
+	A, err := srcA.Load()
 	if err != nil {
-		fmt.Printf("parse dumpdb error,%s %v", args[0], err)
-		os.Exit(1)
+		return nil, fmt.Errorf("load source error,%s %w", srcA.Spec, err)
 	}
-	B, err := dnt.ParseDumpDB(args[1])
+	B, err := srcB.Load()
 	if err != nil {
-		fmt.Printf("parse dumpdb error,%s %v", args[1], err)
-		os.Exit(1)
+		return nil, fmt.Errorf("load source error,%s %w", srcB.Spec, err)
 	}
 
-	handler := NewDiffHandler(A, B)
+	handler := NewDiffHandler(A, B, opt)
 	handler.Start()
-	handler.PrintDiff(args[0], args[1])
+
+	rpt := handler.Report(srcA.Spec, srcB.Spec)
+	if err := rpt.Write(os.Stdout, format); err != nil {
+		return nil, fmt.Errorf("write diff report error, %w", err)
+	}
+	return rpt, nil
+}
+
+// nwayTSIGs tsig key of each source, -tsig of index, otherwise tsig-a for baseline, tsig-b for others
+func nwayTSIGs(n, baseline int, tsigs map[int]string, tsigBase, tsigOther string) []string {
+	rst := make([]string, n)
+	for i := range rst {
+		if key, h := tsigs[i]; h {
+			rst[i] = key
+			continue
+		}
+		rst[i] = tsigOther
+		if i == baseline {
+			rst[i] = tsigBase
+		}
+	}
+	return rst
+}
+
+// nwayDiff load all sources and diff against baseline, tsigs of each source
+func nwayDiff(specs []string, baseline int, tsigs []string, opt *CompareOption, format string) (bool, error) {
+	if baseline < 0 || baseline >= len(specs) {
+		return false, fmt.Errorf("baseline index out of range, %d", baseline)
+	}
+	h := NewNWayDiffHandler(specs, baseline, opt)
+	for i, spec := range specs {
+		src, err := ParseSource(spec, tsigs[i])
+		if err != nil {
+			return false, fmt.Errorf("parse source error,%s %w", spec, err)
+		}
+		zvrList, err := src.Load()
+		if err != nil {
+			return false, fmt.Errorf("load source error,%s %w", spec, err)
+		}
+		h.Add(i, zvrList)
+	}
+	rpt := h.Report()
+	if err := rpt.Write(os.Stdout, format); err != nil {
+		return false, fmt.Errorf("write diff report error, %w", err)
+	}
+	return rpt.Identical, nil
 }
@@ -0,0 +1,252 @@
+// n-way diff, one baseline input and many others, e.g. primary and secondaries
+// report which inputs have or lack each record not present on all inputs
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+
+	"github.com/itoolkits/toolkit/collect"
+	"github.com/itoolkits/toolkit/dnt"
+)
+
+const (
+	cellHas  = "✓"
+	cellLack = "✗"
+)
+
+type NWayRecord struct {
+	Zone   string `json:"zone"`
+	View   string `json:"view"`
+	Record string `json:"record"`
+
+	InBaseline       bool     `json:"in_baseline"`
+	MajorityDisagree bool     `json:"majority_disagree"` // most non baseline inputs disagree with baseline
+	Has              []string `json:"has"`
+	Lack             []string `json:"lack"`
+
+	present []bool
+}
+
+type NWayInputSummary struct {
+	Input   string `json:"input"`
+	Num     int    `json:"num"`
+	Missing int    `json:"missing"` // in baseline, not in input
+	Extra   int    `json:"extra"`   // in input, not in baseline
+}
+
+type NWaySummary struct {
+	Records          int                 `json:"records"`
+	RecordDiff       int                 `json:"record_diff"`
+	MajorityDisagree int                 `json:"majority_disagree"`
+	Inputs           []*NWayInputSummary `json:"inputs"`
+}
+
+type NWayReport struct {
+	Baseline  string        `json:"baseline"`
+	BaseIndex int           `json:"baseline_index"`
+	Inputs    []string      `json:"inputs"`
+	Identical bool          `json:"identical"`
+	Summary   NWaySummary   `json:"summary"`
+	Records   []*NWayRecord `json:"records"`
+}
+
+type NWayDiffHandler struct {
+	opt *CompareOption
+
+	inputs   []string
+	baseline int
+
+	sets  []*collect.HashSet[string]
+	count *collect.HashMultiset[string]
+	nums  []int
+}
+
+// NewNWayDiffHandler create n-way diff handler, baseline is index of inputs
+func NewNWayDiffHandler(inputs []string, baseline int, opt *CompareOption) *NWayDiffHandler {
+	if opt == nil {
+		opt = NewCompareOption()
+	}
+	return &NWayDiffHandler{
+		opt:      opt,
+		inputs:   inputs,
+		baseline: baseline,
+		sets:     make([]*collect.HashSet[string], len(inputs)),
+		count:    collect.NewHashMultiset[string](),
+		nums:     make([]int, len(inputs)),
+	}
+}
+
+// Add add input records, non baseline input views mapped by view map, num of distinct records
+func (h *NWayDiffHandler) Add(idx int, zvrList map[string]map[string][]*dnt.RR) {
+	rSet := collect.NewHashSet[string]()
+	for z, vrList := range zvrList {
+		for v, rList := range vrList {
+			for _, rr := range rList {
+				mv, ok := h.opt.Accept(z, v, rr, idx != h.baseline)
+				if !ok {
+					continue
+				}
+				rSet.Add(z + keySep + mv + keySep + h.opt.Record2Str(rr))
+			}
+		}
+	}
+	h.sets[idx] = rSet
+	h.nums[idx] = rSet.Size()
+	h.count.AddHashSet(rSet)
+}
+
+// Report build n-way report, records ordered by zone, view and record
+func (h *NWayDiffHandler) Report() *NWayReport {
+	n := len(h.inputs)
+	rpt := &NWayReport{
+		Baseline:  h.inputs[h.baseline],
+		BaseIndex: h.baseline,
+		Inputs:    h.inputs,
+		Records:   make([]*NWayRecord, 0),
+	}
+	rpt.Summary.Records = h.count.Size()
+	for i, input := range h.inputs {
+		rpt.Summary.Inputs = append(rpt.Summary.Inputs, &NWayInputSummary{Input: input, Num: h.nums[i]})
+	}
+
+	keys := h.count.Filter(func(_ string, c int) bool {
+		return c < n
+	})
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		d := splitKey(key, "")
+		rec := &NWayRecord{
+			Zone:    d.Zone,
+			View:    d.View,
+			Record:  d.Record,
+			Has:     make([]string, 0),
+			Lack:    make([]string, 0),
+			present: make([]bool, n),
+		}
+		rec.InBaseline = h.sets[h.baseline].Contains(key)
+		disagree := 0
+		for i, set := range h.sets {
+			rec.present[i] = set.Contains(key)
+			if rec.present[i] {
+				rec.Has = append(rec.Has, h.inputs[i])
+			} else {
+				rec.Lack = append(rec.Lack, h.inputs[i])
+			}
+			if i == h.baseline || rec.present[i] == rec.InBaseline {
+				continue
+			}
+			disagree++
+			if rec.InBaseline {
+				rpt.Summary.Inputs[i].Missing++
+			} else {
+				rpt.Summary.Inputs[i].Extra++
+			}
+		}
+		rec.MajorityDisagree = disagree*2 > n-1
+		if rec.MajorityDisagree {
+			rpt.Summary.MajorityDisagree++
+		}
+		rpt.Records = append(rpt.Records, rec)
+	}
+	rpt.Summary.RecordDiff = len(rpt.Records)
+	rpt.Identical = len(rpt.Records) == 0
+	return rpt
+}
+
+// Write render n-way report by format, table is the matrix view
+func (r *NWayReport) Write(w io.Writer, format string) error {
+	switch format {
+	case formatTable, "":
+		tbl := r.matrix(true)
+		tbl.SetOutputMirror(w)
+		tbl.SetStyle(table.StyleDefault)
+		tbl.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 3, Colors: text.Colors{text.FgHiMagenta}},
+		})
+		tbl.Render()
+		fmt.Fprintln(w)
+		return nil
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case formatCSV:
+		_, err := fmt.Fprintln(w, r.matrix(false).RenderCSV())
+		return err
+	case formatMarkdown:
+		_, err := fmt.Fprintln(w, r.matrix(true).RenderMarkdown())
+		return err
+	case formatHTML:
+		_, err := fmt.Fprintln(w, r.matrix(true).RenderHTML())
+		return err
+	default:
+		return fmt.Errorf("output format not support, %s", format)
+	}
+}
+
+// matrix one row per record, one column per input, csv with 1/0 cell and without summary
+func (r *NWayReport) matrix(human bool) table.Writer {
+	header := table.Row{"Zone", "View", "Record"}
+	for i, input := range r.Inputs {
+		if i == r.BaseIndex && human {
+			input = "*" + input
+		}
+		header = append(header, input+columnSuffix(r.Inputs, i))
+	}
+	header = append(header, "Majority Disagree")
+
+	tbl := table.NewWriter()
+	tbl.AppendHeader(header)
+	for _, rec := range r.Records {
+		row := table.Row{rec.Zone, rec.View, rec.Record}
+		for _, p := range rec.present {
+			row = append(row, cell(p, human))
+		}
+		row = append(row, cell(rec.MajorityDisagree, human))
+		tbl.AppendRow(row)
+	}
+	if !human {
+		return tbl
+	}
+
+	footer := table.Row{"SUMMARY", fmt.Sprintf("RECORD DIFF:%d", r.Summary.RecordDiff),
+		fmt.Sprintf("MAJORITY DISAGREE:%d", r.Summary.MajorityDisagree)}
+	for _, ele := range r.Summary.Inputs {
+		footer = append(footer, fmt.Sprintf("NU:%d -%d +%d", ele.Num, ele.Missing, ele.Extra))
+	}
+	footer = append(footer, "")
+	tbl.AppendFooter(footer)
+	return tbl
+}
+
+// columnSuffix distinguish same input name in header
+func columnSuffix(inputs []string, idx int) string {
+	for i := range inputs {
+		if i != idx && inputs[i] == inputs[idx] {
+			return fmt.Sprintf("#%d", idx+1)
+		}
+	}
+	return ""
+}
+
+// cell matrix cell
+func cell(b bool, human bool) string {
+	switch {
+	case human && b:
+		return cellHas
+	case human:
+		return cellLack
+	case b:
+		return "1"
+	default:
+		return "0"
+	}
+}
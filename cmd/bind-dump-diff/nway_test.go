@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/itoolkits/toolkit/dnt"
+)
+
+func TestNWayNumDistinct(t *testing.T) {
+	rr := &dnt.RR{Domain: "www.example.com.", RType: dnt.TypeA, RData: "192.0.2.1"}
+	h := NewNWayDiffHandler([]string{"a", "b"}, 0, nil)
+	h.Add(0, map[string]map[string][]*dnt.RR{"example.com.": {"v1": {rr, rr}}})
+	h.Add(1, map[string]map[string][]*dnt.RR{"example.com.": {"v1": {rr}}})
+	rpt := h.Report()
+	if !rpt.Identical {
+		t.Fatal("duplicate records should be identical")
+	}
+	for _, in := range rpt.Summary.Inputs {
+		if in.Num != 1 {
+			t.Fatalf("num of %s should count distinct records, %d", in.Input, in.Num)
+		}
+	}
+}
+
+func TestNWayTSIGs(t *testing.T) {
+	got := nwayTSIGs(3, 1, map[int]string{2: "k2:s2"}, "ka:sa", "kb:sb")
+	want := []string{"kb:sb", "ka:sa", "k2:s2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tsig of source %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
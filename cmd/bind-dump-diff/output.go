@@ -0,0 +1,282 @@
+// diff report output, support table/json/ndjson/csv/markdown/html
+// json: whole report object, ndjson: one RecordDiff per line, summary object at last line
+// sort engine stream ndjson/csv/markdown/html only
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatNDJSON   = "ndjson"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+
+	changeAdded   = "added"
+	changeRemoved = "removed"
+)
+
+type DiffSummary struct {
+	ZoneDiff   int `json:"zone_diff"`
+	RecordDiff int `json:"record_diff"`
+	Added      int `json:"added"`
+	Removed    int `json:"removed"`
+	ANum       int `json:"a_num"`
+	BNum       int `json:"b_num"`
+}
+
+type DiffReport struct {
+	A         string      `json:"a"`
+	B         string      `json:"b"`
+	Identical bool        `json:"identical"`
+	Summary   DiffSummary `json:"summary"`
+	Diffs     []*ZoneDiff `json:"diffs"`
+}
+
+// Key zone & view title
+func (z *ZoneDiff) Key() string {
+	if z.Scope == scopeZone {
+		return fmt.Sprintf("Zone: %s", z.Zone)
+	}
+	return fmt.Sprintf("Zone: %s, View: %s", z.Zone, z.View)
+}
+
+// Write render report to writer by format
+func (r *DiffReport) Write(w io.Writer, format string) error {
+	switch format {
+	case formatTable, "":
+		r.renderTable(w)
+		return nil
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case formatNDJSON:
+		out, err := NewDiffStream(w, format)
+		if err != nil {
+			return err
+		}
+		return r.WriteStream(out)
+	case formatCSV:
+		_, err := fmt.Fprintln(w, r.recordTable(false).RenderCSV())
+		return err
+	case formatMarkdown:
+		_, err := fmt.Fprintln(w, r.recordTable(true).RenderMarkdown())
+		return err
+	case formatHTML:
+		_, err := fmt.Fprintln(w, r.recordTable(true).RenderHTML())
+		return err
+	default:
+		return fmt.Errorf("output format not support, %s", format)
+	}
+}
+
+// WriteStream write differences record by record into stream, same output as sort engine
+func (r *DiffReport) WriteStream(out DiffStream) error {
+	for _, zd := range r.Diffs {
+		for _, rec := range zd.Removed {
+			if err := out.Write(&RecordDiff{Zone: zd.Zone, View: zd.View, Change: changeRemoved, Record: rec}); err != nil {
+				return err
+			}
+		}
+		for _, rec := range zd.Added {
+			if err := out.Write(&RecordDiff{Zone: zd.Zone, View: zd.View, Change: changeAdded, Record: rec}); err != nil {
+				return err
+			}
+		}
+	}
+	return out.Close(r)
+}
+
+// renderTable render colored table, one row per zone & view
+func (r *DiffReport) renderTable(w io.Writer) {
+	rows := make([]table.Row, 0)
+	for i, zd := range r.Diffs {
+		rows = append(rows, table.Row{i + 1, zd.Key(),
+			strings.Join(zd.A, "\n"), strings.Join(zd.B, "\n")})
+		rows = append(rows, table.Row{"", "", "", ""})
+	}
+
+	tbl := table.NewWriter()
+	tbl.SetOutputMirror(w)
+	tbl.AppendHeader(table.Row{"#", "Zone & View", r.A, r.B})
+	tbl.AppendRows(rows)
+	tbl.AppendSeparator()
+	tbl.SetStyle(table.StyleDefault)
+	tbl.SetColumnConfigs([]table.ColumnConfig{
+		{Name: "Zone & View", Colors: text.Colors{text.FgHiMagenta}, ColorsHeader: text.Colors{text.FgHiMagenta}},
+		{Name: r.A, Colors: text.Colors{text.FgHiYellow}, ColorsHeader: text.Colors{text.FgHiYellow}},
+		{Name: r.B, Colors: text.Colors{text.FgHiCyan}, ColorsHeader: text.Colors{text.FgHiCyan}},
+	})
+	tbl.AppendFooter(table.Row{"SUMMARY",
+		fmt.Sprintf("ZONE DIF:%d, RECORD DIFF:%d", r.Summary.ZoneDiff, r.Summary.RecordDiff),
+		fmt.Sprintf("CHECK NU:%d", r.Summary.ANum),
+		fmt.Sprintf("CHECK NU:%d", r.Summary.BNum)})
+	tbl.Render()
+	fmt.Fprintln(w)
+}
+
+// recordTable plain table, one row per different record, csv without summary footer
+func (r *DiffReport) recordTable(summary bool) table.Writer {
+	tbl := table.NewWriter()
+	tbl.AppendHeader(table.Row{"Zone", "View", "Scope", "Missing In", "Change", "Record"})
+	for _, zd := range r.Diffs {
+		for _, rec := range zd.Removed {
+			tbl.AppendRow(table.Row{zd.Zone, zd.View, zd.Scope, zd.MissingIn, changeRemoved, rec})
+		}
+		for _, rec := range zd.Added {
+			tbl.AppendRow(table.Row{zd.Zone, zd.View, zd.Scope, zd.MissingIn, changeAdded, rec})
+		}
+	}
+	if !summary {
+		return tbl
+	}
+	tbl.AppendFooter(table.Row{"SUMMARY",
+		fmt.Sprintf("ZONE DIFF:%d", r.Summary.ZoneDiff),
+		fmt.Sprintf("RECORD DIFF:%d", r.Summary.RecordDiff),
+		fmt.Sprintf("A:%d B:%d", r.Summary.ANum, r.Summary.BNum),
+		fmt.Sprintf("ADDED:%d", r.Summary.Added),
+		fmt.Sprintf("REMOVED:%d", r.Summary.Removed)})
+	return tbl
+}
+
+type RecordDiff struct {
+	Zone   string `json:"zone"`
+	View   string `json:"view"`
+	Change string `json:"change"`
+	Record string `json:"record"`
+}
+
+// DiffStream stream record differences, used by sort engine
+type DiffStream interface {
+	Write(d *RecordDiff) error
+	// Close write summary and flush
+	Close(rpt *DiffReport) error
+}
+
+// NewDiffStream create diff stream by format, table and json not support
+func NewDiffStream(w io.Writer, format string) (DiffStream, error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case formatNDJSON:
+		return &ndjsonStream{w: bw, enc: json.NewEncoder(bw)}, nil
+	case formatCSV:
+		cw := csv.NewWriter(bw)
+		return &csvStream{w: bw, cw: cw}, cw.Write([]string{"Zone", "View", "Change", "Record"})
+	case formatMarkdown:
+		_, err := fmt.Fprint(bw, "| Zone | View | Change | Record |\n| --- | --- | --- | --- |\n")
+		return &markdownStream{w: bw}, err
+	case formatHTML:
+		_, err := fmt.Fprint(bw, "<table>\n  <thead>\n  <tr><th>Zone</th><th>View</th><th>Change</th><th>Record</th></tr>\n  </thead>\n  <tbody>\n")
+		return &htmlStream{w: bw}, err
+	default:
+		return nil, fmt.Errorf("output format not support by sort engine, %s, use ndjson|csv|markdown|html", format)
+	}
+}
+
+// ndjsonStream one json object per line, summary object at last line
+type ndjsonStream struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// Write implements DiffStream
+func (s *ndjsonStream) Write(d *RecordDiff) error {
+	return s.enc.Encode(d)
+}
+
+// Close implements DiffStream
+func (s *ndjsonStream) Close(rpt *DiffReport) error {
+	err := s.enc.Encode(map[string]any{
+		"a":         rpt.A,
+		"b":         rpt.B,
+		"identical": rpt.Identical,
+		"summary":   rpt.Summary,
+	})
+	if err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+type csvStream struct {
+	w  *bufio.Writer
+	cw *csv.Writer
+}
+
+// Write implements DiffStream
+func (s *csvStream) Write(d *RecordDiff) error {
+	return s.cw.Write([]string{d.Zone, d.View, d.Change, d.Record})
+}
+
+// Close implements DiffStream, csv without summary
+func (s *csvStream) Close(_ *DiffReport) error {
+	s.cw.Flush()
+	if err := s.cw.Error(); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+type markdownStream struct {
+	w *bufio.Writer
+}
+
+// Write implements DiffStream
+func (s *markdownStream) Write(d *RecordDiff) error {
+	_, err := fmt.Fprintf(s.w, "| %s | %s | %s | %s |\n",
+		mdEscape(d.Zone), mdEscape(d.View), d.Change, mdEscape(d.Record))
+	return err
+}
+
+// Close implements DiffStream
+func (s *markdownStream) Close(rpt *DiffReport) error {
+	_, err := fmt.Fprintf(s.w, "| SUMMARY | ZONE DIFF:%d | RECORD DIFF:%d | A:%d B:%d ADDED:%d REMOVED:%d |\n",
+		rpt.Summary.ZoneDiff, rpt.Summary.RecordDiff, rpt.Summary.ANum, rpt.Summary.BNum,
+		rpt.Summary.Added, rpt.Summary.Removed)
+	if err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+type htmlStream struct {
+	w *bufio.Writer
+}
+
+// Write implements DiffStream
+func (s *htmlStream) Write(d *RecordDiff) error {
+	_, err := fmt.Fprintf(s.w, "  <tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+		html.EscapeString(d.Zone), html.EscapeString(d.View), d.Change, html.EscapeString(d.Record))
+	return err
+}
+
+// Close implements DiffStream
+func (s *htmlStream) Close(rpt *DiffReport) error {
+	_, err := fmt.Fprintf(s.w, "  </tbody>\n  <tfoot>\n  <tr><td>SUMMARY</td><td>ZONE DIFF:%d</td><td>RECORD DIFF:%d</td><td>A:%d B:%d ADDED:%d REMOVED:%d</td></tr>\n  </tfoot>\n</table>\n",
+		rpt.Summary.ZoneDiff, rpt.Summary.RecordDiff, rpt.Summary.ANum, rpt.Summary.BNum,
+		rpt.Summary.Added, rpt.Summary.Removed)
+	if err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// mdEscape escape markdown table cell
+func mdEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br/>")
+}
@@ -0,0 +1,180 @@
+// sort diff engine, external sort both sides and merge join in canonical order
+// memory bounded by chunk size, differences streamed out
+// dumpdb, zone file and dir sources streamed, axfr source held in memory zone by zone
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/itoolkits/toolkit/dnt"
+)
+
+const (
+	engineMemory = "memory"
+	engineSort   = "sort"
+
+	keySep       = "\x00"
+	progressStep = 1000000
+)
+
+type SortDiffEngine struct {
+	Opt       *CompareOption
+	TmpDir    string
+	ChunkSize int
+
+	// Progress write progress info when not nil
+	Progress io.Writer
+
+	start time.Time
+}
+
+// Diff diff a and b, write record differences to stream in zone, view, record order
+func (e *SortDiffEngine) Diff(a, b *Source, out DiffStream) (*DiffReport, error) {
+	if e.Opt == nil {
+		e.Opt = NewCompareOption()
+	}
+	e.start = time.Now()
+
+	as, err := e.sort(a, sideA, false)
+	if as != nil {
+		defer as.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	bs, err := e.sort(b, sideB, true)
+	if bs != nil {
+		defer bs.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rpt := &DiffReport{A: a.Spec, B: b.Spec}
+	rpt.Summary.ANum = as.Num()
+	rpt.Summary.BNum = bs.Num()
+
+	ai, err := as.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer ai.Close()
+	bi, err := bs.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer bi.Close()
+
+	ak, aok, err := ai.Next()
+	if err != nil {
+		return nil, err
+	}
+	bk, bok, err := bi.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	lastZV := ""
+	emit := func(key, change string) error {
+		d := splitKey(key, change)
+		zv := d.Zone + keySep + d.View
+		if zv != lastZV {
+			rpt.Summary.ZoneDiff++
+			lastZV = zv
+		}
+		if change == changeAdded {
+			rpt.Summary.Added++
+		} else {
+			rpt.Summary.Removed++
+		}
+		rpt.Summary.RecordDiff++
+		return out.Write(d)
+	}
+
+	merged := 0
+	for aok || bok {
+		switch {
+		case aok && (!bok || ak < bk):
+			err = emit(ak, changeRemoved)
+			if err == nil {
+				ak, aok, err = ai.Next()
+			}
+		case bok && (!aok || bk < ak):
+			err = emit(bk, changeAdded)
+			if err == nil {
+				bk, bok, err = bi.Next()
+			}
+		default:
+			ak, aok, err = ai.Next()
+			if err == nil {
+				bk, bok, err = bi.Next()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		merged++
+		if merged%progressStep == 0 {
+			e.progress("merge", "merged %d keys, %d differences", merged, rpt.Summary.RecordDiff)
+		}
+	}
+	e.progress("merge", "done, %d differences", rpt.Summary.RecordDiff)
+
+	rpt.Identical = rpt.Summary.RecordDiff == 0
+	return rpt, out.Close(rpt)
+}
+
+// sort scan source into external sorter
+func (e *SortDiffEngine) sort(src *Source, side string, mapB bool) (*ExtSorter, error) {
+	sorter := NewExtSorter(e.TmpDir, e.ChunkSize)
+	err := src.Scan(func(zone, view string, rr *dnt.RR) error {
+		view, ok := e.Opt.Accept(zone, view, rr, mapB)
+		if !ok {
+			return nil
+		}
+		runs := sorter.Runs()
+		err := sorter.Add(zone + keySep + view + keySep + e.Opt.Record2Str(rr))
+		if err != nil {
+			return err
+		}
+		if sorter.Runs() > runs {
+			e.progress("sort", "side %s spill run %d", side, sorter.Runs())
+		}
+		if sorter.Num()%progressStep == 0 {
+			e.progress("sort", "side %s read %d records", side, sorter.Num())
+		}
+		return nil
+	})
+	if err != nil {
+		return sorter, fmt.Errorf("scan source error, %s %w", src.Spec, err)
+	}
+	e.progress("sort", "side %s done, %d records, %d runs", side, sorter.Num(), sorter.Runs())
+	return sorter, nil
+}
+
+// progress write progress line
+func (e *SortDiffEngine) progress(phase string, format string, args ...any) {
+	if e.Progress == nil {
+		return
+	}
+	fmt.Fprintf(e.Progress, "[%s] [%s] %s\n",
+		time.Since(e.start).Truncate(time.Millisecond), phase, fmt.Sprintf(format, args...))
+}
+
+// splitKey split sort key to record diff
+func splitKey(key, change string) *RecordDiff {
+	seg := strings.SplitN(key, keySep, 3)
+	for len(seg) < 3 {
+		seg = append(seg, "")
+	}
+	return &RecordDiff{
+		Zone:   seg[0],
+		View:   seg[1],
+		Change: change,
+		Record: seg[2],
+	}
+}
@@ -0,0 +1,348 @@
+// diff input source, dumpdb file, zone file, zone file dir or live axfr
+// spec format:
+//   dumpdb:PATH
+//   zone:[ZONE=]PATH[@VIEW]
+//   dir:PATH[@VIEW]
+//   axfr:SERVER[:PORT]/ZONE[,ZONE...][@VIEW]
+//   PATH - dir when PATH is a directory, otherwise dumpdb
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/itoolkits/toolkit/dnt"
+)
+
+const (
+	sourceDumpDB = "dumpdb"
+	sourceZone   = "zone"
+	sourceDir    = "dir"
+	sourceAXFR   = "axfr"
+
+	defaultAXFRPort = "53"
+)
+
+var zoneFileSuffixes = []string{".zone", ".db", ".hosts", ".txt"}
+
+type Source struct {
+	Spec string
+	Kind string
+	Path string
+	View string
+
+	Zones []string
+
+	Server string
+	Port   string
+
+	// tsig key, format: [algo:]name:secret
+	TSIG string
+}
+
+// ParseSource parse source spec
+func ParseSource(spec string, tsig string) (*Source, error) {
+	s := &Source{
+		Spec: spec,
+		TSIG: tsig,
+	}
+
+	kind, body, h := strings.Cut(spec, ":")
+	switch {
+	case h && (kind == sourceDumpDB || kind == sourceZone || kind == sourceDir || kind == sourceAXFR):
+		s.Kind = kind
+	default:
+		body = spec
+		s.Kind = sourceDumpDB
+		if fi, err := os.Stat(spec); err == nil && fi.IsDir() {
+			s.Kind = sourceDir
+		}
+		s.Path = body
+		return s, nil
+	}
+
+	if s.Kind != sourceDumpDB {
+		body, s.View = cutView(body)
+	}
+
+	switch s.Kind {
+	case sourceDumpDB, sourceDir:
+		s.Path = body
+	case sourceZone:
+		zone, path, h := strings.Cut(body, "=")
+		if !h {
+			path = body
+			zone = zoneFromFileName(path)
+		}
+		s.Path = path
+		s.Zones = []string{dnt.FixDomain(zone)}
+	case sourceAXFR:
+		addr, zones, h := strings.Cut(body, "/")
+		if !h || zones == "" {
+			return nil, fmt.Errorf("axfr source format error, %s", spec)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, defaultAXFRPort
+		}
+		s.Server = host
+		s.Port = port
+		for _, z := range splitList(zones) {
+			s.Zones = append(s.Zones, dnt.FixDomain(z))
+		}
+	}
+
+	if s.Kind != sourceAXFR && s.Path == "" {
+		return nil, fmt.Errorf("source path can not blank, %s", spec)
+	}
+	return s, nil
+}
+
+// Load load records, zone -> view -> records
+func (s *Source) Load() (map[string]map[string][]*dnt.RR, error) {
+	switch s.Kind {
+	case sourceDumpDB:
+		return dnt.ParseDumpDB(s.Path)
+	case sourceZone:
+		rst := make(map[string]map[string][]*dnt.RR)
+		return rst, s.loadZoneFile(rst, s.Zones[0], s.Path)
+	case sourceDir:
+		return s.loadDir()
+	case sourceAXFR:
+		return s.loadAXFR()
+	default:
+		return nil, fmt.Errorf("source kind not support, %s", s.Kind)
+	}
+}
+
+// Scan stream records, dumpdb, zone file and dir never loaded whole into memory
+// axfr transferred into memory zone by zone
+func (s *Source) Scan(fn func(zone, view string, rr *dnt.RR) error) error {
+	switch s.Kind {
+	case sourceDumpDB:
+		return dnt.ScanDumpDB(s.Path, fn)
+	case sourceZone:
+		return s.scanZoneFile(s.Zones[0], s.Path, fn)
+	case sourceDir:
+		return s.scanDir(fn)
+	}
+	zvrList, err := s.Load()
+	if err != nil {
+		return err
+	}
+	for z, vrList := range zvrList {
+		for v, rList := range vrList {
+			for _, rr := range rList {
+				if err := fn(z, v, rr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loadZoneFile parse zone file into result
+func (s *Source) loadZoneFile(rst map[string]map[string][]*dnt.RR, zone, path string) error {
+	rrs, err := dnt.ParseZoneFile(zone, s.View, path)
+	if err != nil {
+		return fmt.Errorf("parse zone file error, %s %w", path, err)
+	}
+	addRecords(rst, zone, s.View, rrs)
+	return nil
+}
+
+// scanZoneFile stream records of zone file
+func (s *Source) scanZoneFile(zone, path string, fn func(zone, view string, rr *dnt.RR) error) error {
+	err := dnt.ScanZoneFile(zone, s.View, path, func(rr *dnt.RR) error {
+		return fn(zone, s.View, rr)
+	})
+	if err != nil {
+		return fmt.Errorf("parse zone file error, %s %w", path, err)
+	}
+	return nil
+}
+
+// loadDir parse all zone files in dir, zone name from file name
+func (s *Source) loadDir() (map[string]map[string][]*dnt.RR, error) {
+	rst := make(map[string]map[string][]*dnt.RR)
+	err := s.scanDir(func(zone, view string, rr *dnt.RR) error {
+		addRecords(rst, zone, view, []*dnt.RR{rr})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rst, nil
+}
+
+// scanDir stream records of zone files in dir, zone name from file name
+// file named as zone file streamed and must parse
+// other files parsed whole before emit, skipped with warning when parse failed, e.g. named.conf
+func (s *Source) scanDir(fn func(zone, view string, rr *dnt.RR) error) error {
+	entries, err := os.ReadDir(s.Path)
+	if err != nil {
+		return err
+	}
+	for _, ele := range entries {
+		name := ele.Name()
+		if ele.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".jnl") {
+			continue
+		}
+		zone, path := zoneFromFileName(name), filepath.Join(s.Path, name)
+		if isZoneFileName(name) {
+			if err = s.scanZoneFile(zone, path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		rrs, err := dnt.ParseZoneFile(zone, s.View, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skip file not zone file, %s %v\n", path, err)
+			continue
+		}
+		for _, rr := range rrs {
+			if err = fn(zone, s.View, rr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isZoneFileName file name with zone file suffix or db. prefix
+func isZoneFileName(name string) bool {
+	if strings.HasPrefix(name, "db.") {
+		return true
+	}
+	for _, suffix := range zoneFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAXFR transfer zones from server
+func (s *Source) loadAXFR() (map[string]map[string][]*dnt.RR, error) {
+	algo, name, secret, err := parseTSIG(s.TSIG)
+	if err != nil {
+		return nil, err
+	}
+	rst := make(map[string]map[string][]*dnt.RR)
+	for _, z := range s.Zones {
+		xfr := dnt.NewXfr(z, "", "", 0)
+		xfr.SetNS(s.Server, s.Port)
+		xfr.SetRetry(1, 0)
+		if name != "" {
+			xfr.SetAlgo(algo, name, secret)
+		}
+		records, err := xfr.Query()
+		if err != nil {
+			return nil, fmt.Errorf("axfr error, %s %s %w", net.JoinHostPort(s.Server, s.Port), z, err)
+		}
+		rrs := make([]*dnt.RR, 0, len(records))
+		soaNum := 0
+		for _, record := range records {
+			// axfr ends with the leading soa again
+			if record.Header().Rrtype == dns.TypeSOA {
+				soaNum++
+				if soaNum > 1 {
+					continue
+				}
+			}
+			rr, err := convRecord(z, s.View, record)
+			if err != nil {
+				return nil, err
+			}
+			rrs = append(rrs, rr)
+		}
+		addRecords(rst, z, s.View, rrs)
+	}
+	return rst, nil
+}
+
+// convRecord convert dns record, keep presentation rdata for types dnt not support
+func convRecord(zone, view string, record dns.RR) (*dnt.RR, error) {
+	conv := &dnt.RecordConv{
+		Zone:   zone,
+		View:   view,
+		Record: record,
+	}
+	rr, err := conv.ConvRR()
+	if err == nil {
+		return rr, nil
+	}
+
+	header := record.Header()
+	domain := dnt.FixDomain(header.Name)
+	hostname, herr := dnt.AdaptHostname(zone, domain)
+	if herr != nil {
+		return nil, err
+	}
+	return &dnt.RR{
+		Zone:     zone,
+		View:     view,
+		Domain:   domain,
+		Hostname: hostname,
+		TTL:      int(header.Ttl),
+		Class:    dns.Class(header.Class).String(),
+		RType:    dns.TypeToString[header.Rrtype],
+		RData:    strings.TrimSpace(strings.TrimPrefix(record.String(), header.String())),
+	}, nil
+}
+
+// addRecords add records to zone & view
+func addRecords(rst map[string]map[string][]*dnt.RR, zone, view string, rrs []*dnt.RR) {
+	vrs, h := rst[zone]
+	if !h {
+		vrs = make(map[string][]*dnt.RR)
+		rst[zone] = vrs
+	}
+	vrs[view] = append(vrs[view], rrs...)
+}
+
+// parseTSIG parse tsig key, format: [algo:]name:secret, default algo hmac-sha256
+func parseTSIG(key string) (string, string, string, error) {
+	if key == "" {
+		return "", "", "", nil
+	}
+	seg := strings.Split(key, ":")
+	switch len(seg) {
+	case 2:
+		return dnt.HmacSHA256, seg[0], seg[1], nil
+	case 3:
+		algo := strings.ToLower(dns.Fqdn(seg[0]))
+		if algo == "hmac-md5." {
+			algo = dnt.HmacMD5
+		}
+		return algo, seg[1], seg[2], nil
+	default:
+		return "", "", "", fmt.Errorf("tsig key format error, [algo:]name:secret")
+	}
+}
+
+// cutView cut @view suffix, ignore @ in dir part
+func cutView(s string) (string, string) {
+	idx := strings.LastIndex(s, "@")
+	if idx < 0 || idx < strings.LastIndex(s, "/") {
+		return s, ""
+	}
+	return s[:idx], s[idx+1:]
+}
+
+// zoneFromFileName zone name from file name, e.g. db.example.com, example.com.zone
+func zoneFromFileName(path string) string {
+	name := filepath.Base(path)
+	for _, suffix := range zoneFileSuffixes {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	name = strings.TrimPrefix(name, "db.")
+	return dnt.FixDomain(name)
+}
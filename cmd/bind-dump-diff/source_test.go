@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/itoolkits/toolkit/dnt"
+)
+
+const testZoneFile = `$TTL 3600
+@	IN SOA ns1 admin 1 3600 600 86400 300
+@	IN NS ns1
+ns1	IN A 192.0.2.1
+www	IN A 192.0.2.10
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rdataOf records of zone and view, type and rdata sorted
+func rdataOf(zvr map[string]map[string][]*dnt.RR, zone, view string) []string {
+	rst := make([]string, 0)
+	for _, rr := range zvr[zone][view] {
+		rst = append(rst, rr.Domain+" "+rr.RType+" "+rr.RData)
+	}
+	sort.Strings(rst)
+	return rst
+}
+
+func TestParseSource(t *testing.T) {
+	s, err := ParseSource("axfr:127.0.0.1:5353/example.com,example.org@internal", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Kind != sourceAXFR || s.Server != "127.0.0.1" || s.Port != "5353" || s.View != "internal" || len(s.Zones) != 2 {
+		t.Fatalf("parse axfr source error, %+v", s)
+	}
+
+	s, err = ParseSource("zone:example.com=/tmp/x@v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Kind != sourceZone || s.Path != "/tmp/x" || s.View != "v1" || s.Zones[0] != dnt.FixDomain("example.com") {
+		t.Fatalf("parse zone source error, %+v", s)
+	}
+}
+
+func TestSourceDumpDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "named_dump.db")
+	writeFile(t, path, `;
+; Zone dump of 'example.com/IN/internal'
+;
+example.com.	3600	IN SOA	ns1.example.com. admin.example.com. 1 3600 600 86400 300
+www.example.com.	3600	IN A	192.0.2.10
+`)
+	s, err := ParseSource("dumpdb:"+path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvr, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := dnt.FixDomain("example.com")
+	if got := rdataOf(zvr, zone, "internal"); len(got) != 2 {
+		t.Fatalf("dumpdb records error, %v", zvr)
+	}
+
+	n := 0
+	if err = s.Scan(func(zone, view string, rr *dnt.RR) error {
+		n++
+		return nil
+	}); err != nil || n != 2 {
+		t.Fatalf("dumpdb scan error, %d %v", n, err)
+	}
+}
+
+func TestSourceDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "db.example.com"), testZoneFile)
+	writeFile(t, filepath.Join(dir, "example.org.zone"), testZoneFile)
+	writeFile(t, filepath.Join(dir, "example.com.jnl"), "binary journal")
+	writeFile(t, filepath.Join(dir, "named.conf"), `zone "example.com" { type master; file "db.example.com"; };`)
+	writeFile(t, filepath.Join(dir, "README"), "zone files of test")
+
+	s, err := ParseSource("dir:"+dir+"@v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvr, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zvr) != 2 {
+		t.Fatalf("dir zones error, %v", zvr)
+	}
+	for _, zone := range []string{"example.com", "example.org"} {
+		if got := rdataOf(zvr, dnt.FixDomain(zone), "v1"); len(got) != 4 {
+			t.Fatalf("dir records of %s error, %v", zone, got)
+		}
+	}
+
+	n := 0
+	if err = s.Scan(func(zone, view string, rr *dnt.RR) error {
+		n++
+		return nil
+	}); err != nil || n != 8 {
+		t.Fatalf("dir scan error, %d %v", n, err)
+	}
+
+	// file named as zone file must parse
+	writeFile(t, filepath.Join(dir, "broken.zone"), "this is not a zone file")
+	if _, err = s.Load(); err == nil {
+		t.Fatal("broken zone file should fail")
+	}
+}
+
+// startAXFRServer serve axfr of zone by records in zone file format
+func startAXFRServer(t *testing.T, zone, content string) string {
+	t.Helper()
+	origin := dns.Fqdn(zone)
+	records := make([]dns.RR, 0)
+	zp := dns.NewZoneParser(strings.NewReader(content), origin, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		records = append(records, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// axfr starts and ends with soa
+	records = append(records, records[0])
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc(origin, func(w dns.ResponseWriter, r *dns.Msg) {
+		if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeAXFR {
+			m := &dns.Msg{}
+			m.SetRcode(r, dns.RcodeRefused)
+			_ = w.WriteMsg(m)
+			return
+		}
+		ch := make(chan *dns.Envelope, 1)
+		tr := &dns.Transfer{}
+		go func() {
+			ch <- &dns.Envelope{RR: records}
+			close(ch)
+		}()
+		_ = tr.Out(w, r, ch)
+		w.Hijack()
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{Listener: l, Handler: mux}
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+	go func() {
+		_ = srv.ActivateAndServe()
+	}()
+	<-started
+	t.Cleanup(func() {
+		_ = srv.Shutdown()
+	})
+	return l.Addr().String()
+}
+
+func TestSourceAXFR(t *testing.T) {
+	addr := startAXFRServer(t, "example.com", testZoneFile)
+
+	s, err := ParseSource("axfr:"+addr+"/example.com@v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvr, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rdataOf(zvr, dnt.FixDomain("example.com"), "v1")
+	if len(got) != 4 {
+		t.Fatalf("axfr records error, trailing soa should be dropped, %v", got)
+	}
+
+	// same records as zone file
+	path := filepath.Join(t.TempDir(), "db.example.com")
+	writeFile(t, path, testZoneFile)
+	zs, err := ParseSource("zone:"+path+"@v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := zs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantList := rdataOf(want, dnt.FixDomain("example.com"), "v1")
+	for i := range wantList {
+		if wantList[i] != got[i] {
+			t.Fatalf("axfr and zone file records differ, %v %v", got, wantList)
+		}
+	}
+
+	s.Zones = []string{dnt.FixDomain("example.net")}
+	if _, err = s.Load(); err == nil {
+		t.Fatal("axfr of refused zone should fail")
+	}
+}
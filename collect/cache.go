@@ -0,0 +1,506 @@
+// cache, in memory, ttl per entry, lru or lfu eviction by max entries
+// load by loader func, concurrent load of same key call loader once
+// loader run detached from caller ctx, caller stop waiting when its ctx done
+// Set, Delete or Clear while loading invalidate the load, loaded value returned not cached
+// expired entry removed by background cleaner, and lazily by Get
+
+package collect
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type CacheEviction int
+
+const (
+	CacheLRU CacheEviction = iota // evict least recently used
+	CacheLFU                      // evict least frequently used, least recently used of same frequency
+)
+
+const defaultCacheCleanInterval = time.Minute
+
+type CacheStats struct {
+	Entries     int
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Loads       uint64
+	LoadErrors  uint64
+}
+
+type cacheConfig[K comparable, V any] struct {
+	maxEntries    int
+	ttl           time.Duration
+	eviction      CacheEviction
+	cleanInterval time.Duration
+	loadTimeout   time.Duration
+	loader        func(context.Context, K) (V, error)
+}
+
+type CacheOption[K comparable, V any] func(cfg *cacheConfig[K, V])
+
+// WithCacheMaxEntries - max entry num, evict when full, default no limit
+func WithCacheMaxEntries[K comparable, V any](n int) CacheOption[K, V] {
+	return func(cfg *cacheConfig[K, V]) {
+		cfg.maxEntries = n
+	}
+}
+
+// WithCacheTTL - default ttl of entry, default never expire
+func WithCacheTTL[K comparable, V any](ttl time.Duration) CacheOption[K, V] {
+	return func(cfg *cacheConfig[K, V]) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithCacheEviction - eviction by CacheLRU or CacheLFU, default CacheLRU
+func WithCacheEviction[K comparable, V any](eviction CacheEviction) CacheOption[K, V] {
+	return func(cfg *cacheConfig[K, V]) {
+		cfg.eviction = eviction
+	}
+}
+
+// WithCacheCleanInterval - background clean interval of expired entry, default 1 minute, 0 disable
+func WithCacheCleanInterval[K comparable, V any](interval time.Duration) CacheOption[K, V] {
+	return func(cfg *cacheConfig[K, V]) {
+		cfg.cleanInterval = interval
+	}
+}
+
+// WithCacheLoadTimeout - timeout of loader ctx, default no timeout
+func WithCacheLoadTimeout[K comparable, V any](timeout time.Duration) CacheOption[K, V] {
+	return func(cfg *cacheConfig[K, V]) {
+		cfg.loadTimeout = timeout
+	}
+}
+
+// WithCacheLoader - loader of Load
+func WithCacheLoader[K comparable, V any](fn func(context.Context, K) (V, error)) CacheOption[K, V] {
+	return func(cfg *cacheConfig[K, V]) {
+		cfg.loader = fn
+	}
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key    K
+	val    V
+	expire time.Time // zero never expire
+
+	elem  *list.Element // lru
+	freq  uint64        // lfu
+	tick  uint64        // lfu
+	index int           // lfu
+}
+
+// expired - entry expired at now
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expire.IsZero() && now.After(e.expire)
+}
+
+type cacheCall[V any] struct {
+	done  chan struct{}
+	val   V
+	err   error
+	stale bool // invalidated while loading, lock held
+}
+
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	items   map[K]*cacheEntry[K, V]
+	policy  cachePolicy[K, V]
+	calls   map[K]*cacheCall[V]
+	max     int
+	ttl     time.Duration
+	timeout time.Duration
+	loader  func(context.Context, K) (V, error)
+	closeCh chan struct{}
+	closed  sync.Once
+	nilVal  V // do not init it
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	loads       atomic.Uint64
+	loadErrors  atomic.Uint64
+}
+
+// NewCache - create cache, Close to stop background cleaner
+func NewCache[K comparable, V any](opts ...CacheOption[K, V]) *Cache[K, V] {
+	cfg := &cacheConfig[K, V]{
+		cleanInterval: defaultCacheCleanInterval,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	c := &Cache[K, V]{
+		items:   make(map[K]*cacheEntry[K, V]),
+		calls:   make(map[K]*cacheCall[V]),
+		max:     cfg.maxEntries,
+		ttl:     cfg.ttl,
+		timeout: cfg.loadTimeout,
+		loader:  cfg.loader,
+		closeCh: make(chan struct{}),
+	}
+	if cfg.eviction == CacheLFU {
+		c.policy = &lfuPolicy[K, V]{}
+	} else {
+		c.policy = &lruPolicy[K, V]{l: list.New()}
+	}
+	if cfg.cleanInterval > 0 {
+		go c.clean(cfg.cleanInterval)
+	}
+	return c
+}
+
+// clean remove expired entry by interval
+func (c *Cache[K, V]) clean(interval time.Duration) {
+	tck := time.NewTicker(interval)
+	defer tck.Stop()
+	for {
+		select {
+		case <-tck.C:
+			c.DeleteExpired()
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+// Close - stop background cleaner
+func (c *Cache[K, V]) Close() {
+	c.closed.Do(func() {
+		close(c.closeCh)
+	})
+}
+
+// Get - get value, hit when exists and not expired
+func (c *Cache[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(k)
+}
+
+// get get value, lock held
+func (c *Cache[K, V]) get(k K) (V, bool) {
+	e, h := c.items[k]
+	if h && e.expired(time.Now()) {
+		c.remove(e)
+		c.expirations.Add(1)
+		h = false
+	}
+	if !h {
+		c.misses.Add(1)
+		return c.nilVal, false
+	}
+	c.policy.access(e)
+	c.hits.Add(1)
+	return e.val, true
+}
+
+// Set - set value with default ttl
+func (c *Cache[K, V]) Set(k K, v V) {
+	c.SetWithTTL(k, v, c.ttl)
+}
+
+// SetWithTTL - set value with ttl, ttl <= 0 never expire
+func (c *Cache[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(k)
+	c.set(k, v, ttl)
+}
+
+// set set value, lock held
+func (c *Cache[K, V]) set(k K, v V, ttl time.Duration) {
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+
+	if e, h := c.items[k]; h {
+		e.val = v
+		e.expire = expire
+		c.policy.access(e)
+		return
+	}
+
+	if c.max > 0 && len(c.items) >= c.max {
+		c.evict()
+	}
+	e := &cacheEntry[K, V]{key: k, val: v, expire: expire}
+	c.items[k] = e
+	c.policy.add(e)
+}
+
+// evict remove victim of policy, lock held
+func (c *Cache[K, V]) evict() {
+	e := c.policy.victim()
+	if e == nil {
+		return
+	}
+	c.remove(e)
+	if e.expired(time.Now()) {
+		c.expirations.Add(1)
+		return
+	}
+	c.evictions.Add(1)
+}
+
+// remove remove entry, lock held
+func (c *Cache[K, V]) remove(e *cacheEntry[K, V]) {
+	delete(c.items, e.key)
+	c.policy.remove(e)
+}
+
+// Delete - delete value, loading value of k not cached
+func (c *Cache[K, V]) Delete(k K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(k)
+	if e, h := c.items[k]; h {
+		c.remove(e)
+	}
+}
+
+// invalidate mark loading call of k stale, later GetOrLoad start new load, lock held
+func (c *Cache[K, V]) invalidate(k K) {
+	if call, h := c.calls[k]; h {
+		call.stale = true
+		delete(c.calls, k)
+	}
+}
+
+// DeleteExpired - delete expired entry, return num
+func (c *Cache[K, V]) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deleteExpired()
+}
+
+// deleteExpired delete expired entry, lock held
+func (c *Cache[K, V]) deleteExpired() int {
+	now := time.Now()
+	n := 0
+	for _, e := range c.items {
+		if e.expired(now) {
+			c.remove(e)
+			n++
+		}
+	}
+	c.expirations.Add(uint64(n))
+	return n
+}
+
+// Len - entry num, expired not removed included
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Clear - delete all, loading values not cached
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, call := range c.calls {
+		call.stale = true
+	}
+	clear(c.calls)
+	clear(c.items)
+	c.policy.clear()
+}
+
+// Load - get value, load by loader of WithCacheLoader when miss
+func (c *Cache[K, V]) Load(ctx context.Context, k K) (V, error) {
+	if c.loader == nil {
+		return c.nilVal, fmt.Errorf("cache loader not set")
+	}
+	return c.GetOrLoad(ctx, k, c.loader)
+}
+
+// GetOrLoad - get value, load by fn when miss, concurrent load of same key call fn once
+// fn called with ctx detached from caller cancel, return ctx error when ctx done before loaded
+// load error not cached, returned to all waiting caller
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, k K, fn func(context.Context, K) (V, error)) (V, error) {
+	c.mu.Lock()
+	if v, h := c.get(k); h {
+		c.mu.Unlock()
+		return v, nil
+	}
+	call, h := c.calls[k]
+	if !h {
+		call = &cacheCall[V]{done: make(chan struct{})}
+		c.calls[k] = call
+		go c.load(context.WithoutCancel(ctx), k, fn, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		return c.nilVal, ctx.Err()
+	}
+}
+
+// load call loader, cache value when call not invalidated
+func (c *Cache[K, V]) load(ctx context.Context, k K, fn func(context.Context, K) (V, error), call *cacheCall[V]) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	val, err := c.callLoader(ctx, k, fn)
+	c.loads.Add(1)
+	if err != nil {
+		c.loadErrors.Add(1)
+	}
+
+	c.mu.Lock()
+	call.val, call.err = val, err
+	if !call.stale {
+		delete(c.calls, k)
+		if err == nil {
+			c.set(k, val, c.ttl)
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// callLoader call loader, panic as error
+func (c *Cache[K, V]) callLoader(ctx context.Context, k K, fn func(context.Context, K) (V, error)) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = c.nilVal, fmt.Errorf("cache loader panic: %v", r)
+		}
+	}()
+	return fn(ctx, k)
+}
+
+// Stats - stats snapshot
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Entries:     c.Len(),
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Loads:       c.loads.Load(),
+		LoadErrors:  c.loadErrors.Load(),
+	}
+}
+
+type cachePolicy[K comparable, V any] interface {
+	add(e *cacheEntry[K, V])
+	access(e *cacheEntry[K, V])
+	remove(e *cacheEntry[K, V])
+	victim() *cacheEntry[K, V]
+	clear()
+}
+
+// lruPolicy - recently used at front
+type lruPolicy[K comparable, V any] struct {
+	l *list.List
+}
+
+func (p *lruPolicy[K, V]) add(e *cacheEntry[K, V]) {
+	e.elem = p.l.PushFront(e)
+}
+
+func (p *lruPolicy[K, V]) access(e *cacheEntry[K, V]) {
+	p.l.MoveToFront(e.elem)
+}
+
+func (p *lruPolicy[K, V]) remove(e *cacheEntry[K, V]) {
+	p.l.Remove(e.elem)
+}
+
+func (p *lruPolicy[K, V]) victim() *cacheEntry[K, V] {
+	back := p.l.Back()
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*cacheEntry[K, V])
+}
+
+func (p *lruPolicy[K, V]) clear() {
+	p.l.Init()
+}
+
+// lfuPolicy - min heap by frequency, then access tick
+type lfuPolicy[K comparable, V any] struct {
+	entries []*cacheEntry[K, V]
+	tick    uint64
+}
+
+func (p *lfuPolicy[K, V]) Len() int {
+	return len(p.entries)
+}
+
+func (p *lfuPolicy[K, V]) Less(i, j int) bool {
+	a, b := p.entries[i], p.entries[j]
+	if a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	return a.tick < b.tick
+}
+
+func (p *lfuPolicy[K, V]) Swap(i, j int) {
+	p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	p.entries[i].index = i
+	p.entries[j].index = j
+}
+
+func (p *lfuPolicy[K, V]) Push(x any) {
+	e := x.(*cacheEntry[K, V])
+	e.index = len(p.entries)
+	p.entries = append(p.entries, e)
+}
+
+func (p *lfuPolicy[K, V]) Pop() any {
+	n := len(p.entries)
+	e := p.entries[n-1]
+	p.entries[n-1] = nil
+	p.entries = p.entries[:n-1]
+	e.index = -1
+	return e
+}
+
+func (p *lfuPolicy[K, V]) add(e *cacheEntry[K, V]) {
+	p.tick++
+	e.freq, e.tick = 1, p.tick
+	heap.Push(p, e)
+}
+
+func (p *lfuPolicy[K, V]) access(e *cacheEntry[K, V]) {
+	p.tick++
+	e.freq++
+	e.tick = p.tick
+	heap.Fix(p, e.index)
+}
+
+func (p *lfuPolicy[K, V]) remove(e *cacheEntry[K, V]) {
+	heap.Remove(p, e.index)
+}
+
+func (p *lfuPolicy[K, V]) victim() *cacheEntry[K, V] {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[0]
+}
+
+func (p *lfuPolicy[K, V]) clear() {
+	clear(p.entries)
+	p.entries = p.entries[:0]
+}
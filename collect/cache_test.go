@@ -0,0 +1,105 @@
+package collect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheGetOrLoadWaiterCtx(t *testing.T) {
+	c := NewCache[string, int](WithCacheCleanInterval[string, int](0))
+	release := make(chan struct{})
+	loaderCtxErr := make(chan error, 1)
+	fn := func(ctx context.Context, _ string) (int, error) {
+		<-release
+		loaderCtxErr <- ctx.Err()
+		return 1, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetOrLoad(ctx, "k", fn); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("caller should stop waiting by ctx, %v", err)
+	}
+
+	// waiter of in flight load, loader called once
+	done := make(chan int)
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+			return 2, nil
+		})
+		done <- v
+	}()
+	close(release)
+	if v := <-done; v != 1 {
+		t.Fatalf("waiter should get in flight value, %d", v)
+	}
+	if err := <-loaderCtxErr; err != nil {
+		t.Fatalf("loader ctx should not canceled by caller, %v", err)
+	}
+	if v, h := c.Get("k"); !h || v != 1 {
+		t.Fatalf("loaded value should cached, %d %v", v, h)
+	}
+}
+
+func TestCacheDeleteWhileLoading(t *testing.T) {
+	c := NewCache[string, int](WithCacheCleanInterval[string, int](0))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int)
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		done <- v
+	}()
+	<-started
+	c.Delete("k")
+	close(release)
+	if v := <-done; v != 1 {
+		t.Fatalf("loading caller should get loaded value, %d", v)
+	}
+	if _, h := c.Get("k"); h {
+		t.Fatal("value loaded before delete should not cached")
+	}
+
+	v, err := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+		return 2, nil
+	})
+	if err != nil || v != 2 {
+		t.Fatalf("load after delete should call loader again, %d %v", v, err)
+	}
+}
+
+func TestCacheLoadTimeout(t *testing.T) {
+	c := NewCache[string, int](WithCacheCleanInterval[string, int](0), WithCacheLoadTimeout[string, int](10*time.Millisecond))
+	_, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, _ string) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("loader should time out, %v", err)
+	}
+	if s := c.Stats(); s.Loads != 1 || s.LoadErrors != 1 {
+		t.Fatalf("load stats error, %+v", s)
+	}
+}
+
+func TestCacheLoader(t *testing.T) {
+	loads := 0
+	c := NewCache(WithCacheCleanInterval[string, int](0), WithCacheLoader(func(_ context.Context, k string) (int, error) {
+		loads++
+		return len(k), nil
+	}))
+	for i := 0; i < 2; i++ {
+		if v, err := c.Load(context.Background(), "abc"); err != nil || v != 3 || loads != 1 {
+			t.Fatalf("load %d %v, loads %d", v, err, loads)
+		}
+	}
+	if _, err := NewCache[string, int](WithCacheCleanInterval[string, int](0)).Load(context.Background(), "k"); err == nil {
+		t.Fatal("load without loader")
+	}
+}
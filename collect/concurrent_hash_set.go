@@ -0,0 +1,401 @@
+// concurrent hash set, sharded by hash, one RWMutex per shard
+// Range/ToSlice iterate snapshot taken with all shards read locked
+
+package collect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+type concurrentShard[T comparable] struct {
+	mu        sync.RWMutex
+	container map[T]struct{}
+}
+
+type ConcurrentHashSet[T comparable] struct {
+	shards []*concurrentShard[T]
+	mask   uint64
+	hash   func(T) uint64
+
+	allowNilVal bool
+	nilVal      T // do not init it
+}
+
+// NewConcurrentHashSet create concurrent hash set, shard num by cpu num
+func NewConcurrentHashSet[T comparable]() *ConcurrentHashSet[T] {
+	return NewConcurrentHashSetWithShards[T](runtime.GOMAXPROCS(0) * 4)
+}
+
+// NewConcurrentHashSetWithShards create concurrent hash set, shard num round up to power of 2
+func NewConcurrentHashSetWithShards[T comparable](shards int) *ConcurrentHashSet[T] {
+	return NewConcurrentHashSetWithHasher[T](shards, defaultHasher[T]())
+}
+
+// NewConcurrentHashSetWithHasher create concurrent hash set with custom hash func
+func NewConcurrentHashSetWithHasher[T comparable](shards int, hash func(T) uint64) *ConcurrentHashSet[T] {
+	n := 1
+	for n < shards && n < math.MaxInt32 {
+		n <<= 1
+	}
+	h := &ConcurrentHashSet[T]{
+		shards: make([]*concurrentShard[T], n),
+		mask:   uint64(n - 1),
+		hash:   hash,
+	}
+	for i := range h.shards {
+		h.shards[i] = &concurrentShard[T]{container: map[T]struct{}{}}
+	}
+	return h
+}
+
+// NewConcurrentHashSetBySlice - create concurrent hash set by slice
+func NewConcurrentHashSetBySlice[T comparable](arr []T) *ConcurrentHashSet[T] {
+	h := NewConcurrentHashSet[T]()
+	h.Add(arr...)
+	return h
+}
+
+// NewConcurrentHashSetByHashSet - create concurrent hash set by hash set
+func NewConcurrentHashSetByHashSet[T comparable](hs ...*HashSet[T]) *ConcurrentHashSet[T] {
+	h := NewConcurrentHashSet[T]()
+	for _, ele := range hs {
+		if ele == nil {
+			continue
+		}
+		ele.Range(func(t T) bool {
+			h.Add(t)
+			return true
+		})
+	}
+	return h
+}
+
+// defaultHasher - hash by kind of T, equal values same hash as ==
+// basic kinds, pointer and chan without allocation, struct, array and interface by reflect
+func defaultHasher[T comparable]() func(T) uint64 {
+	seed := maphash.MakeSeed()
+	typ := reflect.TypeFor[T]()
+	switch typ.Kind() {
+	case reflect.String:
+		return func(v T) uint64 {
+			return maphash.String(seed, *(*string)(unsafe.Pointer(&v)))
+		}
+	case reflect.Float32:
+		return func(v T) uint64 {
+			return mix64(floatBits(float64(*(*float32)(unsafe.Pointer(&v)))))
+		}
+	case reflect.Float64:
+		return func(v T) uint64 {
+			return mix64(floatBits(*(*float64)(unsafe.Pointer(&v))))
+		}
+	case reflect.Complex64:
+		return func(v T) uint64 {
+			c := *(*complex64)(unsafe.Pointer(&v))
+			return mix64(floatBits(float64(real(c))) ^ mix64(floatBits(float64(imag(c)))))
+		}
+	case reflect.Complex128:
+		return func(v T) uint64 {
+			c := *(*complex128)(unsafe.Pointer(&v))
+			return mix64(floatBits(real(c)) ^ mix64(floatBits(imag(c))))
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		// pointer and chan equal by address
+		switch typ.Size() {
+		case 1:
+			return func(v T) uint64 { return mix64(uint64(*(*uint8)(unsafe.Pointer(&v)))) }
+		case 2:
+			return func(v T) uint64 { return mix64(uint64(*(*uint16)(unsafe.Pointer(&v)))) }
+		case 4:
+			return func(v T) uint64 { return mix64(uint64(*(*uint32)(unsafe.Pointer(&v)))) }
+		default:
+			return func(v T) uint64 { return mix64(*(*uint64)(unsafe.Pointer(&v))) }
+		}
+	}
+	return func(v T) uint64 {
+		var mh maphash.Hash
+		mh.SetSeed(seed)
+		writeHash(&mh, reflect.ValueOf(&v).Elem())
+		return mh.Sum64()
+	}
+}
+
+// writeHash - write value into hash, fields compared by == written, blank fields skipped
+func writeHash(mh *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	writeUint := func(x uint64) {
+		binary.LittleEndian.PutUint64(buf[:], x)
+		_, _ = mh.Write(buf[:])
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(floatBits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeUint(floatBits(real(c)))
+		writeUint(floatBits(imag(c)))
+	case reflect.String:
+		_, _ = mh.WriteString(v.String())
+		// separate adjacent strings, {"ab", "c"} and {"a", "bc"}
+		writeUint(uint64(v.Len()))
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeHash(mh, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).Name == "_" {
+				continue
+			}
+			writeHash(mh, v.Field(i))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			writeUint(0)
+			return
+		}
+		e := v.Elem()
+		_, _ = mh.WriteString(e.Type().String())
+		writeHash(mh, e)
+	default:
+		// same as map key of interface holding uncomparable value
+		panic(fmt.Sprintf("concurrent hash set hash of unhashable type %s", v.Type()))
+	}
+}
+
+// floatBits - bits of float, -0 as 0 and all NaN same, equal float same bits
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	if math.IsNaN(f) {
+		return math.Float64bits(math.NaN())
+	}
+	return math.Float64bits(f)
+}
+
+// mix64 - spread integer bits, splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// shard - shard of element
+func (h *ConcurrentHashSet[T]) shard(ele T) *concurrentShard[T] {
+	return h.shards[h.hash(ele)&h.mask]
+}
+
+// rLockAll - read lock all shards in order
+func (h *ConcurrentHashSet[T]) rLockAll() {
+	for _, s := range h.shards {
+		s.mu.RLock()
+	}
+}
+
+// rUnlockAll - read unlock all shards
+func (h *ConcurrentHashSet[T]) rUnlockAll() {
+	for _, s := range h.shards {
+		s.mu.RUnlock()
+	}
+}
+
+// SetAllowNilVal set allow nil value, set before shared
+func (h *ConcurrentHashSet[T]) SetAllowNilVal(allowNilVal bool) {
+	h.allowNilVal = allowNilVal
+}
+
+// Add - add element
+func (h *ConcurrentHashSet[T]) Add(args ...T) *ConcurrentHashSet[T] {
+	for i := range args {
+		// same as HashSet, in most cases, init value is meaningless
+		if h.allowNilVal && args[i] == h.nilVal {
+			continue
+		}
+		s := h.shard(args[i])
+		s.mu.Lock()
+		s.container[args[i]] = nilStructObj
+		s.mu.Unlock()
+	}
+	return h
+}
+
+// AddIfAbsent - add element, return false when element exists or skipped nil value
+func (h *ConcurrentHashSet[T]) AddIfAbsent(ele T) bool {
+	if h.allowNilVal && ele == h.nilVal {
+		return false
+	}
+	s := h.shard(ele)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.container[ele]; ok {
+		return false
+	}
+	s.container[ele] = nilStructObj
+	return true
+}
+
+// Size - element num, snapshot of all shards
+func (h *ConcurrentHashSet[T]) Size() int {
+	h.rLockAll()
+	defer h.rUnlockAll()
+	n := 0
+	for _, s := range h.shards {
+		n += len(s.container)
+	}
+	return n
+}
+
+// Contains - contains ele
+func (h *ConcurrentHashSet[T]) Contains(ele T) bool {
+	s := h.shard(ele)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.container[ele]
+	return ok
+}
+
+// Remove - remove
+func (h *ConcurrentHashSet[T]) Remove(ele T) *ConcurrentHashSet[T] {
+	s := h.shard(ele)
+	s.mu.Lock()
+	delete(s.container, ele)
+	s.mu.Unlock()
+	return h
+}
+
+// Clear - clear container
+func (h *ConcurrentHashSet[T]) Clear() *ConcurrentHashSet[T] {
+	for _, s := range h.shards {
+		s.mu.Lock()
+		clear(s.container)
+		s.mu.Unlock()
+	}
+	return h
+}
+
+// Range - loop element of snapshot, fn can modify the set
+func (h *ConcurrentHashSet[T]) Range(fn func(T) bool) {
+	for _, ele := range h.ToSlice() {
+		if !fn(ele) {
+			break
+		}
+	}
+}
+
+// ToSlice - snapshot to slice
+func (h *ConcurrentHashSet[T]) ToSlice() []T {
+	h.rLockAll()
+	defer h.rUnlockAll()
+	n := 0
+	for _, s := range h.shards {
+		n += len(s.container)
+	}
+	rst := make([]T, 0, n)
+	for _, s := range h.shards {
+		for k := range s.container {
+			rst = append(rst, k)
+		}
+	}
+	return rst
+}
+
+// ToHashSet - snapshot to hash set
+func (h *ConcurrentHashSet[T]) ToHashSet() *HashSet[T] {
+	return NewHashSetBySlice(h.ToSlice())
+}
+
+// AddHashSet - add concurrent hash set
+func (h *ConcurrentHashSet[T]) AddHashSet(hs ...*ConcurrentHashSet[T]) *ConcurrentHashSet[T] {
+	for i := range hs {
+		if hs[i] == nil {
+			continue
+		}
+		h.Add(hs[i].ToSlice()...)
+	}
+	return h
+}
+
+// RemoveHashSet - remove concurrent hash set
+func (h *ConcurrentHashSet[T]) RemoveHashSet(hs ...*ConcurrentHashSet[T]) *ConcurrentHashSet[T] {
+	for i := range hs {
+		if hs[i] == nil {
+			continue
+		}
+		for _, ele := range hs[i].ToSlice() {
+			h.Remove(ele)
+		}
+	}
+	return h
+}
+
+// Intersection get intersection set
+func (h *ConcurrentHashSet[T]) Intersection(target *ConcurrentHashSet[T]) *ConcurrentHashSet[T] {
+	if target == nil || target.Size() < 1 || h.Size() < 1 {
+		return nil
+	}
+
+	a := h
+	b := target
+	if a.Size() > b.Size() {
+		a = target
+		b = h
+	}
+
+	rst := NewConcurrentHashSetWithHasher[T](len(h.shards), h.hash)
+	a.Range(func(ele T) bool {
+		if b.Contains(ele) {
+			rst.Add(ele)
+		}
+		return true
+	})
+	return rst
+}
+
+// Union get Union set
+func (h *ConcurrentHashSet[T]) Union(target *ConcurrentHashSet[T]) *ConcurrentHashSet[T] {
+	rst := NewConcurrentHashSetWithHasher[T](len(h.shards), h.hash)
+	rst.AddHashSet(target, h)
+	return rst
+}
+
+// Except get Except by set
+func (h *ConcurrentHashSet[T]) Except(target *ConcurrentHashSet[T]) *ConcurrentHashSet[T] {
+	rst := NewConcurrentHashSetWithHasher[T](len(h.shards), h.hash)
+	h.Range(func(ele T) bool {
+		if target != nil && !target.Contains(ele) {
+			rst.Add(ele)
+		}
+		return true
+	})
+	return rst
+}
+
+// CompareConcurrentHashSet compare concurrent hash set, snapshot of each set
+func CompareConcurrentHashSet[T comparable](a, b *ConcurrentHashSet[T]) (*HashSet[T], *HashSet[T]) {
+	return CompareHashSet(a.ToHashSet(), b.ToHashSet())
+}
@@ -0,0 +1,219 @@
+package collect
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+const benchSetSize = 1 << 12
+
+func benchKeys() []string {
+	keys := make([]string, benchSetSize)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func TestConcurrentHashSetFloatKey(t *testing.T) {
+	h := NewConcurrentHashSet[float64]()
+	h.Add(0.0)
+	if !h.Contains(math.Copysign(0, -1)) {
+		t.Fatal("-0 should equal 0")
+	}
+	h.Add(1.5)
+	if !h.Contains(1.5) || h.Contains(2.5) {
+		t.Fatal("contains float error")
+	}
+}
+
+type setKey struct {
+	Name  string
+	Score float64
+	Tags  [2]string
+	Ref   *int
+	Extra any
+	_     int
+}
+
+func TestConcurrentHashSetCompositeKey(t *testing.T) {
+	n := 1
+	h := NewConcurrentHashSetWithShards[setKey](64)
+	h.Add(setKey{Name: "a", Score: 0, Tags: [2]string{"x", "y"}, Ref: &n, Extra: 1})
+	// -0 equal 0, same pointer, same dynamic type and value
+	if !h.Contains(setKey{Name: "a", Score: math.Copysign(0, -1), Tags: [2]string{"x", "y"}, Ref: &n, Extra: 1}) {
+		t.Fatal("equal struct not found")
+	}
+	m := 1
+	for _, k := range []setKey{
+		{Name: "a", Tags: [2]string{"x", "y"}, Ref: &m, Extra: 1},
+		{Name: "a", Tags: [2]string{"x", "y"}, Ref: &n, Extra: int64(1)},
+		{Name: "a", Tags: [2]string{"xy", ""}, Ref: &n, Extra: 1},
+	} {
+		if h.Contains(k) {
+			t.Fatalf("not equal struct found, %+v", k)
+		}
+	}
+
+	arr := NewConcurrentHashSet[[3]int]()
+	arr.Add([3]int{1, 2, 3})
+	if !arr.Contains([3]int{1, 2, 3}) || arr.Contains([3]int{3, 2, 1}) {
+		t.Fatal("array key")
+	}
+
+	iface := NewConcurrentHashSet[any]()
+	iface.Add(1, "1", nil, setKey{Name: "b"})
+	if !iface.Contains(1) || !iface.Contains("1") || !iface.Contains(nil) || !iface.Contains(setKey{Name: "b"}) ||
+		iface.Contains(uint(1)) || iface.Size() != 4 {
+		t.Fatal("interface key")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("unhashable dynamic type not panic")
+			}
+		}()
+		iface.Add([]int{1})
+	}()
+}
+
+func TestConcurrentHashSetRandom(t *testing.T) {
+	type key struct {
+		A int8
+		B string
+	}
+	rnd := rand.New(rand.NewSource(1))
+	h := NewConcurrentHashSetWithShards[key](8)
+	model := make(map[key]struct{})
+	for i := 0; i < 20000; i++ {
+		k := key{A: int8(rnd.Intn(16)), B: strconv.Itoa(rnd.Intn(16))}
+		switch rnd.Intn(3) {
+		case 0:
+			h.Add(k)
+			model[k] = struct{}{}
+		case 1:
+			_, exists := model[k]
+			if h.AddIfAbsent(k) == exists {
+				t.Fatalf("add if absent %+v, exists %v", k, exists)
+			}
+			model[k] = struct{}{}
+		default:
+			h.Remove(k)
+			delete(model, k)
+		}
+		_, exists := model[k]
+		if h.Contains(k) != exists || h.Size() != len(model) {
+			t.Fatalf("step %d key %+v, size %d want %d", i, k, h.Size(), len(model))
+		}
+	}
+}
+
+func TestConcurrentHashSetAllowNilVal(t *testing.T) {
+	h := NewConcurrentHashSet[string]()
+	h.SetAllowNilVal(true)
+	h.Add("")
+	if h.AddIfAbsent("") || h.Size() != 0 {
+		t.Fatal("nil value added")
+	}
+	if !h.AddIfAbsent("a") || h.AddIfAbsent("a") {
+		t.Fatal("add if absent")
+	}
+}
+
+func BenchmarkHashSetAdd(b *testing.B) {
+	keys := benchKeys()
+	h := NewHashSet[string]()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Add(keys[i%benchSetSize])
+	}
+}
+
+func BenchmarkConcurrentHashSetAdd(b *testing.B) {
+	keys := benchKeys()
+	h := NewConcurrentHashSet[string]()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Add(keys[i%benchSetSize])
+	}
+}
+
+func BenchmarkHashSetContains(b *testing.B) {
+	keys := benchKeys()
+	h := NewHashSetBySlice(keys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Contains(keys[i%benchSetSize])
+	}
+}
+
+func BenchmarkConcurrentHashSetContains(b *testing.B) {
+	keys := benchKeys()
+	h := NewConcurrentHashSetBySlice(keys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Contains(keys[i%benchSetSize])
+	}
+}
+
+// BenchmarkHashSetParallel - hash set guarded by one RWMutex, 1 write per 10 ops
+func BenchmarkHashSetParallel(b *testing.B) {
+	keys := benchKeys()
+	h := NewHashSetBySlice(keys)
+	var mu sync.RWMutex
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%benchSetSize]
+			if i%10 == 0 {
+				mu.Lock()
+				h.Add(key)
+				mu.Unlock()
+			} else {
+				mu.RLock()
+				h.Contains(key)
+				mu.RUnlock()
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkConcurrentHashSetParallel - 1 write per 10 ops
+func BenchmarkConcurrentHashSetParallel(b *testing.B) {
+	keys := benchKeys()
+	h := NewConcurrentHashSetBySlice(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%benchSetSize]
+			if i%10 == 0 {
+				h.Add(key)
+			} else {
+				h.Contains(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkHashSetToSlice(b *testing.B) {
+	h := NewHashSetBySlice(benchKeys())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ToSlice()
+	}
+}
+
+func BenchmarkConcurrentHashSetToSlice(b *testing.B) {
+	h := NewConcurrentHashSetBySlice(benchKeys())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ToSlice()
+	}
+}
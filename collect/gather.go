@@ -1,35 +1,188 @@
 // gather use to batch collect
+// element put into queue, batch call by size or timeout
+// queue full handled by policy, block / drop oldest / drop newest
+// callback error or panic retried by retry func, then error hook
+// workers > 1 call batches concurrently, batches of same partition key by same worker in order
+// callback must not call Flush or Close without timeout of its gather, they wait the callback itself
+// options typed by element, e.g. NewGather(time.Second, 100, call, WithGatherWorkers[Event](4))
 
 package collect
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+type GatherPolicy int
+
+const (
+	GatherBlock      GatherPolicy = iota // wait for queue space
+	GatherDropOldest                     // drop oldest queued element
+	GatherDropNewest                     // drop element being put
+)
+
+var (
+	ErrGatherClosed = errors.New("gather closed")
+	ErrGatherFull   = errors.New("gather queue full")
+)
+
+// GatherRetryFunc - decide retry of failed batch by error and attempt from 1, return wait before retry
+type GatherRetryFunc func(err error, attempt int) (time.Duration, bool)
+
+type GatherStats struct {
+	Queued   int    // element num in queue
+	Pending  int    // element num in batch not called
+	InFlight int    // batch num dispatched to worker not finished
+	Batches  uint64 // batch num called success
+	Items    uint64 // element num called success
+	Dropped  uint64 // element num dropped by policy
+	Retries  uint64 // retry num
+	Failed   uint64 // batch num failed after retry
+}
+
+type gatherConfig[T any] struct {
+	queueSize int
+	policy    GatherPolicy
+	retry     GatherRetryFunc
+	onError   func([]T, error)
+	workers   int
+	inFlight  int
+	partition func(T) string
+}
+
+// GatherOption - option of gather with element type T
+type GatherOption[T any] func(cfg *gatherConfig[T])
+
+// WithGatherQueueSize - queue size, default size*2
+func WithGatherQueueSize[T any](n int) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.queueSize = n
+	}
+}
+
+// WithGatherPolicy - policy when queue full, default GatherBlock
+func WithGatherPolicy[T any](policy GatherPolicy) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.policy = policy
+	}
+}
+
+// WithGatherRetry - retry failed batch with fixed interval
+func WithGatherRetry[T any](retries int, interval time.Duration) GatherOption[T] {
+	return WithGatherRetryFunc[T](func(err error, attempt int) (time.Duration, bool) {
+		return interval, attempt <= retries
+	})
+}
+
+// WithGatherRetryFunc - retry failed batch by fn
+func WithGatherRetryFunc[T any](fn GatherRetryFunc) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.retry = fn
+	}
+}
+
+// WithGatherErrorHook - fn called with batch failed after retry
+func WithGatherErrorHook[T any](fn func([]T, error)) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.onError = fn
+	}
+}
+
+// WithGatherWorkers - concurrent batch call by n worker goroutine, default call in gather goroutine
+func WithGatherWorkers[T any](n int) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.workers = n
+	}
+}
+
+// WithGatherMaxInFlight - max batch dispatched to worker not finished, default worker num
+func WithGatherMaxInFlight[T any](n int) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.inFlight = n
+	}
+}
+
+// WithGatherPartition - batch element by key, batches of same key called in order
+func WithGatherPartition[T any](fn func(T) string) GatherOption[T] {
+	return func(cfg *gatherConfig[T]) {
+		cfg.partition = fn
+	}
+}
+
 type Gather[T any] struct {
 	timeout time.Duration
 	size    int
-	call    func([]T, *Gather[T])
+	call    func([]T, *Gather[T]) error
+	policy  GatherPolicy
+	retry   GatherRetryFunc
+	onError func([]T, error)
 
-	ch chan T
+	partition func(T) string
+	seed      maphash.Seed
 
-	batch []T
+	mu      sync.RWMutex // guard closed, put hold read lock
+	closed  bool
+	ch      chan T
+	closing chan struct{} // closed when Close called, wake blocked put
+	abort   chan struct{} // closed when ctx of Close done, stop retry
+	closeMu sync.Mutex
+
+	flushCh chan chan struct{}
+
+	batches map[string][]T // batch of partition key, "" without partition
+
+	queues   []chan []T    // worker queue, one shared without partition
+	sem      chan struct{} // in flight bound
+	inFlight sync.WaitGroup
+	workers  sync.WaitGroup
 
 	done chan struct{}
 
 	tck *time.Ticker
+
+	called  atomic.Uint64
+	items   atomic.Uint64
+	dropped atomic.Uint64
+	retries atomic.Uint64
+	failed  atomic.Uint64
+	pending atomic.Int64
 }
 
-// NewGather create gather struct, use one goroutine
-func NewGather[T any](timeout time.Duration, size int, call func([]T, *Gather[T])) *Gather[T] {
+// NewGather create gather struct, use one goroutine, and worker goroutines by WithGatherWorkers
+func NewGather[T any](timeout time.Duration, size int, call func([]T, *Gather[T]) error, opts ...GatherOption[T]) *Gather[T] {
+	size = max(size, 1)
+	cfg := &gatherConfig[T]{
+		queueSize: size * 2,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	g := &Gather[T]{
 		timeout: timeout,
 		size:    size,
-		ch:      make(chan T, size*2),
-		batch:   make([]T, 0, size),
 		call:    call,
+		policy:  cfg.policy,
+		retry:   cfg.retry,
+		onError: cfg.onError,
+		ch:      make(chan T, max(cfg.queueSize, 1)),
+		flushCh: make(chan chan struct{}),
+		closing: make(chan struct{}),
+		abort:   make(chan struct{}),
+		batches: map[string][]T{},
+		seed:    maphash.MakeSeed(),
 		tck:     time.NewTicker(timeout),
 		done:    make(chan struct{}),
+
+		partition: cfg.partition,
+	}
+	if cfg.workers > 1 {
+		g.startWorkers(cfg.workers, cfg.inFlight)
 	}
 
 	// goroutine
@@ -42,31 +195,95 @@ func NewGather[T any](timeout time.Duration, size int, call func([]T, *Gather[T]
 func (g *Gather[T]) init() {
 	defer func() {
 		g.tck.Stop()
-	}()
-
-	if g.size <= 1 {
-		for v := range g.ch {
-			g.call([]T{v}, g)
+		for _, q := range g.queues {
+			close(q)
 		}
-		return
-	}
+		g.workers.Wait()
+		close(g.done)
+	}()
 
 	for {
 		select {
 		case ele, ok := <-g.ch:
 			if !ok {
 				g.callback()
-				close(g.done)
-				g.tck.Stop()
 				return
 			}
-			g.batch = append(g.batch, ele)
-			if len(g.batch) >= g.size {
-				g.callback()
-				g.resetTicker()
-			}
+			g.add(ele)
 		case <-g.tck.C:
 			g.callback()
+		case ack := <-g.flushCh:
+			g.drain()
+			g.callback()
+			g.resetTicker()
+			g.inFlight.Wait()
+			close(ack)
+		}
+	}
+}
+
+// startWorkers start worker goroutines, shared queue without partition
+func (g *Gather[T]) startWorkers(n, inFlight int) {
+	if inFlight < 1 {
+		inFlight = n
+	}
+	g.sem = make(chan struct{}, inFlight)
+
+	queueNum := 1
+	if g.partition != nil {
+		queueNum = n
+	}
+	g.queues = make([]chan []T, queueNum)
+	for i := range g.queues {
+		g.queues[i] = make(chan []T, inFlight)
+	}
+
+	g.workers.Add(n)
+	for i := 0; i < n; i++ {
+		go g.work(g.queues[i%queueNum])
+	}
+}
+
+// work call batches of queue
+func (g *Gather[T]) work(queue chan []T) {
+	defer g.workers.Done()
+	for batch := range queue {
+		g.process(batch)
+		<-g.sem
+		g.inFlight.Done()
+	}
+}
+
+// add add element to batch, call when batch full
+func (g *Gather[T]) add(ele T) {
+	key := ""
+	if g.partition != nil {
+		key = g.partition(ele)
+	}
+	batch := append(g.batches[key], ele)
+	g.pending.Add(1)
+	if len(batch) < g.size {
+		g.batches[key] = batch
+		return
+	}
+	delete(g.batches, key)
+	g.dispatch(key, batch)
+	if len(g.batches) == 0 {
+		g.resetTicker()
+	}
+}
+
+// drain move queued element to batch, no wait
+func (g *Gather[T]) drain() {
+	for n := len(g.ch); n > 0; n-- {
+		select {
+		case ele, ok := <-g.ch:
+			if !ok {
+				return
+			}
+			g.add(ele)
+		default:
+			return
 		}
 	}
 }
@@ -76,24 +293,220 @@ func (g *Gather[T]) resetTicker() {
 	g.tck.Reset(g.timeout)
 }
 
-// Close - close gather
-func (g *Gather[T]) Close() <-chan struct{} {
-	close(g.ch)
+// Flush - call batch of element put before, return after callback
+// not call in callback, deadlock as flush wait the callback
+func (g *Gather[T]) Flush() error {
+	ack := make(chan struct{})
+	select {
+	case g.flushCh <- ack:
+	case <-g.done:
+		return ErrGatherClosed
+	}
+	<-ack
+	return nil
+}
+
+// Close - stop put, wait queued element called or ctx done
+// blocked put return ErrGatherClosed, retry of failed batch stop when ctx done
+func (g *Gather[T]) Close(ctx context.Context) error {
+	g.closeOnce(g.closing)
+
+	g.mu.Lock()
+	if !g.closed {
+		g.closed = true
+		close(g.ch)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-g.done:
+		return nil
+	case <-ctx.Done():
+		g.closeOnce(g.abort)
+		return ctx.Err()
+	}
+}
+
+// closeOnce close channel if not closed
+func (g *Gather[T]) closeOnce(ch chan struct{}) {
+	g.closeMu.Lock()
+	defer g.closeMu.Unlock()
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+// CloseTimeout - close, wait at most timeout
+func (g *Gather[T]) CloseTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return g.Close(ctx)
+}
+
+// Done - closed after all element called
+func (g *Gather[T]) Done() <-chan struct{} {
 	return g.done
 }
 
-// Put add element
-func (g *Gather[T]) Put(ele T) {
-	g.ch <- ele
+// Put add element, queue full handled by policy
+func (g *Gather[T]) Put(ele T) error {
+	return g.PutContext(context.Background(), ele)
+}
+
+// PutContext add element, block policy wait until ctx done or Close called
+func (g *Gather[T]) PutContext(ctx context.Context, ele T) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		return ErrGatherClosed
+	}
+	if g.offer(ele) {
+		return nil
+	}
+
+	switch g.policy {
+	case GatherDropNewest:
+		g.dropped.Add(1)
+		return ErrGatherFull
+	case GatherDropOldest:
+		g.evict(ele)
+		return nil
+	}
+
+	// read lock held, Close wake it by closing before lock
+	select {
+	case g.ch <- ele:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-g.closing:
+		return ErrGatherClosed
+	}
+}
+
+// TryPut add element without wait, ErrGatherFull when queue full unless drop oldest policy
+func (g *Gather[T]) TryPut(ele T) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		return ErrGatherClosed
+	}
+	if g.offer(ele) {
+		return nil
+	}
+	if g.policy == GatherDropOldest {
+		g.evict(ele)
+		return nil
+	}
+	g.dropped.Add(1)
+	return ErrGatherFull
+}
+
+// offer send element without wait
+func (g *Gather[T]) offer(ele T) bool {
+	select {
+	case g.ch <- ele:
+		return true
+	default:
+		return false
+	}
 }
 
-// callback call user function
+// evict drop oldest queued element until element sent
+func (g *Gather[T]) evict(ele T) {
+	for !g.offer(ele) {
+		select {
+		case <-g.ch:
+			g.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Stats - stats snapshot
+func (g *Gather[T]) Stats() GatherStats {
+	return GatherStats{
+		Queued:   len(g.ch),
+		Pending:  int(g.pending.Load()),
+		InFlight: len(g.sem),
+		Batches:  g.called.Load(),
+		Items:    g.items.Load(),
+		Dropped:  g.dropped.Load(),
+		Retries:  g.retries.Load(),
+		Failed:   g.failed.Load(),
+	}
+}
+
+// callback call batch of all partition
 func (g *Gather[T]) callback() {
-	if len(g.batch) < 1 {
+	for key, batch := range g.batches {
+		delete(g.batches, key)
+		g.dispatch(key, batch)
+	}
+}
+
+// dispatch call batch in gather goroutine, or send to worker, wait when in flight full
+func (g *Gather[T]) dispatch(key string, batch []T) {
+	if len(g.queues) == 0 {
+		// sync call
+		g.process(batch)
+		return
+	}
+	g.sem <- struct{}{}
+	g.inFlight.Add(1)
+	g.queues[maphash.String(g.seed, key)%uint64(len(g.queues))] <- batch
+}
+
+// process call batch with retry, no retry after ctx of Close done
+func (g *Gather[T]) process(batch []T) {
+	defer g.pending.Add(-int64(len(batch)))
+
+	for attempt := 1; ; attempt++ {
+		err := g.safeCall(batch)
+		if err == nil {
+			g.called.Add(1)
+			g.items.Add(uint64(len(batch)))
+			return
+		}
+		if g.retry != nil {
+			if wait, ok := g.retry(err, attempt); ok && g.sleep(wait) {
+				g.retries.Add(1)
+				continue
+			}
+		}
+		g.failed.Add(1)
+		if g.onError != nil {
+			g.onError(batch, err)
+		}
 		return
 	}
-	batch := g.batch
-	g.batch = make([]T, 0, g.size)
-	// sync call
-	g.call(batch, g)
+}
+
+// sleep wait before retry, false when aborted
+func (g *Gather[T]) sleep(wait time.Duration) bool {
+	select {
+	case <-g.abort:
+		return false
+	default:
+	}
+	tm := time.NewTimer(wait)
+	defer tm.Stop()
+	select {
+	case <-tm.C:
+		return true
+	case <-g.abort:
+		return false
+	}
+}
+
+// safeCall call user function, panic as error
+func (g *Gather[T]) safeCall(batch []T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("gather callback panic: %v", r)
+		}
+	}()
+	return g.call(batch, g)
 }
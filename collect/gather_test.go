@@ -0,0 +1,294 @@
+package collect
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatherSink - record batches called
+type gatherSink struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (s *gatherSink) call(batch []int, _ *Gather[int]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, slices.Clone(batch))
+	return nil
+}
+
+// items - all element called in order
+func (s *gatherSink) items() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rst := make([]int, 0)
+	for _, b := range s.batches {
+		rst = append(rst, b...)
+	}
+	return rst
+}
+
+func TestGatherFlushBySize(t *testing.T) {
+	sink := &gatherSink{}
+	g := NewGather(time.Hour, 3, sink.call)
+	for i := 0; i < 7; i++ {
+		if err := g.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.batches) != 3 || len(sink.batches[0]) != 3 || len(sink.batches[2]) != 1 ||
+		!slices.Equal(sink.items(), []int{0, 1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("batches %v", sink.batches)
+	}
+	if err := g.CloseTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Put(1); !errors.Is(err, ErrGatherClosed) {
+		t.Fatalf("put after close, %v", err)
+	}
+	if err := g.Flush(); !errors.Is(err, ErrGatherClosed) {
+		t.Fatalf("flush after close, %v", err)
+	}
+	if st := g.Stats(); st.Batches != 3 || st.Items != 7 || st.Pending != 0 {
+		t.Fatalf("stats %+v", st)
+	}
+}
+
+func TestGatherFlushByInterval(t *testing.T) {
+	sink := &gatherSink{}
+	g := NewGather(time.Millisecond*20, 100, sink.call)
+	defer g.CloseTimeout(time.Second)
+	_ = g.Put(1)
+	_ = g.Put(2)
+	deadline := time.Now().Add(time.Second)
+	for len(sink.items()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 5)
+	}
+	if !slices.Equal(sink.items(), []int{1, 2}) {
+		t.Fatalf("interval batches %v", sink.batches)
+	}
+}
+
+func TestGatherCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	calls := atomic.Int64{}
+	g := NewGather(time.Hour, 1, func(batch []int, _ *Gather[int]) error {
+		calls.Add(1)
+		<-release
+		return errors.New("fail")
+	}, WithGatherRetry[int](100, time.Hour))
+
+	_ = g.Put(1)
+	_ = g.Put(2)
+	start := time.Now()
+	if err := g.CloseTimeout(time.Millisecond * 50); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("close timeout, %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("close not returned by timeout")
+	}
+	// retry wait stopped after close timeout, remaining batch failed without retry wait
+	close(release)
+	select {
+	case <-g.Done():
+	case <-time.After(time.Second * 2):
+		t.Fatal("gather not done after close timeout")
+	}
+	if st := g.Stats(); st.Failed != 2 || st.Retries != 0 || calls.Load() != 2 {
+		t.Fatalf("stats %+v calls %d", st, calls.Load())
+	}
+}
+
+// blockedGather - gather with callback blocked until release, queue size 2
+func blockedGather(policy GatherPolicy, sink *gatherSink) (*Gather[int], chan struct{}) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	g := NewGather(time.Hour, 1, func(batch []int, g *Gather[int]) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return sink.call(batch, g)
+	}, WithGatherQueueSize[int](2), WithGatherPolicy[int](policy))
+	// first element taken by callback, queue empty
+	_ = g.Put(0)
+	<-started
+	return g, release
+}
+
+func TestGatherPolicy(t *testing.T) {
+	// drop newest
+	sink := &gatherSink{}
+	g, release := blockedGather(GatherDropNewest, sink)
+	_ = g.Put(1)
+	_ = g.Put(2)
+	if err := g.Put(3); !errors.Is(err, ErrGatherFull) {
+		t.Fatalf("drop newest put, %v", err)
+	}
+	close(release)
+	_ = g.CloseTimeout(time.Second)
+	if !slices.Equal(sink.items(), []int{0, 1, 2}) || g.Stats().Dropped != 1 {
+		t.Fatalf("drop newest %v %+v", sink.items(), g.Stats())
+	}
+
+	// drop oldest
+	sink = &gatherSink{}
+	g, release = blockedGather(GatherDropOldest, sink)
+	for i := 1; i <= 4; i++ {
+		if err := g.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	_ = g.CloseTimeout(time.Second)
+	if !slices.Equal(sink.items(), []int{0, 3, 4}) || g.Stats().Dropped != 2 {
+		t.Fatalf("drop oldest %v %+v", sink.items(), g.Stats())
+	}
+
+	// block until ctx done, try put not wait
+	sink = &gatherSink{}
+	g, release = blockedGather(GatherBlock, sink)
+	_ = g.Put(1)
+	_ = g.Put(2)
+	if err := g.TryPut(3); !errors.Is(err, ErrGatherFull) {
+		t.Fatalf("try put, %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err := g.PutContext(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("blocked put ctx, %v", err)
+	}
+	// blocked put woken by close
+	putErr := make(chan error, 1)
+	go func() {
+		putErr <- g.Put(4)
+	}()
+	time.Sleep(time.Millisecond * 20)
+	closed := make(chan error, 1)
+	go func() {
+		closed <- g.CloseTimeout(time.Second)
+	}()
+	if err := <-putErr; !errors.Is(err, ErrGatherClosed) {
+		t.Fatalf("blocked put on close, %v", err)
+	}
+	close(release)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sink.items(), []int{0, 1, 2}) {
+		t.Fatalf("block policy %v", sink.items())
+	}
+}
+
+func TestGatherRetry(t *testing.T) {
+	attempts := atomic.Int64{}
+	var failed []string
+	g := NewGather(time.Hour, 2, func(batch []string, _ *Gather[string]) error {
+		n := attempts.Add(1)
+		if batch[0] == "panic" {
+			panic("broken")
+		}
+		if n < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	}, WithGatherRetry[string](2, time.Millisecond), WithGatherErrorHook(func(batch []string, err error) {
+		failed = append(failed, batch...)
+	}))
+
+	_ = g.Put("a")
+	_ = g.Put("b")
+	_ = g.Flush()
+	if st := g.Stats(); st.Batches != 1 || st.Retries != 2 || st.Failed != 0 {
+		t.Fatalf("retried batch stats %+v", st)
+	}
+
+	// panic retried then error hook
+	_ = g.Put("panic")
+	_ = g.CloseTimeout(time.Second)
+	if st := g.Stats(); st.Failed != 1 || st.Retries != 4 || !slices.Equal(failed, []string{"panic"}) {
+		t.Fatalf("failed batch stats %+v, hook %v", st, failed)
+	}
+}
+
+func TestGatherWorkers(t *testing.T) {
+	var running, peak atomic.Int64
+	sink := &gatherSink{}
+	g := NewGather(time.Hour, 1, func(batch []int, g *Gather[int]) error {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond * 20)
+		running.Add(-1)
+		return sink.call(batch, g)
+	}, WithGatherWorkers[int](4), WithGatherMaxInFlight[int](2), WithGatherQueueSize[int](16))
+
+	for i := 0; i < 8; i++ {
+		_ = g.Put(i)
+	}
+	// flush wait in flight batches
+	if err := g.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.items()) != 8 || g.Stats().InFlight != 0 {
+		t.Fatalf("flushed %v %+v", sink.items(), g.Stats())
+	}
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("concurrent batches %d, want max in flight 2", p)
+	}
+	if err := g.CloseTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGatherPartitionOrder(t *testing.T) {
+	type event struct {
+		key string
+		seq int
+	}
+	mu := sync.Mutex{}
+	got := map[string][]int{}
+	g := NewGather(time.Millisecond*5, 3, func(batch []event, _ *Gather[event]) error {
+		// batch hold one partition, random delay reorder batches of different worker
+		time.Sleep(time.Duration(batch[0].seq%3) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range batch {
+			if e.key != batch[0].key {
+				return errors.New("mixed partition batch")
+			}
+			got[e.key] = append(got[e.key], e.seq)
+		}
+		return nil
+	}, WithGatherWorkers[event](4), WithGatherPartition(func(e event) string {
+		return e.key
+	}))
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	for i := 0; i < 500; i++ {
+		if err := g.Put(event{key: keys[i%len(keys)], seq: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.CloseTimeout(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+	if st := g.Stats(); st.Failed != 0 || st.Items != 500 {
+		t.Fatalf("stats %+v", st)
+	}
+	for _, k := range keys {
+		if len(got[k]) != 100 || !slices.IsSorted(got[k]) {
+			t.Fatalf("partition %s order %v", k, got[k])
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// hash multiset, element with occurrence count
+
+package collect
+
+type HashMultiset[T comparable] struct {
+	container map[T]int
+
+	total int
+}
+
+// NewHashMultiset create hash multiset
+func NewHashMultiset[T comparable]() *HashMultiset[T] {
+	return &HashMultiset[T]{
+		container: map[T]int{},
+	}
+}
+
+// NewHashMultisetBySlice - create hash multiset by slice
+func NewHashMultisetBySlice[T comparable](arr []T) *HashMultiset[T] {
+	h := NewHashMultiset[T]()
+	h.Add(arr...)
+	return h
+}
+
+// Add - add element, count once per element
+func (h *HashMultiset[T]) Add(args ...T) *HashMultiset[T] {
+	for i := range args {
+		h.container[args[i]]++
+	}
+	h.total += len(args)
+	return h
+}
+
+// AddCount - add element occurrences
+func (h *HashMultiset[T]) AddCount(ele T, n int) *HashMultiset[T] {
+	if n <= 0 {
+		return h
+	}
+	h.container[ele] += n
+	h.total += n
+	return h
+}
+
+// AddHashSet - add each element of hash sets once
+func (h *HashMultiset[T]) AddHashSet(hs ...*HashSet[T]) *HashMultiset[T] {
+	for i := range hs {
+		hs[i].Range(func(ele T) bool {
+			h.Add(ele)
+			return true
+		})
+	}
+	return h
+}
+
+// Remove - remove one occurrence
+func (h *HashMultiset[T]) Remove(ele T) *HashMultiset[T] {
+	return h.RemoveCount(ele, 1)
+}
+
+// RemoveCount - remove occurrences, delete element when count to zero
+func (h *HashMultiset[T]) RemoveCount(ele T, n int) *HashMultiset[T] {
+	c, ok := h.container[ele]
+	if !ok || n <= 0 {
+		return h
+	}
+	if n >= c {
+		delete(h.container, ele)
+		h.total -= c
+		return h
+	}
+	h.container[ele] = c - n
+	h.total -= n
+	return h
+}
+
+// RemoveAll - remove all occurrences of element
+func (h *HashMultiset[T]) RemoveAll(ele T) *HashMultiset[T] {
+	h.total -= h.container[ele]
+	delete(h.container, ele)
+	return h
+}
+
+// Count - element occurrences
+func (h *HashMultiset[T]) Count(ele T) int {
+	return h.container[ele]
+}
+
+// Contains - contains ele
+func (h *HashMultiset[T]) Contains(ele T) bool {
+	_, ok := h.container[ele]
+	return ok
+}
+
+// Size - distinct element number
+func (h *HashMultiset[T]) Size() int {
+	return len(h.container)
+}
+
+// Total - total occurrences
+func (h *HashMultiset[T]) Total() int {
+	return h.total
+}
+
+// Clear - clear container
+func (h *HashMultiset[T]) Clear() *HashMultiset[T] {
+	clear(h.container)
+	h.total = 0
+	return h
+}
+
+// Range - loop element and count
+func (h *HashMultiset[T]) Range(fn func(T, int) bool) {
+	for ele, c := range h.container {
+		if !fn(ele, c) {
+			break
+		}
+	}
+}
+
+// ToSlice - distinct elements to slice
+func (h *HashMultiset[T]) ToSlice() []T {
+	rst := make([]T, 0, len(h.container))
+	for k := range h.container {
+		rst = append(rst, k)
+	}
+	return rst
+}
+
+// ToHashSet - distinct elements to hash set
+func (h *HashMultiset[T]) ToHashSet() *HashSet[T] {
+	rst := NewHashSet[T]()
+	for k := range h.container {
+		rst.Add(k)
+	}
+	return rst
+}
+
+// Filter - distinct elements which count match fn
+func (h *HashMultiset[T]) Filter(fn func(T, int) bool) []T {
+	rst := make([]T, 0)
+	for ele, c := range h.container {
+		if fn(ele, c) {
+			rst = append(rst, ele)
+		}
+	}
+	return rst
+}
@@ -0,0 +1,104 @@
+package collect
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkHashMultiset - compare multiset with reference map of element -> count
+func checkHashMultiset(t *testing.T, h *HashMultiset[int], ref map[int]int) {
+	t.Helper()
+	total := 0
+	for e, c := range ref {
+		total += c
+		if h.Count(e) != c || !h.Contains(e) {
+			t.Fatalf("count %d = %d, want %d", e, h.Count(e), c)
+		}
+	}
+	if h.Size() != len(ref) || h.Total() != total {
+		t.Fatalf("size %d total %d, want %d %d", h.Size(), h.Total(), len(ref), total)
+	}
+	got := map[int]int{}
+	h.Range(func(e, c int) bool {
+		got[e] = c
+		return true
+	})
+	if !maps.Equal(got, ref) {
+		t.Fatalf("range %v, want %v", got, ref)
+	}
+	keys := slices.Sorted(maps.Keys(ref))
+	if s := h.ToSlice(); !slices.Equal(slices.Sorted(slices.Values(s)), keys) {
+		t.Fatalf("to slice %v, want %v", s, keys)
+	}
+	if hs := h.ToHashSet(); hs.Size() != len(ref) {
+		t.Fatalf("to hash set size %d, want %d", hs.Size(), len(ref))
+	}
+	want := make([]int, 0)
+	for _, e := range keys {
+		if ref[e] > 1 {
+			want = append(want, e)
+		}
+	}
+	if s := h.Filter(func(_, c int) bool { return c > 1 }); !slices.Equal(slices.Sorted(slices.Values(s)), want) {
+		t.Fatalf("filter %v, want %v", s, want)
+	}
+}
+
+func TestHashMultisetRandomOps(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	h := NewHashMultiset[int]()
+	ref := map[int]int{}
+	// remove n occurrences from reference
+	remove := func(e, n int) {
+		if n <= 0 {
+			return
+		}
+		if ref[e] -= n; ref[e] <= 0 {
+			delete(ref, e)
+		}
+	}
+	for i := 0; i < 5000; i++ {
+		e := rnd.Intn(50)
+		n := rnd.Intn(5) - 1
+		switch op := rnd.Intn(12); {
+		case op < 3:
+			h.Add(e, e)
+			ref[e] += 2
+		case op < 5:
+			// not positive count ignored
+			h.AddCount(e, n)
+			if n > 0 {
+				ref[e] += n
+			}
+		case op < 6:
+			hs := NewHashSetBySlice([]int{e, e, e + 1})
+			h.AddHashSet(hs)
+			ref[e]++
+			ref[e+1]++
+		case op < 8:
+			h.Remove(e)
+			remove(e, 1)
+		case op < 10:
+			h.RemoveCount(e, n)
+			remove(e, n)
+		case op < 11:
+			h.RemoveAll(e)
+			delete(ref, e)
+		default:
+			if rnd.Intn(20) == 0 {
+				h.Clear()
+				clear(ref)
+			}
+		}
+		if _, exists := ref[e]; h.Contains(e) != exists {
+			t.Fatalf("contains %d, want %v", e, exists)
+		}
+		checkHashMultiset(t, h, ref)
+	}
+
+	if h = NewHashMultisetBySlice([]int{1, 2, 1}); h.Count(1) != 2 || h.Total() != 3 {
+		t.Fatalf("by slice count %d total %d", h.Count(1), h.Total())
+	}
+}
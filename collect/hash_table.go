@@ -7,10 +7,17 @@
 // r1 1, 2, 3
 // r2 4, 5, 6
 
+// cells indexed by row and by column, row and column lookup both by hash
+// Row/Column return views backed by table, ToMap/ToColumnMap return copies
+
 package collect
 
+import "maps"
+
 type HashTable[R, C comparable, V any] struct {
 	container map[R]map[C]V
+	columns   map[C]map[R]V // column index
+	size      int
 	nilVal    V // do not init it
 }
 
@@ -18,18 +25,56 @@ type HashTable[R, C comparable, V any] struct {
 func NewHashTable[R, C comparable, V any]() *HashTable[R, C, V] {
 	return &HashTable[R, C, V]{
 		container: make(map[R]map[C]V),
+		columns:   make(map[C]map[R]V),
 	}
 }
 
-// Add - add element
+// NewHashTableByMap - create hash table by nested map, row -> column -> value
+func NewHashTableByMap[R, C comparable, V any](m map[R]map[C]V) *HashTable[R, C, V] {
+	t := NewHashTable[R, C, V]()
+	t.AddMap(m)
+	return t
+}
+
+// Add - add element, replace exists
 func (t *HashTable[R, C, V]) Add(r R, c C, v V) {
 	columns, h := t.container[r]
 	if !h {
-		t.container[r] = map[C]V{
-			c: v,
+		columns = make(map[C]V)
+		t.container[r] = columns
+	}
+	if _, h = columns[c]; !h {
+		t.size++
+	}
+	columns[c] = v
+
+	rows, h := t.columns[c]
+	if !h {
+		rows = make(map[R]V)
+		t.columns[c] = rows
+	}
+	rows[r] = v
+}
+
+// AddMap - add elements of nested map, row -> column -> value
+func (t *HashTable[R, C, V]) AddMap(m map[R]map[C]V) {
+	for r, columns := range m {
+		for c, v := range columns {
+			t.Add(r, c, v)
 		}
-	} else {
-		columns[c] = v
+	}
+}
+
+// AddHashTable - add elements of hash tables
+func (t *HashTable[R, C, V]) AddHashTable(ts ...*HashTable[R, C, V]) {
+	for i := range ts {
+		if ts[i] == nil {
+			continue
+		}
+		ts[i].Range(func(r R, c C, v V) bool {
+			t.Add(r, c, v)
+			return true
+		})
 	}
 }
 
@@ -37,10 +82,28 @@ func (t *HashTable[R, C, V]) Add(r R, c C, v V) {
 func (t *HashTable[R, C, V]) Get(r R, c C) (V, bool) {
 	columns, h := t.container[r]
 	if !h {
-		v, h := columns[c]
-		return v, h
+		return t.nilVal, false
 	}
-	return t.nilVal, false
+	v, h := columns[c]
+	return v, h
+}
+
+// Contains - contains element of row and column
+func (t *HashTable[R, C, V]) Contains(r R, c C) bool {
+	_, h := t.Get(r, c)
+	return h
+}
+
+// ContainsRow - contains row
+func (t *HashTable[R, C, V]) ContainsRow(r R) bool {
+	_, h := t.container[r]
+	return h
+}
+
+// ContainsColumn - contains column
+func (t *HashTable[R, C, V]) ContainsColumn(c C) bool {
+	_, h := t.columns[c]
+	return h
 }
 
 // Delete - delete element by row and column
@@ -54,21 +117,254 @@ func (t *HashTable[R, C, V]) Delete(r R, c C) {
 		return
 	}
 	delete(columns, c)
+	if len(columns) == 0 {
+		delete(t.container, r)
+	}
+
+	rows := t.columns[c]
+	delete(rows, r)
+	if len(rows) == 0 {
+		delete(t.columns, c)
+	}
+	t.size--
+}
+
+// RemoveRow - remove row, return removed column -> value
+func (t *HashTable[R, C, V]) RemoveRow(r R) map[C]V {
+	columns, h := t.container[r]
+	if !h {
+		return nil
+	}
+	delete(t.container, r)
+	for c := range columns {
+		rows := t.columns[c]
+		delete(rows, r)
+		if len(rows) == 0 {
+			delete(t.columns, c)
+		}
+	}
+	t.size -= len(columns)
+	return columns
+}
+
+// RemoveColumn - remove column, return removed row -> value
+func (t *HashTable[R, C, V]) RemoveColumn(c C) map[R]V {
+	rows, h := t.columns[c]
+	if !h {
+		return nil
+	}
+	delete(t.columns, c)
+	for r := range rows {
+		columns := t.container[r]
+		delete(columns, c)
+		if len(columns) == 0 {
+			delete(t.container, r)
+		}
+	}
+	t.size -= len(rows)
+	return rows
+}
+
+// Size - cell num
+func (t *HashTable[R, C, V]) Size() int {
+	return t.size
+}
+
+// RowSize - row num
+func (t *HashTable[R, C, V]) RowSize() int {
+	return len(t.container)
+}
+
+// ColumnSize - column num
+func (t *HashTable[R, C, V]) ColumnSize() int {
+	return len(t.columns)
+}
+
+// Row - view of row, column -> value, changes of table visible, modify it modify table
+func (t *HashTable[R, C, V]) Row(r R) *TableRow[R, C, V] {
+	return &TableRow[R, C, V]{table: t, row: r}
+}
+
+// Column - view of column, row -> value, changes of table visible, modify it modify table
+func (t *HashTable[R, C, V]) Column(c C) *TableColumn[R, C, V] {
+	return &TableColumn[R, C, V]{table: t, column: c}
+}
+
+// RowKeys - rows
+func (t *HashTable[R, C, V]) RowKeys() []R {
+	rst := make([]R, 0, len(t.container))
+	for r := range t.container {
+		rst = append(rst, r)
+	}
+	return rst
+}
+
+// ColumnKeys - columns
+func (t *HashTable[R, C, V]) ColumnKeys() []C {
+	rst := make([]C, 0, len(t.columns))
+	for c := range t.columns {
+		rst = append(rst, c)
+	}
+	return rst
 }
 
 // Clear - clear container
 func (t *HashTable[R, C, V]) Clear() {
 	clear(t.container)
+	clear(t.columns)
+	t.size = 0
 }
 
-// Range - loop element
+// Range - loop element, stop when fn return false
 func (t *HashTable[R, C, V]) Range(fn func(R, C, V) bool) {
 	for r, columns := range t.container {
 		for c, ele := range columns {
-			fnR := fn(r, c, ele)
-			if !fnR {
-				break
+			if !fn(r, c, ele) {
+				return
 			}
 		}
 	}
 }
+
+// RangeRow - loop element of row, stop when fn return false
+func (t *HashTable[R, C, V]) RangeRow(r R, fn func(C, V) bool) {
+	for c, ele := range t.container[r] {
+		if !fn(c, ele) {
+			return
+		}
+	}
+}
+
+// RangeColumn - loop element of column, stop when fn return false
+func (t *HashTable[R, C, V]) RangeColumn(c C, fn func(R, V) bool) {
+	for r, ele := range t.columns[c] {
+		if !fn(r, ele) {
+			return
+		}
+	}
+}
+
+// ToMap - copy to nested map, row -> column -> value
+func (t *HashTable[R, C, V]) ToMap() map[R]map[C]V {
+	rst := make(map[R]map[C]V, len(t.container))
+	for r, columns := range t.container {
+		rst[r] = maps.Clone(columns)
+	}
+	return rst
+}
+
+// ToColumnMap - copy to nested map, column -> row -> value
+func (t *HashTable[R, C, V]) ToColumnMap() map[C]map[R]V {
+	rst := make(map[C]map[R]V, len(t.columns))
+	for c, rows := range t.columns {
+		rst[c] = maps.Clone(rows)
+	}
+	return rst
+}
+
+// Transpose - new table with row and column swapped
+func (t *HashTable[R, C, V]) Transpose() *HashTable[C, R, V] {
+	return NewHashTableByMap(t.columns)
+}
+
+// TableRow - row view of hash table, empty when row not exists
+type TableRow[R, C comparable, V any] struct {
+	table *HashTable[R, C, V]
+	row   R
+}
+
+// Get - get element of column
+func (v *TableRow[R, C, V]) Get(c C) (V, bool) {
+	return v.table.Get(v.row, c)
+}
+
+// Contains - contains column
+func (v *TableRow[R, C, V]) Contains(c C) bool {
+	return v.table.Contains(v.row, c)
+}
+
+// Add - add element of column into table, replace exists
+func (v *TableRow[R, C, V]) Add(c C, val V) {
+	v.table.Add(v.row, c, val)
+}
+
+// Delete - delete element of column from table
+func (v *TableRow[R, C, V]) Delete(c C) {
+	v.table.Delete(v.row, c)
+}
+
+// Size - element num of row
+func (v *TableRow[R, C, V]) Size() int {
+	return len(v.table.container[v.row])
+}
+
+// Keys - columns of row
+func (v *TableRow[R, C, V]) Keys() []C {
+	columns := v.table.container[v.row]
+	rst := make([]C, 0, len(columns))
+	for c := range columns {
+		rst = append(rst, c)
+	}
+	return rst
+}
+
+// Range - loop element, stop when fn return false
+func (v *TableRow[R, C, V]) Range(fn func(C, V) bool) {
+	v.table.RangeRow(v.row, fn)
+}
+
+// ToMap - copy of row, column -> value, nil when row not exists
+func (v *TableRow[R, C, V]) ToMap() map[C]V {
+	return maps.Clone(v.table.container[v.row])
+}
+
+// TableColumn - column view of hash table, empty when column not exists
+type TableColumn[R, C comparable, V any] struct {
+	table  *HashTable[R, C, V]
+	column C
+}
+
+// Get - get element of row
+func (v *TableColumn[R, C, V]) Get(r R) (V, bool) {
+	return v.table.Get(r, v.column)
+}
+
+// Contains - contains row
+func (v *TableColumn[R, C, V]) Contains(r R) bool {
+	return v.table.Contains(r, v.column)
+}
+
+// Add - add element of row into table, replace exists
+func (v *TableColumn[R, C, V]) Add(r R, val V) {
+	v.table.Add(r, v.column, val)
+}
+
+// Delete - delete element of row from table
+func (v *TableColumn[R, C, V]) Delete(r R) {
+	v.table.Delete(r, v.column)
+}
+
+// Size - element num of column
+func (v *TableColumn[R, C, V]) Size() int {
+	return len(v.table.columns[v.column])
+}
+
+// Keys - rows of column
+func (v *TableColumn[R, C, V]) Keys() []R {
+	rows := v.table.columns[v.column]
+	rst := make([]R, 0, len(rows))
+	for r := range rows {
+		rst = append(rst, r)
+	}
+	return rst
+}
+
+// Range - loop element, stop when fn return false
+func (v *TableColumn[R, C, V]) Range(fn func(R, V) bool) {
+	v.table.RangeColumn(v.column, fn)
+}
+
+// ToMap - copy of column, row -> value, nil when column not exists
+func (v *TableColumn[R, C, V]) ToMap() map[R]V {
+	return maps.Clone(v.table.columns[v.column])
+}
@@ -0,0 +1,162 @@
+package collect
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkHashTable - compare table with reference map of [row, column] -> value
+func checkHashTable(t *testing.T, tb *HashTable[int, int, int], ref map[[2]int]int) {
+	t.Helper()
+	if tb.Size() != len(ref) {
+		t.Fatalf("size %d, want %d", tb.Size(), len(ref))
+	}
+	rows := map[int]int{}
+	columns := map[int]int{}
+	for k, v := range ref {
+		rows[k[0]]++
+		columns[k[1]]++
+		if got, h := tb.Get(k[0], k[1]); !h || got != v {
+			t.Fatalf("get %v = %d %v, want %d", k, got, h, v)
+		}
+	}
+	if tb.RowSize() != len(rows) || tb.ColumnSize() != len(columns) {
+		t.Fatalf("row/column size %d/%d, want %d/%d", tb.RowSize(), tb.ColumnSize(), len(rows), len(columns))
+	}
+	for r, n := range rows {
+		if !tb.ContainsRow(r) || tb.Row(r).Size() != n || len(tb.Row(r).ToMap()) != n || len(tb.Row(r).Keys()) != n {
+			t.Fatalf("row %d size %d, want %d", r, tb.Row(r).Size(), n)
+		}
+	}
+	for c, n := range columns {
+		if !tb.ContainsColumn(c) || tb.Column(c).Size() != n || len(tb.Column(c).ToMap()) != n || len(tb.Column(c).Keys()) != n {
+			t.Fatalf("column %d size %d, want %d", c, tb.Column(c).Size(), n)
+		}
+	}
+
+	n := 0
+	for r, cv := range tb.ToMap() {
+		for c, v := range cv {
+			n++
+			if ref[[2]int{r, c}] != v {
+				t.Fatalf("to map [%d %d] = %d, want %d", r, c, v, ref[[2]int{r, c}])
+			}
+		}
+	}
+	for c, rv := range tb.ToColumnMap() {
+		for r, v := range rv {
+			n--
+			if ref[[2]int{r, c}] != v {
+				t.Fatalf("to column map [%d %d] = %d, want %d", r, c, v, ref[[2]int{r, c}])
+			}
+		}
+	}
+	if n != 0 {
+		t.Fatal("to map and to column map size differ")
+	}
+
+	tt := tb.Transpose()
+	if tt.Size() != len(ref) {
+		t.Fatalf("transpose size %d, want %d", tt.Size(), len(ref))
+	}
+	for k, v := range ref {
+		if got, h := tt.Get(k[1], k[0]); !h || got != v {
+			t.Fatalf("transpose get %v = %d %v, want %d", k, got, h, v)
+		}
+	}
+}
+
+func TestHashTableRandomOps(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tb := NewHashTable[int, int, int]()
+	ref := map[[2]int]int{}
+	for i := 0; i < 5000; i++ {
+		r, c, v := rnd.Intn(8), rnd.Intn(8), rnd.Int()
+		switch op := rnd.Intn(10); {
+		case op < 6:
+			tb.Add(r, c, v)
+			ref[[2]int{r, c}] = v
+		case op < 8:
+			tb.Delete(r, c)
+			delete(ref, [2]int{r, c})
+		case op < 9:
+			removed := tb.RemoveRow(r)
+			for k, v := range ref {
+				if k[0] != r {
+					continue
+				}
+				if removed[k[1]] != v {
+					t.Fatalf("remove row %d column %d = %d, want %d", r, k[1], removed[k[1]], v)
+				}
+				delete(removed, k[1])
+				delete(ref, k)
+			}
+			if len(removed) != 0 {
+				t.Fatalf("remove row %d extra %v", r, removed)
+			}
+		default:
+			removed := tb.RemoveColumn(c)
+			for k, v := range ref {
+				if k[1] != c {
+					continue
+				}
+				if removed[k[0]] != v {
+					t.Fatalf("remove column %d row %d = %d, want %d", c, k[0], removed[k[0]], v)
+				}
+				delete(removed, k[0])
+				delete(ref, k)
+			}
+			if len(removed) != 0 {
+				t.Fatalf("remove column %d extra %v", c, removed)
+			}
+		}
+		checkHashTable(t, tb, ref)
+	}
+}
+
+func TestHashTableViews(t *testing.T) {
+	tb := NewHashTable[int, int, int]()
+	// view of not exists row, later added visible
+	row, column := tb.Row(1), tb.Column(1)
+	if row.Size() != 0 || row.ToMap() != nil || column.Contains(1) {
+		t.Fatal("view of empty table")
+	}
+	tb.Add(1, 1, 1)
+	row.Add(2, 2)
+	column.Add(2, 3)
+	tb.Add(1, 3, 3)
+	if v, h := column.Get(2); !h || v != 3 || !tb.Contains(1, 2) || !tb.Contains(2, 1) {
+		t.Fatal("modify of view not in table")
+	}
+	if row.Size() != 3 || column.Size() != 2 || tb.Column(2).Size() != 1 {
+		t.Fatalf("view size row %d column %d", row.Size(), column.Size())
+	}
+
+	// delete by view keep column index
+	row.Delete(1)
+	if tb.Contains(1, 1) || column.Size() != 1 || tb.Size() != 3 {
+		t.Fatalf("delete by view, column %v", column.ToMap())
+	}
+	n := 0
+	row.Range(func(c, v int) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatal("range of view not stopped")
+	}
+	tb.RemoveRow(1)
+	if row.Size() != 0 || row.Contains(2) {
+		t.Fatal("removed row visible in view")
+	}
+
+	copied := column.ToMap()
+	copied[5] = 5
+	if column.Contains(5) {
+		t.Fatal("to map of view should be copy")
+	}
+	tb.ToMap()[2][4] = 4
+	if tb.Contains(2, 4) {
+		t.Fatal("to map should be copy")
+	}
+}
@@ -0,0 +1,124 @@
+// linked hash set, element in insertion order
+// add exists element keep its position
+
+package collect
+
+import (
+	"container/list"
+	"iter"
+)
+
+type LinkedHashSet[T comparable] struct {
+	container map[T]*list.Element
+	order     *list.List
+	nilVal    T // do not init it
+}
+
+// NewLinkedHashSet - create linked hash set
+func NewLinkedHashSet[T comparable]() *LinkedHashSet[T] {
+	return &LinkedHashSet[T]{
+		container: map[T]*list.Element{},
+		order:     list.New(),
+	}
+}
+
+// NewLinkedHashSetBySlice - create linked hash set by slice, order of first occurrence
+func NewLinkedHashSetBySlice[T comparable](arr []T) *LinkedHashSet[T] {
+	h := NewLinkedHashSet[T]()
+	h.Add(arr...)
+	return h
+}
+
+// Add - add element
+func (h *LinkedHashSet[T]) Add(args ...T) *LinkedHashSet[T] {
+	for i := range args {
+		if _, ok := h.container[args[i]]; ok {
+			continue
+		}
+		h.container[args[i]] = h.order.PushBack(args[i])
+	}
+	return h
+}
+
+// Remove - remove element
+func (h *LinkedHashSet[T]) Remove(ele T) *LinkedHashSet[T] {
+	if e, ok := h.container[ele]; ok {
+		h.order.Remove(e)
+		delete(h.container, ele)
+	}
+	return h
+}
+
+// Contains - contains element
+func (h *LinkedHashSet[T]) Contains(ele T) bool {
+	_, ok := h.container[ele]
+	return ok
+}
+
+// Size - element num
+func (h *LinkedHashSet[T]) Size() int {
+	return len(h.container)
+}
+
+// Clear - clear container
+func (h *LinkedHashSet[T]) Clear() *LinkedHashSet[T] {
+	clear(h.container)
+	h.order.Init()
+	return h
+}
+
+// First - first added element
+func (h *LinkedHashSet[T]) First() (T, bool) {
+	if e := h.order.Front(); e != nil {
+		return e.Value.(T), true
+	}
+	return h.nilVal, false
+}
+
+// Last - last added element
+func (h *LinkedHashSet[T]) Last() (T, bool) {
+	if e := h.order.Back(); e != nil {
+		return e.Value.(T), true
+	}
+	return h.nilVal, false
+}
+
+// Range - loop element in insertion order, stop when fn return false
+func (h *LinkedHashSet[T]) Range(fn func(T) bool) {
+	for e := h.order.Front(); e != nil; e = e.Next() {
+		if !fn(e.Value.(T)) {
+			return
+		}
+	}
+}
+
+// All - elements in insertion order
+func (h *LinkedHashSet[T]) All() iter.Seq[T] {
+	return h.Range
+}
+
+// Backward - elements in reverse insertion order
+func (h *LinkedHashSet[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for e := h.order.Back(); e != nil; e = e.Prev() {
+			if !yield(e.Value.(T)) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice - elements in insertion order
+func (h *LinkedHashSet[T]) ToSlice() []T {
+	rst := make([]T, 0, len(h.container))
+	h.Range(func(ele T) bool {
+		rst = append(rst, ele)
+		return true
+	})
+	return rst
+}
+
+// ToHashSet - to hash set
+func (h *LinkedHashSet[T]) ToHashSet() *HashSet[T] {
+	return NewHashSetBySlice(h.ToSlice())
+}
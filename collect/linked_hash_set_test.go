@@ -0,0 +1,78 @@
+package collect
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkLinkedHashSet - compare set with reference elements in insertion order
+func checkLinkedHashSet(t *testing.T, h *LinkedHashSet[int], eles []int) {
+	t.Helper()
+	if h.Size() != len(eles) || !slices.Equal(h.ToSlice(), eles) || !slices.Equal(slices.Collect(h.All()), eles) {
+		t.Fatalf("elements %v, want %v", h.ToSlice(), eles)
+	}
+	got := slices.Collect(h.Backward())
+	slices.Reverse(got)
+	if !slices.Equal(got, eles) {
+		t.Fatalf("backward %v, want reverse of %v", got, eles)
+	}
+	first, fh := h.First()
+	last, lh := h.Last()
+	if fh != (len(eles) > 0) || lh != fh || (fh && (first != eles[0] || last != eles[len(eles)-1])) {
+		t.Fatalf("first %d %v last %d %v, want ends of %v", first, fh, last, lh, eles)
+	}
+	if hs := h.ToHashSet(); hs.Size() != len(eles) {
+		t.Fatalf("to hash set size %d, want %d", hs.Size(), len(eles))
+	}
+
+	// early stop
+	if len(eles) > 1 {
+		n := 0
+		h.Range(func(int) bool {
+			n++
+			return n < 2
+		})
+		if n != 2 {
+			t.Fatalf("range not stopped, %d", n)
+		}
+	}
+}
+
+func TestLinkedHashSetRandomOps(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	h := NewLinkedHashSet[int]()
+	eles := make([]int, 0)
+	for i := 0; i < 5000; i++ {
+		e := rnd.Intn(100)
+		idx := slices.Index(eles, e)
+		switch op := rnd.Intn(10); {
+		case op < 5:
+			// exists element keep position
+			h.Add(e)
+			if idx < 0 {
+				eles = append(eles, e)
+			}
+		case op < 8:
+			h.Remove(e)
+			if idx >= 0 {
+				eles = slices.Delete(eles, idx, idx+1)
+			}
+		case op < 9:
+			if h.Contains(e) != (idx >= 0) {
+				t.Fatalf("contains %d, want %v", e, idx >= 0)
+			}
+		default:
+			if rnd.Intn(50) == 0 {
+				h.Clear()
+				eles = eles[:0]
+			}
+		}
+		checkLinkedHashSet(t, h, eles)
+	}
+
+	// by slice, order of first occurrence
+	if h = NewLinkedHashSetBySlice([]int{3, 1, 3, 2, 1}); !slices.Equal(h.ToSlice(), []int{3, 1, 2}) {
+		t.Fatalf("by slice %v", h.ToSlice())
+	}
+}
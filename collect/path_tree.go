@@ -1,25 +1,285 @@
+// path tree, trie keyed by path of K
+// e.g. url segments ["api", "v1", "user"], domain labels ["com", "example", "www"]
+// radix mode compress single child chain into one node, less node for long sparse path
+
 package collect
 
-type PathTreeNode[K comparable, V any] struct {
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+type PathTreeNode[K cmp.Ordered, V any] struct {
+	label    []K // edge from parent, one K unless radix mode
 	v        V
-	children map[K]PathTreeNode[K, V]
+	has      bool
+	count    int // value num of subtree
+	children map[K]*PathTreeNode[K, V]
+}
+
+type PathTree[K cmp.Ordered, V any] struct {
+	root   *PathTreeNode[K, V]
+	radix  bool
+	nilVal V // do not init it
 }
-type PathTree[K comparable, V any] struct {
-	root *PathTreeNode[K, V]
+
+type pathTreeConfig struct {
+	radix bool
+}
+
+type PathTreeOption func(cfg *pathTreeConfig)
+
+// WithRadix - compressed path tree
+func WithRadix() PathTreeOption {
+	return func(cfg *pathTreeConfig) {
+		cfg.radix = true
+	}
 }
 
-func NewPathTree[K comparable, V any]() *PathTree[K, V] {
+// NewPathTree - create path tree
+func NewPathTree[K cmp.Ordered, V any](opts ...PathTreeOption) *PathTree[K, V] {
+	cfg := &pathTreeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
 	return &PathTree[K, V]{
-		root: &PathTreeNode[K, V]{
-			children: make(map[K]PathTreeNode[K, V]),
-		},
+		root:  newPathTreeNode[K, V](nil),
+		radix: cfg.radix,
+	}
+}
+
+func newPathTreeNode[K cmp.Ordered, V any](label []K) *PathTreeNode[K, V] {
+	return &PathTreeNode[K, V]{
+		label:    label,
+		children: make(map[K]*PathTreeNode[K, V]),
 	}
 }
 
+// Add - add value of path, replace exists
 func (t *PathTree[K, V]) Add(ks []K, v V) {
+	n := t.root
+	path := []*PathTreeNode[K, V]{n}
+	for len(ks) > 0 {
+		child, h := n.children[ks[0]]
+		if !h {
+			label := ks[:1]
+			if t.radix {
+				label = ks
+			}
+			child = newPathTreeNode[K, V](slices.Clone(label))
+			n.children[ks[0]] = child
+		}
+
+		l := commonPrefixLen(child.label, ks)
+		if l < len(child.label) {
+			// split edge, only in radix mode
+			mid := newPathTreeNode[K, V](child.label[:l])
+			mid.count = child.count
+			child.label = child.label[l:]
+			mid.children[child.label[0]] = child
+			n.children[ks[0]] = mid
+			child = mid
+		}
+
+		path = append(path, child)
+		n = child
+		ks = ks[l:]
+	}
+
+	if !n.has {
+		for _, p := range path {
+			p.count++
+		}
+	}
+	n.v = v
+	n.has = true
+}
+
+// Get - get value of path
+func (t *PathTree[K, V]) Get(ks []K) (V, bool) {
+	n := t.root
+	for len(ks) > 0 {
+		child, h := n.children[ks[0]]
+		if !h || !hasPathPrefix(ks, child.label) {
+			return t.nilVal, false
+		}
+		n = child
+		ks = ks[len(child.label):]
+	}
+	if !n.has {
+		return t.nilVal, false
+	}
+	return n.v, true
+}
+
+// Contains - contains value of path
+func (t *PathTree[K, V]) Contains(ks []K) bool {
+	_, h := t.Get(ks)
+	return h
+}
+
+// Delete - delete value of path, empty node removed
+func (t *PathTree[K, V]) Delete(ks []K) bool {
+	n := t.root
+	path := []*PathTreeNode[K, V]{n}
+	for len(ks) > 0 {
+		child, h := n.children[ks[0]]
+		if !h || !hasPathPrefix(ks, child.label) {
+			return false
+		}
+		path = append(path, child)
+		n = child
+		ks = ks[len(child.label):]
+	}
+	if !n.has {
+		return false
+	}
+
+	n.v = t.nilVal
+	n.has = false
+	for _, p := range path {
+		p.count--
+	}
+
+	i := len(path) - 1
+	for ; i > 0; i-- {
+		p := path[i]
+		if p.has || len(p.children) > 0 {
+			break
+		}
+		delete(path[i-1].children, p.label[0])
+	}
+	if t.radix && i > 0 {
+		mergePathTreeNode(path[i])
+	}
+	return true
+}
+
+// mergePathTreeNode - merge only child into node without value
+func mergePathTreeNode[K cmp.Ordered, V any](n *PathTreeNode[K, V]) {
+	if n.has || len(n.children) != 1 {
+		return
+	}
+	for _, child := range n.children {
+		n.label = append(slices.Clone(n.label), child.label...)
+		n.v = child.v
+		n.has = child.has
+		n.children = child.children
+	}
+}
+
+// LongestPrefix - value of longest path which is prefix of ks
+func (t *PathTree[K, V]) LongestPrefix(ks []K) ([]K, V, bool) {
+	n := t.root
+	depth, best := 0, -1
+	v := t.nilVal
+	if n.has {
+		best, v = 0, n.v
+	}
+
+	rest := ks
+	for len(rest) > 0 {
+		child, h := n.children[rest[0]]
+		if !h || !hasPathPrefix(rest, child.label) {
+			break
+		}
+		n = child
+		depth += len(child.label)
+		rest = rest[len(child.label):]
+		if n.has {
+			best, v = depth, n.v
+		}
+	}
+
+	if best < 0 {
+		return nil, t.nilVal, false
+	}
+	return ks[:best], v, true
+}
+
+// locate - node of which subtree contain all path with prefix, and path of the node
+func (t *PathTree[K, V]) locate(prefix []K) (*PathTreeNode[K, V], []K) {
+	n := t.root
+	var base []K
+	for len(prefix) > 0 {
+		child, h := n.children[prefix[0]]
+		if !h {
+			return nil, nil
+		}
+		l := commonPrefixLen(child.label, prefix)
+		if l < len(prefix) && l < len(child.label) {
+			return nil, nil
+		}
+		base = append(base, child.label...)
+		n = child
+		if l == len(prefix) {
+			break
+		}
+		prefix = prefix[l:]
+	}
+	return n, base
+}
 
+// CountPrefix - value num of path with prefix
+func (t *PathTree[K, V]) CountPrefix(prefix []K) int {
+	n, _ := t.locate(prefix)
+	if n == nil {
+		return 0
+	}
+	return n.count
+}
+
+// Walk - loop path with prefix in sorted order, stop when fn return false
+func (t *PathTree[K, V]) Walk(prefix []K, fn func([]K, V) bool) {
+	n, base := t.locate(prefix)
+	if n == nil {
+		return
+	}
+	walkPathTree(n, base, fn)
+}
+
+// walkPathTree - depth first, value of node before children
+func walkPathTree[K cmp.Ordered, V any](n *PathTreeNode[K, V], ks []K, fn func([]K, V) bool) bool {
+	if n.has && !fn(slices.Clone(ks), n.v) {
+		return false
+	}
+	for _, k := range slices.Sorted(maps.Keys(n.children)) {
+		child := n.children[k]
+		if !walkPathTree(child, append(ks, child.label...), fn) {
+			return false
+		}
+	}
+	return true
 }
 
-func (t *PathTree[K, V]) Get(ks []K) {
+// Range - loop all path in sorted order
+func (t *PathTree[K, V]) Range(fn func([]K, V) bool) {
+	t.Walk(nil, fn)
+}
+
+// Size - value num
+func (t *PathTree[K, V]) Size() int {
+	return t.root.count
+}
+
+// Clear - clear tree
+func (t *PathTree[K, V]) Clear() {
+	t.root = newPathTreeNode[K, V](nil)
+}
+
+// commonPrefixLen - length of common prefix
+func commonPrefixLen[K comparable](a, b []K) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
 
+// hasPathPrefix - ks start with prefix
+func hasPathPrefix[K comparable](ks, prefix []K) bool {
+	return len(ks) >= len(prefix) && commonPrefixLen(ks, prefix) == len(prefix)
 }
@@ -0,0 +1,135 @@
+package collect
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// randPath - path of letters a-c, length 0-4
+func randPath(rnd *rand.Rand) []string {
+	p := make([]string, rnd.Intn(5))
+	for i := range p {
+		p[i] = string(rune('a' + rnd.Intn(3)))
+	}
+	return p
+}
+
+// checkPathTree - compare tree with reference map of joined path -> value
+func checkPathTree(t *testing.T, tree *PathTree[string, int], ref map[string]int, query []string) {
+	t.Helper()
+	if tree.Size() != len(ref) {
+		t.Fatalf("size %d, want %d", tree.Size(), len(ref))
+	}
+	q := strings.Join(query, "")
+
+	// longest prefix
+	best := -1
+	for k := range ref {
+		if strings.HasPrefix(q, k) && len(k) > best {
+			best = len(k)
+		}
+	}
+	ks, v, h := tree.LongestPrefix(query)
+	if (best >= 0) != h || (h && (len(ks) != best || v != ref[q[:best]])) {
+		t.Fatalf("longest prefix of %q = %v %d %v, want len %d", q, ks, v, h, best)
+	}
+
+	// walk in sorted order, node before children
+	want := make([]string, 0)
+	for k := range ref {
+		if strings.HasPrefix(k, q) {
+			want = append(want, k)
+		}
+	}
+	sort.Strings(want)
+	if n := tree.CountPrefix(query); n != len(want) {
+		t.Fatalf("count prefix %q = %d, want %d", q, n, len(want))
+	}
+	got := make([]string, 0, len(want))
+	tree.Walk(query, func(p []string, v int) bool {
+		k := strings.Join(p, "")
+		if v != ref[k] {
+			t.Fatalf("walk %q value %d, want %d", k, v, ref[k])
+		}
+		got = append(got, k)
+		return true
+	})
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("walk %q = %v, want %v", q, got, want)
+	}
+
+	// early stop
+	if len(want) > 1 {
+		n := 0
+		tree.Walk(query, func([]string, int) bool {
+			n++
+			return n < 2
+		})
+		if n != 2 {
+			t.Fatalf("walk %q not stopped, %d", q, n)
+		}
+	}
+}
+
+func TestPathTreeRandomOps(t *testing.T) {
+	for _, radix := range []bool{false, true} {
+		rnd := rand.New(rand.NewSource(1))
+		opts := []PathTreeOption{}
+		if radix {
+			opts = append(opts, WithRadix())
+		}
+		tree := NewPathTree[string, int](opts...)
+		ref := map[string]int{}
+		for i := 0; i < 5000; i++ {
+			p := randPath(rnd)
+			k := strings.Join(p, "")
+			switch op := rnd.Intn(10); {
+			case op < 5:
+				v := rnd.Int()
+				tree.Add(p, v)
+				ref[k] = v
+			case op < 8:
+				_, exists := ref[k]
+				if tree.Delete(p) != exists {
+					t.Fatalf("radix %v delete %q, exists %v", radix, k, exists)
+				}
+				delete(ref, k)
+			case op < 9:
+				v, h := tree.Get(p)
+				if want, exists := ref[k]; h != exists || v != want || tree.Contains(p) != exists {
+					t.Fatalf("radix %v get %q = %d %v, want %d %v", radix, k, v, h, want, exists)
+				}
+			default:
+				if rnd.Intn(20) == 0 {
+					tree.Clear()
+					clear(ref)
+				}
+			}
+			checkPathTree(t, tree, ref, randPath(rnd))
+		}
+	}
+}
+
+func TestPathTreeWalkKeys(t *testing.T) {
+	tree := NewPathTree[string, int](WithRadix())
+	tree.Add([]string{"com", "example", "www"}, 1)
+	tree.Add([]string{"com", "example", "api"}, 2)
+	tree.Add(nil, 0)
+
+	// prefix ends inside compressed label
+	paths := make([][]string, 0)
+	tree.Walk([]string{"com"}, func(p []string, v int) bool {
+		paths = append(paths, p)
+		// path passed to fn is copy
+		p[0] = "changed"
+		return true
+	})
+	if len(paths) != 2 || strings.Join(paths[0][1:], ".") != "example.api" || !tree.Contains([]string{"com", "example", "www"}) {
+		t.Fatalf("walk paths %v", paths)
+	}
+	if ks, v, h := tree.LongestPrefix([]string{"org"}); !h || len(ks) != 0 || v != 0 {
+		t.Fatalf("root value as longest prefix %v %d %v", ks, v, h)
+	}
+}
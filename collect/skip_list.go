@@ -0,0 +1,234 @@
+// skip list, ordered by comparator, base of SortedMap and SortedSet
+// span of level is node num to next, rank and index lookup in O(log n)
+
+package collect
+
+import (
+	"math/rand/v2"
+)
+
+const (
+	skipListMaxLevel = 32
+	skipListP        = 0.25
+)
+
+type skipLevel[K, V any] struct {
+	next *skipNode[K, V]
+	span int
+}
+
+type skipNode[K, V any] struct {
+	key    K
+	val    V
+	prev   *skipNode[K, V]
+	levels []skipLevel[K, V]
+}
+
+type skipList[K, V any] struct {
+	head  *skipNode[K, V]
+	tail  *skipNode[K, V]
+	level int
+	size  int
+	cmp   func(a, b K) int
+}
+
+// newSkipList create skip list
+func newSkipList[K, V any](cmp func(a, b K) int) *skipList[K, V] {
+	return &skipList[K, V]{
+		head:  &skipNode[K, V]{levels: make([]skipLevel[K, V], skipListMaxLevel)},
+		level: 1,
+		cmp:   cmp,
+	}
+}
+
+// randomLevel level of new node
+func randomLevel() int {
+	lvl := 1
+	for lvl < skipListMaxLevel && rand.Float64() < skipListP {
+		lvl++
+	}
+	return lvl
+}
+
+// first first node
+func (l *skipList[K, V]) first() *skipNode[K, V] {
+	return l.head.levels[0].next
+}
+
+// lastBefore last node of which key match before, head when none
+func (l *skipList[K, V]) lastBefore(before func(K) bool) *skipNode[K, V] {
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && before(x.levels[i].next.key) {
+			x = x.levels[i].next
+		}
+	}
+	return x
+}
+
+// find node of key
+func (l *skipList[K, V]) find(k K) *skipNode[K, V] {
+	x := l.ceiling(k)
+	if x != nil && l.cmp(x.key, k) == 0 {
+		return x
+	}
+	return nil
+}
+
+// ceiling first node key >= k
+func (l *skipList[K, V]) ceiling(k K) *skipNode[K, V] {
+	return l.lastBefore(func(key K) bool { return l.cmp(key, k) < 0 }).levels[0].next
+}
+
+// higher first node key > k
+func (l *skipList[K, V]) higher(k K) *skipNode[K, V] {
+	return l.lastBefore(func(key K) bool { return l.cmp(key, k) <= 0 }).levels[0].next
+}
+
+// floor last node key <= k
+func (l *skipList[K, V]) floor(k K) *skipNode[K, V] {
+	return l.nodeOrNil(l.lastBefore(func(key K) bool { return l.cmp(key, k) <= 0 }))
+}
+
+// lower last node key < k
+func (l *skipList[K, V]) lower(k K) *skipNode[K, V] {
+	return l.nodeOrNil(l.lastBefore(func(key K) bool { return l.cmp(key, k) < 0 }))
+}
+
+// nodeOrNil nil when head
+func (l *skipList[K, V]) nodeOrNil(x *skipNode[K, V]) *skipNode[K, V] {
+	if x == l.head {
+		return nil
+	}
+	return x
+}
+
+// rank num of key < k
+func (l *skipList[K, V]) rank(k K) int {
+	rank := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && l.cmp(x.levels[i].next.key, k) < 0 {
+			rank += x.levels[i].span
+			x = x.levels[i].next
+		}
+	}
+	return rank
+}
+
+// at node of index from 0
+func (l *skipList[K, V]) at(idx int) *skipNode[K, V] {
+	if idx < 0 || idx >= l.size {
+		return nil
+	}
+	traversed := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && traversed+x.levels[i].span <= idx+1 {
+			traversed += x.levels[i].span
+			x = x.levels[i].next
+		}
+		if traversed == idx+1 {
+			return x
+		}
+	}
+	return nil
+}
+
+// set insert or replace value, return true when inserted
+func (l *skipList[K, V]) set(k K, v V) bool {
+	var update [skipListMaxLevel]*skipNode[K, V]
+	var rank [skipListMaxLevel]int
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].next != nil && l.cmp(x.levels[i].next.key, k) < 0 {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].next
+		}
+		update[i] = x
+	}
+	if next := x.levels[0].next; next != nil && l.cmp(next.key, k) == 0 {
+		next.val = v
+		return false
+	}
+
+	lvl := randomLevel()
+	if lvl > l.level {
+		for i := l.level; i < lvl; i++ {
+			rank[i] = 0
+			update[i] = l.head
+			update[i].levels[i].span = l.size
+		}
+		l.level = lvl
+	}
+
+	n := &skipNode[K, V]{key: k, val: v, levels: make([]skipLevel[K, V], lvl)}
+	for i := 0; i < lvl; i++ {
+		n.levels[i].next = update[i].levels[i].next
+		update[i].levels[i].next = n
+		n.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	for i := lvl; i < l.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != l.head {
+		n.prev = update[0]
+	}
+	if n.levels[0].next != nil {
+		n.levels[0].next.prev = n
+	} else {
+		l.tail = n
+	}
+	l.size++
+	return true
+}
+
+// delete delete node of key, return deleted node
+func (l *skipList[K, V]) delete(k K) *skipNode[K, V] {
+	var update [skipListMaxLevel]*skipNode[K, V]
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && l.cmp(x.levels[i].next.key, k) < 0 {
+			x = x.levels[i].next
+		}
+		update[i] = x
+	}
+	x = x.levels[0].next
+	if x == nil || l.cmp(x.key, k) != 0 {
+		return nil
+	}
+
+	for i := 0; i < l.level; i++ {
+		if update[i].levels[i].next == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].next = x.levels[i].next
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	if x.levels[0].next != nil {
+		x.levels[0].next.prev = x.prev
+	} else {
+		l.tail = x.prev
+	}
+	for l.level > 1 && l.head.levels[l.level-1].next == nil {
+		l.level--
+	}
+	l.size--
+	return x
+}
+
+// clear delete all
+func (l *skipList[K, V]) clear() {
+	l.head = &skipNode[K, V]{levels: make([]skipLevel[K, V], skipListMaxLevel)}
+	l.tail = nil
+	l.level = 1
+	l.size = 0
+}
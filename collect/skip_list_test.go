@@ -0,0 +1,142 @@
+package collect
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// refBounds - index of floor, ceiling, lower and higher of q in sorted keys, -1 when none
+func refBounds(keys []int, cmp func(a, b int) int, q int) (floor, ceiling, lower, higher int) {
+	floor, ceiling, lower, higher = -1, -1, -1, -1
+	for i, k := range keys {
+		c := cmp(k, q)
+		if c <= 0 {
+			floor = i
+		}
+		if c < 0 {
+			lower = i
+		}
+		if c >= 0 && ceiling < 0 {
+			ceiling = i
+		}
+		if c > 0 && higher < 0 {
+			higher = i
+		}
+	}
+	return
+}
+
+// checkSkipList - compare list with reference sorted keys and values, check span and prev link
+func checkSkipList(t *testing.T, l *skipList[int, int], keys []int, ref map[int]int) {
+	t.Helper()
+	if l.size != len(keys) {
+		t.Fatalf("size %d, want %d", l.size, len(keys))
+	}
+	// index of node on level 0
+	index := map[*skipNode[int, int]]int{l.head: -1}
+	var prev *skipNode[int, int]
+	i := 0
+	for n := l.first(); n != nil; n = n.levels[0].next {
+		if i >= len(keys) || n.key != keys[i] || n.val != ref[n.key] {
+			t.Fatalf("node %d = %d:%d, want %v", i, n.key, n.val, keys)
+		}
+		if n.prev != prev {
+			t.Fatalf("prev of %d broken", n.key)
+		}
+		index[n] = i
+		prev = n
+		i++
+	}
+	if l.tail != prev {
+		t.Fatal("tail broken")
+	}
+	for lv := 0; lv < l.level; lv++ {
+		for x := l.head; x.levels[lv].next != nil; x = x.levels[lv].next {
+			next := x.levels[lv].next
+			if _, ok := index[next]; !ok {
+				t.Fatalf("level %d node %d not on level 0", lv, next.key)
+			}
+			if span := index[next] - index[x]; x.levels[lv].span != span {
+				t.Fatalf("level %d span of %d = %d, want %d", lv, index[x], x.levels[lv].span, span)
+			}
+		}
+	}
+	for lv := l.level; lv < skipListMaxLevel; lv++ {
+		if l.head.levels[lv].next != nil {
+			t.Fatalf("level %d above list level %d not empty", lv, l.level)
+		}
+	}
+	for i, k := range keys {
+		if n := l.at(i); n == nil || n.key != k || l.rank(k) != i {
+			t.Fatalf("at/rank %d of %d broken", i, k)
+		}
+	}
+	if l.at(-1) != nil || l.at(len(keys)) != nil {
+		t.Fatal("at out of range not nil")
+	}
+}
+
+func TestSkipListRandomOps(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	l := newSkipList[int, int](cmp.Compare[int])
+	ref := map[int]int{}
+	keys := make([]int, 0)
+	for i := 0; i < 5000; i++ {
+		k := rnd.Intn(200)
+		idx, exists := slices.BinarySearch(keys, k)
+		switch op := rnd.Intn(10); {
+		case op < 5:
+			v := rnd.Int()
+			if l.set(k, v) == exists {
+				t.Fatalf("set %d inserted, exists %v", k, exists)
+			}
+			if !exists {
+				keys = slices.Insert(keys, idx, k)
+			}
+			ref[k] = v
+		case op < 9:
+			if n := l.delete(k); (n != nil) != exists || (exists && (n.key != k || n.val != ref[k])) {
+				t.Fatalf("delete %d = %v, exists %v", k, n, exists)
+			}
+			if exists {
+				keys = slices.Delete(keys, idx, idx+1)
+			}
+			delete(ref, k)
+		default:
+			if rnd.Intn(50) == 0 {
+				l.clear()
+				keys = keys[:0]
+				clear(ref)
+			}
+		}
+		checkSkipList(t, l, keys, ref)
+
+		q := rnd.Intn(220) - 10
+		floor, ceiling, lower, higher := refBounds(keys, cmp.Compare[int], q)
+		for _, cs := range []struct {
+			name string
+			n    *skipNode[int, int]
+			want int
+		}{{"floor", l.floor(q), floor}, {"ceiling", l.ceiling(q), ceiling}, {"lower", l.lower(q), lower}, {"higher", l.higher(q), higher}} {
+			if (cs.n == nil) != (cs.want < 0) || (cs.n != nil && cs.n.key != keys[cs.want]) {
+				t.Fatalf("%s of %d = %v, want index %d of %v", cs.name, q, cs.n, cs.want, keys)
+			}
+		}
+		if _, exists := ref[q]; (l.find(q) != nil) != exists {
+			t.Fatalf("find %d, exists %v", q, exists)
+		}
+		if r := l.rank(q); r != ceilingOrSize(ceiling, len(keys)) {
+			t.Fatalf("rank of %d = %d, want %d", q, r, ceilingOrSize(ceiling, len(keys)))
+		}
+	}
+}
+
+// ceilingOrSize - rank by ceiling index, size when no ceiling
+func ceilingOrSize(ceiling, size int) int {
+	if ceiling < 0 {
+		return size
+	}
+	return ceiling
+}
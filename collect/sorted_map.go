@@ -0,0 +1,167 @@
+// sorted map, key ordered by comparator, skip list implement
+// not concurrency safe
+
+package collect
+
+import (
+	"cmp"
+	"iter"
+)
+
+type SortedMap[K, V any] struct {
+	list   *skipList[K, V]
+	nilKey K // do not init it
+	nilVal V // do not init it
+}
+
+// NewSortedMap - create sorted map, key in ascending order
+func NewSortedMap[K cmp.Ordered, V any]() *SortedMap[K, V] {
+	return NewSortedMapFunc[K, V](cmp.Compare[K])
+}
+
+// NewSortedMapFunc - create sorted map with comparator, negative when a < b
+func NewSortedMapFunc[K, V any](cmp func(a, b K) int) *SortedMap[K, V] {
+	return &SortedMap[K, V]{
+		list: newSkipList[K, V](cmp),
+	}
+}
+
+// Put - put value, replace exists
+func (m *SortedMap[K, V]) Put(k K, v V) {
+	m.list.set(k, v)
+}
+
+// Get - get value
+func (m *SortedMap[K, V]) Get(k K) (V, bool) {
+	n := m.list.find(k)
+	if n == nil {
+		return m.nilVal, false
+	}
+	return n.val, true
+}
+
+// Contains - contains key
+func (m *SortedMap[K, V]) Contains(k K) bool {
+	return m.list.find(k) != nil
+}
+
+// Delete - delete key, return false when not exists
+func (m *SortedMap[K, V]) Delete(k K) bool {
+	return m.list.delete(k) != nil
+}
+
+// Size - entry num
+func (m *SortedMap[K, V]) Size() int {
+	return m.list.size
+}
+
+// Clear - delete all
+func (m *SortedMap[K, V]) Clear() {
+	m.list.clear()
+}
+
+// entry key and value of node
+func (m *SortedMap[K, V]) entry(n *skipNode[K, V]) (K, V, bool) {
+	if n == nil {
+		return m.nilKey, m.nilVal, false
+	}
+	return n.key, n.val, true
+}
+
+// First - entry of min key
+func (m *SortedMap[K, V]) First() (K, V, bool) {
+	return m.entry(m.list.first())
+}
+
+// Last - entry of max key
+func (m *SortedMap[K, V]) Last() (K, V, bool) {
+	return m.entry(m.list.tail)
+}
+
+// Floor - entry of max key <= k
+func (m *SortedMap[K, V]) Floor(k K) (K, V, bool) {
+	return m.entry(m.list.floor(k))
+}
+
+// Ceiling - entry of min key >= k
+func (m *SortedMap[K, V]) Ceiling(k K) (K, V, bool) {
+	return m.entry(m.list.ceiling(k))
+}
+
+// Lower - entry of max key < k
+func (m *SortedMap[K, V]) Lower(k K) (K, V, bool) {
+	return m.entry(m.list.lower(k))
+}
+
+// Higher - entry of min key > k
+func (m *SortedMap[K, V]) Higher(k K) (K, V, bool) {
+	return m.entry(m.list.higher(k))
+}
+
+// Rank - num of key < k, index of k when exists
+func (m *SortedMap[K, V]) Rank(k K) int {
+	return m.list.rank(k)
+}
+
+// At - entry of index in ascending order
+func (m *SortedMap[K, V]) At(idx int) (K, V, bool) {
+	return m.entry(m.list.at(idx))
+}
+
+// Range - loop entry in ascending order, stop when fn return false
+func (m *SortedMap[K, V]) Range(fn func(K, V) bool) {
+	for n := m.list.first(); n != nil; n = n.levels[0].next {
+		if !fn(n.key, n.val) {
+			return
+		}
+	}
+}
+
+// All - entries in ascending order
+func (m *SortedMap[K, V]) All() iter.Seq2[K, V] {
+	return m.Range
+}
+
+// Backward - entries in descending order
+func (m *SortedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := m.list.tail; n != nil; n = n.prev {
+			if !yield(n.key, n.val) {
+				return
+			}
+		}
+	}
+}
+
+// Between - entries of key in [from, to) in ascending order
+func (m *SortedMap[K, V]) Between(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := m.list.ceiling(from); n != nil && m.list.cmp(n.key, to) < 0; n = n.levels[0].next {
+			if !yield(n.key, n.val) {
+				return
+			}
+		}
+	}
+}
+
+// Keys - keys in ascending order
+func (m *SortedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for n := m.list.first(); n != nil; n = n.levels[0].next {
+			if !yield(n.key) {
+				return
+			}
+		}
+	}
+}
+
+// Values - values in ascending order of key
+func (m *SortedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for n := m.list.first(); n != nil; n = n.levels[0].next {
+			if !yield(n.val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package collect
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkSortedMap - compare map with reference keys sorted by cmp and values, query by random key
+func checkSortedMap(t *testing.T, rnd *rand.Rand, m *SortedMap[int, int], cmp func(a, b int) int, keys []int, ref map[int]int) {
+	t.Helper()
+	q := rnd.Intn(220) - 10
+	if m.Size() != len(keys) {
+		t.Fatalf("size %d, want %d", m.Size(), len(keys))
+	}
+	// entry of index, not found when idx < 0
+	entry := func(name string, idx int, k, v int, h bool) {
+		t.Helper()
+		if h != (idx >= 0) || (h && (k != keys[idx] || v != ref[k])) {
+			t.Fatalf("%s of %d = %d:%d %v, want index %d of %v", name, q, k, v, h, idx, keys)
+		}
+	}
+	last := len(keys) - 1
+	k, v, h := m.First()
+	entry("first", min(0, last), k, v, h)
+	k, v, h = m.Last()
+	entry("last", last, k, v, h)
+	floor, ceiling, lower, higher := refBounds(keys, cmp, q)
+	k, v, h = m.Floor(q)
+	entry("floor", floor, k, v, h)
+	k, v, h = m.Ceiling(q)
+	entry("ceiling", ceiling, k, v, h)
+	k, v, h = m.Lower(q)
+	entry("lower", lower, k, v, h)
+	k, v, h = m.Higher(q)
+	entry("higher", higher, k, v, h)
+	if r := m.Rank(q); r != ceilingOrSize(ceiling, len(keys)) {
+		t.Fatalf("rank of %d = %d, want %d", q, r, ceilingOrSize(ceiling, len(keys)))
+	}
+	idx := rnd.Intn(len(keys)+2) - 1
+	k, v, h = m.At(idx)
+	if idx >= len(keys) {
+		idx = -1
+	}
+	entry("at", idx, k, v, h)
+
+	got := make([]int, 0, len(keys))
+	for k, v := range m.All() {
+		if v != ref[k] {
+			t.Fatalf("all %d = %d, want %d", k, v, ref[k])
+		}
+		got = append(got, k)
+	}
+	if !slices.Equal(got, keys) || !slices.Equal(slices.Collect(m.Keys()), keys) {
+		t.Fatalf("all %v, want %v", got, keys)
+	}
+	vals := make([]int, 0, len(keys))
+	for _, k := range keys {
+		vals = append(vals, ref[k])
+	}
+	if !slices.Equal(slices.Collect(m.Values()), vals) {
+		t.Fatalf("values %v, want %v", slices.Collect(m.Values()), vals)
+	}
+	got = got[:0]
+	for k := range m.Backward() {
+		got = append(got, k)
+	}
+	slices.Reverse(got)
+	if !slices.Equal(got, keys) {
+		t.Fatalf("backward %v, want reverse of %v", got, keys)
+	}
+
+	// between [q, to)
+	to := q + rnd.Intn(40) - 10
+	want := make([]int, 0)
+	for _, k := range keys {
+		if cmp(k, q) >= 0 && cmp(k, to) < 0 {
+			want = append(want, k)
+		}
+	}
+	got = got[:0]
+	for k := range m.Between(q, to) {
+		got = append(got, k)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("between %d %d = %v, want %v", q, to, got, want)
+	}
+}
+
+func TestSortedMapRandomOps(t *testing.T) {
+	desc := func(a, b int) int { return cmp.Compare(b, a) }
+	for _, c := range []func(a, b int) int{cmp.Compare[int], desc} {
+		rnd := rand.New(rand.NewSource(1))
+		m := NewSortedMapFunc[int, int](c)
+		ref := map[int]int{}
+		keys := make([]int, 0)
+		for i := 0; i < 5000; i++ {
+			k := rnd.Intn(200)
+			idx, exists := slices.BinarySearchFunc(keys, k, c)
+			switch op := rnd.Intn(10); {
+			case op < 5:
+				v := rnd.Int()
+				m.Put(k, v)
+				if !exists {
+					keys = slices.Insert(keys, idx, k)
+				}
+				ref[k] = v
+			case op < 8:
+				if m.Delete(k) != exists {
+					t.Fatalf("delete %d, exists %v", k, exists)
+				}
+				if exists {
+					keys = slices.Delete(keys, idx, idx+1)
+				}
+				delete(ref, k)
+			case op < 9:
+				v, h := m.Get(k)
+				if h != exists || v != ref[k] || m.Contains(k) != exists {
+					t.Fatalf("get %d = %d %v, want %d %v", k, v, h, ref[k], exists)
+				}
+			default:
+				if rnd.Intn(50) == 0 {
+					m.Clear()
+					keys = keys[:0]
+					clear(ref)
+				}
+			}
+			checkSortedMap(t, rnd, m, c, keys, ref)
+		}
+	}
+}
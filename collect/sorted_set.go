@@ -0,0 +1,157 @@
+// sorted set, element ordered by comparator, skip list implement
+// not concurrency safe
+
+package collect
+
+import (
+	"cmp"
+	"iter"
+)
+
+type SortedSet[T any] struct {
+	list   *skipList[T, struct{}]
+	nilVal T // do not init it
+}
+
+// NewSortedSet - create sorted set, element in ascending order
+func NewSortedSet[T cmp.Ordered]() *SortedSet[T] {
+	return NewSortedSetFunc[T](cmp.Compare[T])
+}
+
+// NewSortedSetFunc - create sorted set with comparator, negative when a < b
+func NewSortedSetFunc[T any](cmp func(a, b T) int) *SortedSet[T] {
+	return &SortedSet[T]{
+		list: newSkipList[T, struct{}](cmp),
+	}
+}
+
+// NewSortedSetBySlice - create sorted set by slice
+func NewSortedSetBySlice[T cmp.Ordered](arr []T) *SortedSet[T] {
+	s := NewSortedSet[T]()
+	s.Add(arr...)
+	return s
+}
+
+// Add - add element
+func (s *SortedSet[T]) Add(args ...T) *SortedSet[T] {
+	for i := range args {
+		s.list.set(args[i], nilStructObj)
+	}
+	return s
+}
+
+// Remove - remove element
+func (s *SortedSet[T]) Remove(ele T) *SortedSet[T] {
+	s.list.delete(ele)
+	return s
+}
+
+// Contains - contains element
+func (s *SortedSet[T]) Contains(ele T) bool {
+	return s.list.find(ele) != nil
+}
+
+// Size - element num
+func (s *SortedSet[T]) Size() int {
+	return s.list.size
+}
+
+// Clear - clear container
+func (s *SortedSet[T]) Clear() *SortedSet[T] {
+	s.list.clear()
+	return s
+}
+
+// element key of node
+func (s *SortedSet[T]) element(n *skipNode[T, struct{}]) (T, bool) {
+	if n == nil {
+		return s.nilVal, false
+	}
+	return n.key, true
+}
+
+// First - min element
+func (s *SortedSet[T]) First() (T, bool) {
+	return s.element(s.list.first())
+}
+
+// Last - max element
+func (s *SortedSet[T]) Last() (T, bool) {
+	return s.element(s.list.tail)
+}
+
+// Floor - max element <= ele
+func (s *SortedSet[T]) Floor(ele T) (T, bool) {
+	return s.element(s.list.floor(ele))
+}
+
+// Ceiling - min element >= ele
+func (s *SortedSet[T]) Ceiling(ele T) (T, bool) {
+	return s.element(s.list.ceiling(ele))
+}
+
+// Lower - max element < ele
+func (s *SortedSet[T]) Lower(ele T) (T, bool) {
+	return s.element(s.list.lower(ele))
+}
+
+// Higher - min element > ele
+func (s *SortedSet[T]) Higher(ele T) (T, bool) {
+	return s.element(s.list.higher(ele))
+}
+
+// Rank - num of element < ele, index of ele when exists
+func (s *SortedSet[T]) Rank(ele T) int {
+	return s.list.rank(ele)
+}
+
+// At - element of index in ascending order
+func (s *SortedSet[T]) At(idx int) (T, bool) {
+	return s.element(s.list.at(idx))
+}
+
+// Range - loop element in ascending order, stop when fn return false
+func (s *SortedSet[T]) Range(fn func(T) bool) {
+	for n := s.list.first(); n != nil; n = n.levels[0].next {
+		if !fn(n.key) {
+			return
+		}
+	}
+}
+
+// All - elements in ascending order
+func (s *SortedSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
+// Backward - elements in descending order
+func (s *SortedSet[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for n := s.list.tail; n != nil; n = n.prev {
+			if !yield(n.key) {
+				return
+			}
+		}
+	}
+}
+
+// Between - elements in [from, to) in ascending order
+func (s *SortedSet[T]) Between(from, to T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for n := s.list.ceiling(from); n != nil && s.list.cmp(n.key, to) < 0; n = n.levels[0].next {
+			if !yield(n.key) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice - elements in ascending order
+func (s *SortedSet[T]) ToSlice() []T {
+	rst := make([]T, 0, s.list.size)
+	s.Range(func(ele T) bool {
+		rst = append(rst, ele)
+		return true
+	})
+	return rst
+}
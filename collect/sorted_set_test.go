@@ -0,0 +1,106 @@
+package collect
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkSortedSet - compare set with reference elements sorted by cmp, query by random element
+func checkSortedSet(t *testing.T, rnd *rand.Rand, s *SortedSet[int], cmp func(a, b int) int, eles []int) {
+	t.Helper()
+	if s.Size() != len(eles) || !slices.Equal(s.ToSlice(), eles) {
+		t.Fatalf("elements %v, want %v", s.ToSlice(), eles)
+	}
+	q := rnd.Intn(220) - 10
+	// element of index, not found when idx < 0
+	element := func(name string, idx int, e int, h bool) {
+		t.Helper()
+		if h != (idx >= 0) || (h && e != eles[idx]) {
+			t.Fatalf("%s of %d = %d %v, want index %d of %v", name, q, e, h, idx, eles)
+		}
+	}
+	last := len(eles) - 1
+	e, h := s.First()
+	element("first", min(0, last), e, h)
+	e, h = s.Last()
+	element("last", last, e, h)
+	floor, ceiling, lower, higher := refBounds(eles, cmp, q)
+	e, h = s.Floor(q)
+	element("floor", floor, e, h)
+	e, h = s.Ceiling(q)
+	element("ceiling", ceiling, e, h)
+	e, h = s.Lower(q)
+	element("lower", lower, e, h)
+	e, h = s.Higher(q)
+	element("higher", higher, e, h)
+	if r := s.Rank(q); r != ceilingOrSize(ceiling, len(eles)) {
+		t.Fatalf("rank of %d = %d, want %d", q, r, ceilingOrSize(ceiling, len(eles)))
+	}
+	idx := rnd.Intn(len(eles)+2) - 1
+	e, h = s.At(idx)
+	if idx >= len(eles) {
+		idx = -1
+	}
+	element("at", idx, e, h)
+
+	if !slices.Equal(slices.Collect(s.All()), eles) {
+		t.Fatalf("all %v, want %v", slices.Collect(s.All()), eles)
+	}
+	got := slices.Collect(s.Backward())
+	slices.Reverse(got)
+	if !slices.Equal(got, eles) {
+		t.Fatalf("backward %v, want reverse of %v", got, eles)
+	}
+	to := q + rnd.Intn(40) - 10
+	want := make([]int, 0)
+	for _, e := range eles {
+		if cmp(e, q) >= 0 && cmp(e, to) < 0 {
+			want = append(want, e)
+		}
+	}
+	if got = slices.Collect(s.Between(q, to)); !slices.Equal(got, want) {
+		t.Fatalf("between %d %d = %v, want %v", q, to, got, want)
+	}
+}
+
+func TestSortedSetRandomOps(t *testing.T) {
+	desc := func(a, b int) int { return cmp.Compare(b, a) }
+	for _, c := range []func(a, b int) int{cmp.Compare[int], desc} {
+		rnd := rand.New(rand.NewSource(1))
+		s := NewSortedSetFunc[int](c)
+		eles := make([]int, 0)
+		for i := 0; i < 5000; i++ {
+			e := rnd.Intn(200)
+			idx, exists := slices.BinarySearchFunc(eles, e, c)
+			switch op := rnd.Intn(10); {
+			case op < 5:
+				s.Add(e)
+				if !exists {
+					eles = slices.Insert(eles, idx, e)
+				}
+			case op < 8:
+				s.Remove(e)
+				if exists {
+					eles = slices.Delete(eles, idx, idx+1)
+				}
+			case op < 9:
+				if s.Contains(e) != exists {
+					t.Fatalf("contains %d, want %v", e, exists)
+				}
+			default:
+				if rnd.Intn(50) == 0 {
+					s.Clear()
+					eles = eles[:0]
+				}
+			}
+			checkSortedSet(t, rnd, s, c, eles)
+		}
+	}
+
+	// by slice, duplicate element once
+	if s := NewSortedSetBySlice([]int{3, 1, 2, 3, 1}); !slices.Equal(s.ToSlice(), []int{1, 2, 3}) {
+		t.Fatalf("by slice %v", s.ToSlice())
+	}
+}
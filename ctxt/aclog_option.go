@@ -0,0 +1,344 @@
+// access log option, redaction, body size, route opt-out and sampling
+// redaction keys case insensitive, form and multipart body redacted by query keys and single key fields
+// body not parsed by content type redacted whole when redaction configured
+
+package ctxt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	RedactedValue = "******"
+
+	acLogSkipKey = "ACLogSkip"
+)
+
+var defaultRedactHeaders = []string{HeaderAuthorization, HeaderAK, "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+type acLogConfig struct {
+	redactHeaders map[string]struct{}
+	redactQuery   map[string]struct{}
+	redactFields  [][]string
+
+	maxBodySize     int
+	maxResponseSize int
+
+	skipRoutes     map[string]struct{}
+	successSample  float64
+	logResponse    bool
+	logRequestBody bool
+}
+
+type ACLogOption func(cfg *acLogConfig)
+
+// WithRedactHeaders - redact headers, case insensitive, default Authorization, AK, Cookie
+func WithRedactHeaders(names ...string) ACLogOption {
+	return func(cfg *acLogConfig) {
+		for _, name := range names {
+			cfg.redactHeaders[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+}
+
+// WithRedactQuery - redact query, path params and form body fields, case insensitive
+func WithRedactQuery(keys ...string) ACLogOption {
+	return func(cfg *acLogConfig) {
+		for _, k := range keys {
+			cfg.redactQuery[strings.ToLower(k)] = struct{}{}
+		}
+	}
+}
+
+// WithRedactFields - redact json body fields of request and response by key path, e.g. user.password
+// array elements are walked through, * match any key, single key path redact form and multipart field too
+// body not parsed as json logged as RedactedValue
+func WithRedactFields(paths ...string) ACLogOption {
+	return func(cfg *acLogConfig) {
+		for _, p := range paths {
+			if p != "" {
+				cfg.redactFields = append(cfg.redactFields, strings.Split(p, "."))
+			}
+		}
+	}
+}
+
+// WithMaxBodySize - max logged request body size, default AcLogMaxLen
+func WithMaxBodySize(size int) ACLogOption {
+	return func(cfg *acLogConfig) {
+		cfg.maxBodySize = size
+	}
+}
+
+// WithMaxResponseSize - max logged response data size, default AcLogMaxLen
+func WithMaxResponseSize(size int) ACLogOption {
+	return func(cfg *acLogConfig) {
+		cfg.maxResponseSize = size
+	}
+}
+
+// WithSkipRoutes - no access log for route templates, e.g. /healthz
+func WithSkipRoutes(routes ...string) ACLogOption {
+	return func(cfg *acLogConfig) {
+		for _, r := range routes {
+			cfg.skipRoutes[r] = struct{}{}
+		}
+	}
+}
+
+// WithSuccessSampling - log successful request by rate in [0, 1], error request always logged
+func WithSuccessSampling(rate float64) ACLogOption {
+	return func(cfg *acLogConfig) {
+		cfg.successSample = rate
+	}
+}
+
+// WithLogResponse - log response data, default true
+func WithLogResponse(enable bool) ACLogOption {
+	return func(cfg *acLogConfig) {
+		cfg.logResponse = enable
+	}
+}
+
+// WithLogRequestBody - log request body, default true
+func WithLogRequestBody(enable bool) ACLogOption {
+	return func(cfg *acLogConfig) {
+		cfg.logRequestBody = enable
+	}
+}
+
+// SkipACLog - route middleware, no access log for this route
+func SkipACLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(acLogSkipKey, true)
+		c.Next()
+	}
+}
+
+// newACLogConfig - create config with default value
+func newACLogConfig(opts ...ACLogOption) *acLogConfig {
+	cfg := &acLogConfig{
+		redactHeaders:   make(map[string]struct{}),
+		redactQuery:     make(map[string]struct{}),
+		maxBodySize:     AcLogMaxLen,
+		maxResponseSize: AcLogMaxLen,
+		skipRoutes:      make(map[string]struct{}),
+		successSample:   1,
+		logResponse:     true,
+		logRequestBody:  true,
+	}
+	WithRedactHeaders(defaultRedactHeaders...)(cfg)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// skip - check skip access log of route
+func (cfg *acLogConfig) skip(c *gin.Context) bool {
+	if _, h := cfg.skipRoutes[c.FullPath()]; h {
+		return true
+	}
+	return c.GetBool(acLogSkipKey)
+}
+
+// sampled - check successful request sampled
+func (cfg *acLogConfig) sampled(httpCode int) bool {
+	if httpCode >= 400 || cfg.successSample >= 1 {
+		return true
+	}
+	return rand.Float64() < cfg.successSample
+}
+
+// headers - redacted header json
+func (cfg *acLogConfig) headers(header http.Header) string {
+	if len(header) < 1 {
+		return ""
+	}
+	rst := make(http.Header, len(header))
+	for k, vs := range header {
+		if _, h := cfg.redactHeaders[http.CanonicalHeaderKey(k)]; h {
+			rst[k] = []string{RedactedValue}
+			continue
+		}
+		rst[k] = vs
+	}
+	hj, err := json.Marshal(rst)
+	if err != nil {
+		return ""
+	}
+	return string(hj)
+}
+
+// params - redact query and path params
+func (cfg *acLogConfig) params(params map[string]string) map[string]string {
+	for k := range params {
+		if _, h := cfg.redactQuery[strings.ToLower(k)]; h {
+			params[k] = RedactedValue
+		}
+	}
+	return params
+}
+
+// requestBody - redacted request body by content type, truncate
+func (cfg *acLogConfig) requestBody(contentType string, bts []byte) string {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case binding.MIMEPOSTForm:
+		return cfg.formBody(bts, cfg.maxBodySize)
+	case binding.MIMEMultipartPOSTForm:
+		return cfg.multipartBody(bts, params["boundary"], cfg.maxBodySize)
+	default:
+		return cfg.body(bts, cfg.maxBodySize)
+	}
+}
+
+// formBody - redact form body fields by query keys and single key fields, truncate
+// body not parsed as form redacted whole
+func (cfg *acLogConfig) formBody(bts []byte, maxSize int) string {
+	if len(bts) < 1 {
+		return ""
+	}
+	values, err := url.ParseQuery(string(bts))
+	if err != nil {
+		return RedactedValue
+	}
+	return cfg.encodeForm(values, maxSize)
+}
+
+// multipartBody - multipart fields redacted as form body, file logged as @filename without content
+// body not parsed as multipart redacted whole
+func (cfg *acLogConfig) multipartBody(bts []byte, boundary string, maxSize int) string {
+	if len(bts) < 1 {
+		return ""
+	}
+	if boundary == "" {
+		return RedactedValue
+	}
+	values := make(url.Values)
+	mr := multipart.NewReader(bytes.NewReader(bts), boundary)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) && len(values) > 0 {
+			break
+		}
+		if err != nil {
+			return RedactedValue
+		}
+		name := part.FormName()
+		if fileName := part.FileName(); fileName != "" {
+			values.Add(name, "@"+fileName)
+			continue
+		}
+		v, err := io.ReadAll(part)
+		if err != nil {
+			return RedactedValue
+		}
+		values.Add(name, string(v))
+	}
+	return cfg.encodeForm(values, maxSize)
+}
+
+// encodeForm - encode form values sorted by key, redacted value not escaped, truncate
+func (cfg *acLogConfig) encodeForm(values url.Values, maxSize int) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		redact := cfg.redactFormKey(k)
+		for _, v := range values[k] {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(k))
+			sb.WriteByte('=')
+			if redact {
+				sb.WriteString(RedactedValue)
+			} else {
+				sb.WriteString(url.QueryEscape(v))
+			}
+		}
+	}
+	body := sb.String()
+	if maxSize > 0 && len(body) > maxSize {
+		body = body[:maxSize]
+	}
+	return body
+}
+
+// redactFormKey - form field redacted by query keys or single key fields
+func (cfg *acLogConfig) redactFormKey(k string) bool {
+	if _, h := cfg.redactQuery[strings.ToLower(k)]; h {
+		return true
+	}
+	for _, path := range cfg.redactFields {
+		if len(path) == 1 && (path[0] == "*" || strings.EqualFold(path[0], k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// body - redact json body fields and truncate, body not parsed as json redacted whole when fields set
+func (cfg *acLogConfig) body(bts []byte, maxSize int) string {
+	if len(bts) < 1 {
+		return ""
+	}
+	if len(cfg.redactFields) > 0 {
+		var v any
+		if err := json.Unmarshal(bts, &v); err != nil {
+			return RedactedValue
+		}
+		for _, path := range cfg.redactFields {
+			v = redactPath(v, path)
+		}
+		rbts, err := json.Marshal(v)
+		if err != nil {
+			return RedactedValue
+		}
+		bts = rbts
+	}
+	if maxSize > 0 && len(bts) > maxSize {
+		bts = bts[:maxSize]
+	}
+	return string(bts)
+}
+
+// redactPath - redact value by key path, walk through arrays
+func redactPath(v any, path []string) any {
+	if len(path) < 1 {
+		return RedactedValue
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		for k, sub := range val {
+			if path[0] == "*" || strings.EqualFold(k, path[0]) {
+				val[k] = redactPath(sub, path[1:])
+			}
+		}
+		return val
+	case []any:
+		for i := range val {
+			val[i] = redactPath(val[i], path)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,174 @@
+package ctxt
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureAccessLog - access log records written while fn run
+func captureAccessLog(t *testing.T, fn func()) []map[string]any {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	old := AccessLogger
+	AccessLogger = slog.New(slog.NewJSONHandler(buf, nil))
+	defer func() {
+		AccessLogger = old
+	}()
+	fn()
+
+	rst := make([]map[string]any, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		rec := make(map[string]any)
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		rst = append(rst, rec)
+	}
+	return rst
+}
+
+// acLogEngine - engine with access log and echo route
+func acLogEngine(opts ...ACLogOption) *gin.Engine {
+	engine := gin.New()
+	engine.Use(ACLog(opts...), ResponseFunc(), ErrorFunc())
+	engine.POST("/echo/:id", func(c *gin.Context) {
+		GetCTX(c).SetData(map[string]any{"password": "resp-secret", "name": "bob"})
+	})
+	engine.GET("/healthz", func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	})
+	engine.GET("/skip", SkipACLog(), func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	})
+	engine.NoRoute(func(c *gin.Context) {
+		GetCTX(c).SetErrorResponse(http.StatusNotFound, http.StatusNotFound, "not found")
+	})
+	return engine
+}
+
+// logRequest - serve request and return the only access log record
+func logRequest(t *testing.T, engine *gin.Engine, r *http.Request) map[string]any {
+	t.Helper()
+	recs := captureAccessLog(t, func() {
+		doRequest(t, engine, r)
+	})
+	if len(recs) != 1 {
+		t.Fatalf("access log records %v", recs)
+	}
+	return recs[0]
+}
+
+// multipartRequest - multipart form request with fields and one file
+func multipartRequest(t *testing.T, fields map[string]string, file string) *http.Request {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fw, err := mw.CreateFormFile("upload", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write([]byte(file))
+	if err = mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/echo/1", buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestACLogRedactBody(t *testing.T) {
+	engine := acLogEngine(WithRedactFields("password", "user.token"), WithRedactQuery("sig"))
+
+	jsonRequest := func(contentType, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/echo/1?sig=query-secret&page=2", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		return r
+	}
+	cases := []struct {
+		name string
+		r    *http.Request
+		want string
+	}{
+		{"json", jsonRequest("application/json", `{"password":"secret","user":[{"token":"secret","id":1}]}`),
+			`{"password":"******","user":[{"id":1,"token":"******"}]}`},
+		{"malformed json", jsonRequest("application/json", `{"password":"secret"`), RedactedValue},
+		{"text", jsonRequest("text/plain", "password=secret"), RedactedValue},
+		{"form", jsonRequest("application/x-www-form-urlencoded; charset=utf-8", "password=secret&name=bob&SIG=x"),
+			"SIG=******&name=bob&password=******"},
+		{"broken form", jsonRequest("application/x-www-form-urlencoded", "password=%zz"), RedactedValue},
+		{"multipart", multipartRequest(t, map[string]string{"Password": "secret", "name": "bob"}, "file-secret"),
+			"Password=******&name=bob&upload=%40a.txt"},
+		{"broken multipart", jsonRequest("multipart/form-data; boundary=xyz", "password=secret"), RedactedValue},
+		{"multipart without boundary", jsonRequest("multipart/form-data", "password=secret"), RedactedValue},
+	}
+	for _, cs := range cases {
+		rec := logRequest(t, engine, cs.r)
+		param, _ := rec["http_param"].(string)
+		if strings.Contains(param, "secret") {
+			t.Fatalf("%s secret logged, %s", cs.name, param)
+		}
+		params := make(map[string]string)
+		if err := json.Unmarshal([]byte(param), &params); err != nil {
+			t.Fatalf("%s http param %q, %v", cs.name, param, err)
+		}
+		if params[RequestBodyParam] != cs.want {
+			t.Fatalf("%s body %q, want %q", cs.name, params[RequestBodyParam], cs.want)
+		}
+		if cs.r.URL.RawQuery != "" && (params["sig"] != RedactedValue || params["page"] != "2" || params["id"] != "1") {
+			t.Fatalf("%s query params %v", cs.name, params)
+		}
+		if rsp, _ := rec["response_param"].(string); !strings.Contains(rsp, RedactedValue) || strings.Contains(rsp, "resp-secret") {
+			t.Fatalf("%s response %s", cs.name, rsp)
+		}
+	}
+
+	// no redaction configured, text body logged as is, truncated
+	engine = acLogEngine(WithMaxBodySize(4))
+	rec := logRequest(t, engine, jsonRequest("text/plain", "hello world"))
+	if param, _ := rec["http_param"].(string); !strings.Contains(param, `"hell"`) {
+		t.Fatalf("text body %s", param)
+	}
+}
+
+func TestACLogRedactHeaders(t *testing.T) {
+	engine := acLogEngine(WithRedactHeaders("x-api-key"))
+	r := httptest.NewRequest(http.MethodPost, "/echo/1", nil)
+	r.Header.Set(HeaderAuthorization, "HMAC-SHA256 secret")
+	r.Header.Set("X-Api-Key", "secret")
+	r.Header.Set("X-Other", "visible")
+	rec := logRequest(t, engine, r)
+	header, _ := rec["http_header"].(string)
+	if strings.Contains(header, "secret") || !strings.Contains(header, "visible") {
+		t.Fatalf("header %s", header)
+	}
+}
+
+func TestACLogSkipAndSampling(t *testing.T) {
+	engine := acLogEngine(WithSkipRoutes("/healthz"), WithSuccessSampling(0), WithLogResponse(false))
+	recs := captureAccessLog(t, func() {
+		doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/skip", nil))
+		doRequest(t, engine, httptest.NewRequest(http.MethodPost, "/echo/1", nil))
+		doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/none", nil))
+	})
+	// only unmatched route logged, error always logged
+	if len(recs) != 1 || recs[0]["http_url"] != "/none" {
+		t.Fatalf("access log records %v", recs)
+	}
+}
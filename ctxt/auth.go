@@ -0,0 +1,195 @@
+// auth middleware, md5 by default, hmac-sha256 request signing opt in by WithAuthMode
+// AuthModeAny accept both, client can downgrade to md5 which has no nonce and body signing,
+// md5 signature can be replayed in timestamp window, use AuthModeHMAC after clients migrated
+
+package ctxt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AuthModeHMAC = "hmac"
+	AuthModeMD5  = "md5"
+	AuthModeAny  = "any" // hmac when authorization use hmac scheme, otherwise md5, allow downgrade to md5
+
+	defaultMaxSignBody = 10 << 20
+)
+
+type authConfig struct {
+	mode       string
+	nonceStore NonceStore
+	credStore  CredentialStore
+	skew       time.Duration
+	maxBody    int64
+}
+
+type AuthOption func(cfg *authConfig)
+
+// WithAuthMode - config auth mode, default md5
+// AuthModeAny let client choose md5, replayable in timestamp window
+func WithAuthMode(mode string) AuthOption {
+	return func(cfg *authConfig) {
+		cfg.mode = mode
+	}
+}
+
+// WithNonceStore - config nonce store, default in memory
+func WithNonceStore(store NonceStore) AuthOption {
+	return func(cfg *authConfig) {
+		cfg.nonceStore = store
+	}
+}
+
+// WithCredentialStore - config credential store, default the store AddAKSKCache write into
+func WithCredentialStore(store CredentialStore) AuthOption {
+	return func(cfg *authConfig) {
+		cfg.credStore = store
+	}
+}
+
+// WithSignSkew - config allowed timestamp skew, default 5 minutes
+func WithSignSkew(skew time.Duration) AuthOption {
+	return func(cfg *authConfig) {
+		cfg.skew = skew
+	}
+}
+
+// WithMaxSignBody - config max body bytes read for hmac signature, default 10MB, 413 when exceeded
+// NewServer limit body of all routes by it before access log read body
+func WithMaxSignBody(n int64) AuthOption {
+	return func(cfg *authConfig) {
+		cfg.maxBody = n
+	}
+}
+
+// newAuthConfig - create config with default value
+func newAuthConfig(opts ...AuthOption) *authConfig {
+	cfg := &authConfig{
+		mode:    AuthModeMD5,
+		skew:    defaultSignSkew,
+		maxBody: defaultMaxSignBody,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.nonceStore == nil {
+		cfg.nonceStore = NewMemoryNonceStore()
+	}
+	if cfg.credStore == nil {
+		cfg.credStore = defaultCredentialStore
+	}
+	return cfg
+}
+
+// AuthFunc - auth func
+func AuthFunc(opts ...AuthOption) gin.HandlerFunc {
+	cfg := newAuthConfig(opts...)
+
+	return func(ctx *gin.Context) {
+		reqCtx := GetCTX(ctx)
+		ak := ctx.GetHeader(HeaderAK)
+		auth := ctx.GetHeader(HeaderAuthorization)
+		tsStr := ctx.GetHeader(HeaderTimestamp)
+		if tsStr == "" {
+			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, fmt.Sprintf("timestamp can not blank"))
+			ctx.Abort()
+			return
+		}
+		ts, err := strconv.ParseInt(tsStr, 10, 64)
+		if err != nil {
+			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, fmt.Sprintf("timestamp format error,  %v", err))
+			ctx.Abort()
+			return
+		}
+
+		if ak == "" {
+			reqCtx.SetErrorResponse(http.StatusUnauthorized, http.StatusUnauthorized, "ak can not blank")
+			ctx.Abort()
+			return
+		}
+		cred, err := cfg.credStore.Get(ak)
+		if err != nil {
+			reqCtx.SetErrorResponse(http.StatusInternalServerError, http.StatusInternalServerError,
+				fmt.Sprintf("get credential error, %v", err))
+			ctx.Abort()
+			return
+		}
+		if cred == nil {
+			reqCtx.SetErrorResponse(http.StatusUnauthorized, http.StatusUnauthorized, "no authorization")
+			ctx.Abort()
+			return
+		}
+
+		isHMAC := strings.HasPrefix(auth, HMACScheme+" ")
+		switch {
+		case cfg.mode == AuthModeMD5 || (cfg.mode == AuthModeAny && !isHMAC):
+			err = md5Verify(ak, auth, cred.SK, ts, cfg.skew)
+		case cfg.mode == AuthModeHMAC || cfg.mode == AuthModeAny:
+			err = cfg.hmacCheck(ctx, cred.SK, ts)
+		default:
+			err = fmt.Errorf("auth mode not support, %s", cfg.mode)
+		}
+		if err == nil {
+			err = cred.Usable()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			reqCtx.SetErrorResponse(http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body exceed %d bytes", maxBytesErr.Limit))
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			reqCtx.SetErrorResponse(http.StatusUnauthorized, http.StatusUnauthorized, err.Error())
+			ctx.Abort()
+			return
+		}
+		if !cred.AllowRoute(ctx.Request.URL.Path) {
+			reqCtx.SetErrorResponse(http.StatusForbidden, http.StatusForbidden, "route not allowed")
+			ctx.Abort()
+			return
+		}
+
+		reqCtx.AK = cred.AK
+		reqCtx.Owner = cred.Owner
+		ctx.Next()
+	}
+}
+
+// hmacCheck - read body at most maxBody and check hmac signature
+func (cfg *authConfig) hmacCheck(ctx *gin.Context, sk string, ts int64) error {
+	var body []byte
+	if ctx.Request.Body != nil {
+		if cfg.maxBody > 0 {
+			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, cfg.maxBody)
+		}
+		bts, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			return fmt.Errorf("read request body error, %w", err)
+		}
+		body = bts
+		ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bts))
+	}
+	return HMACCheck(ctx.Request, body, sk, ts, cfg.nonceStore, cfg.skew)
+}
+
+// BodyLimitFunc - limit request body to n bytes, use before any middleware reading body, e.g. ACLog
+// read over limit get *http.MaxBytesError, AuthFunc respond 413
+func BodyLimitFunc(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if n > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		}
+		c.Next()
+	}
+}
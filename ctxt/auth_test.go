@@ -0,0 +1,203 @@
+package ctxt
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// doRequest - serve request by handler, decode response
+func doRequest(t *testing.T, h http.Handler, r *http.Request) (*httptest.ResponseRecorder, *Response) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	rsps := &Response{}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), rsps); err != nil {
+			t.Fatalf("decode response %q error, %v", w.Body.String(), err)
+		}
+	}
+	return w, rsps
+}
+
+// authEngine - engine with response chain and auth protected echo route
+func authEngine(store CredentialStore, opts ...AuthOption) *gin.Engine {
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc())
+	engine.POST("/api/v1/echo", AuthFunc(append([]AuthOption{WithCredentialStore(store)}, opts...)...), func(c *gin.Context) {
+		bts, err := readBody(c)
+		if err != nil {
+			GetCTX(c).SetError(err)
+			return
+		}
+		GetCTX(c).SetData(string(bts))
+	})
+	return engine
+}
+
+func testCredentialStore() *MemoryCredentialStore {
+	store := NewMemoryCredentialStore()
+	store.Put(&Credential{AK: "ak1", SK: "sk1", Owner: "alice"})
+	return store
+}
+
+func TestAuthHMAC(t *testing.T) {
+	engine := authEngine(testCredentialStore(), WithAuthMode(AuthModeHMAC))
+	signer := NewSigner("ak1", "sk1")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/echo?b=2&a=1", strings.NewReader(`{"x":1}`))
+	if err := signer.Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	replay := r.Clone(r.Context())
+	replay.Body, _ = r.GetBody()
+
+	w, rsps := doRequest(t, engine, r)
+	if w.Code != http.StatusOK || rsps.Data != `{"x":1}` {
+		t.Fatalf("signed request %d %+v", w.Code, rsps)
+	}
+
+	// same nonce
+	w, rsps = doRequest(t, engine, replay)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(rsps.Msg, "nonce") {
+		t.Fatalf("replayed request %d %+v", w.Code, rsps)
+	}
+
+	// body changed after sign
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/echo", strings.NewReader(`{"x":1}`))
+	if err := signer.Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	r2 := httptest.NewRequest(http.MethodPost, "/api/v1/echo", strings.NewReader(`{"x":2}`))
+	r2.Header = r.Header
+	if w, _ = doRequest(t, engine, r2); w.Code != http.StatusUnauthorized {
+		t.Fatalf("tampered body %d", w.Code)
+	}
+
+	// md5 rejected in hmac mode
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/echo", nil)
+	signer.SignMD5(r)
+	if w, _ = doRequest(t, engine, r); w.Code != http.StatusUnauthorized {
+		t.Fatalf("md5 in hmac mode %d", w.Code)
+	}
+
+	// wrong sk
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/echo", nil)
+	if err := NewSigner("ak1", "bad").Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ = doRequest(t, engine, r); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong sk %d", w.Code)
+	}
+}
+
+func TestAuthMD5Skew(t *testing.T) {
+	store := testCredentialStore()
+	old := time.Now().Add(-time.Minute * 3).Unix()
+	md5Request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/echo", nil)
+		r.Header.Set(HeaderAK, "ak1")
+		r.Header.Set(HeaderTimestamp, strconv.FormatInt(old, 10))
+		r.Header.Set(HeaderAuthorization, hopeAuth("ak1", "sk1", old))
+		return r
+	}
+
+	if w, _ := doRequest(t, authEngine(store), md5Request()); w.Code != http.StatusOK {
+		t.Fatalf("md5 in default skew %d", w.Code)
+	}
+	w, rsps := doRequest(t, authEngine(store, WithSignSkew(time.Minute)), md5Request())
+	if w.Code != http.StatusUnauthorized || rsps.Msg != "timestamp out of range" {
+		t.Fatalf("md5 out of configured skew %d %+v", w.Code, rsps)
+	}
+}
+
+func TestAuthCredential(t *testing.T) {
+	store := testCredentialStore()
+	store.Put(&Credential{AK: "ak2", SK: "sk2", RoutePrefixes: []string{"/api/v2"}})
+	store.Put(&Credential{AK: "ak3", SK: "sk3", Disabled: true})
+	engine := authEngine(store, WithAuthMode(AuthModeAny))
+
+	cases := []struct {
+		ak, sk string
+		code   int
+	}{
+		{"ak1", "sk1", http.StatusOK},
+		{"ak2", "sk2", http.StatusForbidden},
+		{"ak3", "sk3", http.StatusUnauthorized},
+		{"ak4", "sk4", http.StatusUnauthorized},
+	}
+	for _, cs := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/echo", nil)
+		if err := NewSigner(cs.ak, cs.sk).Sign(r); err != nil {
+			t.Fatal(err)
+		}
+		if w, _ := doRequest(t, engine, r); w.Code != cs.code {
+			t.Fatalf("%s got %d, want %d", cs.ak, w.Code, cs.code)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/echo", nil)
+	r.Header.Set(HeaderAK, "ak1")
+	if w, _ := doRequest(t, engine, r); w.Code != http.StatusBadRequest {
+		t.Fatalf("no timestamp %d", w.Code)
+	}
+}
+
+type countReader struct {
+	r io.Reader
+	n int
+}
+
+// Read implements io.Reader
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestAuthMaxSignBody(t *testing.T) {
+	store := testCredentialStore()
+	s, err := NewServer("127.0.0.1:0", WithServerAuth(WithCredentialStore(store), WithAuthMode(AuthModeHMAC), WithMaxSignBody(16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.API("/api/v1").POST("/echo", func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	})
+
+	signed := func(body string) (*http.Request, *countReader) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/echo", strings.NewReader(body))
+		if err := NewSigner("ak1", "sk1").Sign(r); err != nil {
+			t.Fatal(err)
+		}
+		cr := &countReader{r: strings.NewReader(body)}
+		r.Body = io.NopCloser(cr)
+		return r, cr
+	}
+
+	r, _ := signed(`{"a":1}`)
+	if w, _ := doRequest(t, s.Engine(), r); w.Code != http.StatusOK {
+		t.Fatalf("small body %d", w.Code)
+	}
+	// access log read body before auth, limit applied before it
+	r, cr := signed(string(bytes.Repeat([]byte("x"), 1<<20)))
+	w, rsps := doRequest(t, s.Engine(), r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large body %d %+v", w.Code, rsps)
+	}
+	if cr.n > 4096 {
+		t.Fatalf("read %d bytes of body over limit", cr.n)
+	}
+}
@@ -0,0 +1,323 @@
+// auth credential store, in memory, json file with hot reload, mysql
+
+package ctxt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/itoolkits/toolkit/collect"
+	"github.com/itoolkits/toolkit/daot"
+)
+
+const (
+	defaultCredentialCacheTTL     = time.Second * 30
+	defaultCredentialCacheEntries = 10000
+)
+
+type Credential struct {
+	AK            string    `json:"ak"`
+	SK            string    `json:"sk"`
+	Owner         string    `json:"owner"`
+	RoutePrefixes []string  `json:"route_prefixes"` // empty allow all routes
+	ExpireAt      time.Time `json:"expire_at"`      // zero never expire
+	Disabled      bool      `json:"disabled"`
+}
+
+// Usable - check credential not disabled and not expired
+func (c *Credential) Usable() error {
+	if c.Disabled {
+		return fmt.Errorf("ak disabled")
+	}
+	if !c.ExpireAt.IsZero() && time.Now().After(c.ExpireAt) {
+		return fmt.Errorf("ak expired")
+	}
+	return nil
+}
+
+// AllowRoute - check route path allowed, prefix match on path segment boundary
+// e.g. prefix /api/v1 allow /api/v1 and /api/v1/x, not /api/v10
+func (c *Credential) AllowRoute(path string) bool {
+	if len(c.RoutePrefixes) < 1 {
+		return true
+	}
+	for _, prefix := range c.RoutePrefixes {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type CredentialStore interface {
+	// Get credential by ak, return nil when not exist
+	Get(ak string) (*Credential, error)
+}
+
+var defaultCredentialStore = NewMemoryCredentialStore()
+
+// DefaultCredentialStore - default store, AddAKSKCache write into it
+func DefaultCredentialStore() *MemoryCredentialStore {
+	return defaultCredentialStore
+}
+
+// MemoryCredentialStore - concurrency safe in memory store
+type MemoryCredentialStore struct {
+	mu    sync.RWMutex
+	creds map[string]*Credential
+}
+
+var _ CredentialStore = (*MemoryCredentialStore)(nil)
+
+// NewMemoryCredentialStore - create memory store
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{
+		creds: make(map[string]*Credential),
+	}
+}
+
+// Get implements CredentialStore
+func (m *MemoryCredentialStore) Get(ak string) (*Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.creds[ak], nil
+}
+
+// Put - add or replace credential
+func (m *MemoryCredentialStore) Put(cred *Credential) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.creds[cred.AK] = cred
+}
+
+// Replace - replace all credentials
+func (m *MemoryCredentialStore) Replace(creds []*Credential) {
+	cm := make(map[string]*Credential, len(creds))
+	for _, cred := range creds {
+		cm[cred.AK] = cred
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.creds = cm
+}
+
+// Revoke - delete credential
+func (m *MemoryCredentialStore) Revoke(ak string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.creds, ak)
+}
+
+// SetDisabled - disable or enable credential
+func (m *MemoryCredentialStore) SetDisabled(ak string, disabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cred, h := m.creds[ak]
+	if !h {
+		return
+	}
+	c := *cred
+	c.Disabled = disabled
+	m.creds[ak] = &c
+}
+
+// FileCredentialStore - json file store, reload when file changed
+type FileCredentialStore struct {
+	path string
+
+	mem *MemoryCredentialStore
+
+	modTime time.Time
+	size    int64
+
+	done chan struct{}
+	once sync.Once
+}
+
+var _ CredentialStore = (*FileCredentialStore)(nil)
+
+// NewFileCredentialStore - create file store, file is json array of Credential, check change every interval
+func NewFileCredentialStore(path string, interval time.Duration) (*FileCredentialStore, error) {
+	f := &FileCredentialStore{
+		path: path,
+		mem:  NewMemoryCredentialStore(),
+		done: make(chan struct{}),
+	}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = time.Second * 5
+	}
+	go f.watch(interval)
+	return f, nil
+}
+
+// Get implements CredentialStore
+func (f *FileCredentialStore) Get(ak string) (*Credential, error) {
+	return f.mem.Get(ak)
+}
+
+// Close - stop watching file
+func (f *FileCredentialStore) Close() {
+	f.once.Do(func() {
+		close(f.done)
+	})
+}
+
+// watch - poll file change
+func (f *FileCredentialStore) watch(interval time.Duration) {
+	tck := time.NewTicker(interval)
+	defer tck.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-tck.C:
+			if err := f.reload(); err != nil {
+				slog.Error("reload credential file error", "path", f.path, "error", err)
+			}
+		}
+	}
+}
+
+// reload - reload file when mod time or size changed
+func (f *FileCredentialStore) reload() error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return nil
+	}
+	bts, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	creds := make([]*Credential, 0)
+	if err = json.Unmarshal(bts, &creds); err != nil {
+		return fmt.Errorf("credential file format error, %w", err)
+	}
+	f.mem.Replace(creds)
+	f.modTime = fi.ModTime()
+	f.size = fi.Size()
+	slog.Info("credential file loaded", "path", f.path, "num", len(creds))
+	return nil
+}
+
+type CredentialPO struct {
+	ID            int64      `gorm:"column:id;primary_key" json:"-"`
+	AK            string     `gorm:"column:ak;unique" json:"ak"`
+	SK            string     `gorm:"column:sk" json:"-"`
+	Owner         string     `gorm:"column:owner" json:"owner"`
+	RoutePrefixes string     `gorm:"column:route_prefixes" json:"route_prefixes"` // comma separated
+	ExpireAt      *time.Time `gorm:"column:expire_at;default:null" json:"expire_at"`
+	Disabled      bool       `gorm:"column:disabled" json:"disabled"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;default:null" json:"updated_at"`
+}
+
+func (d *CredentialPO) TableName() string {
+	return "auth_credential"
+}
+
+// MySQLCredentialStore - mysql store, credential cached by ttl, not exist ak cached too
+// change by other replicas take effect after cache ttl
+type MySQLCredentialStore struct {
+	db    *gorm.DB
+	dao   *daot.Dao[CredentialPO]
+	cache *collect.Cache[string, *Credential]
+}
+
+var _ CredentialStore = (*MySQLCredentialStore)(nil)
+
+// NewMySQLCredentialStore - create mysql store, cacheTTL <= 0 default 30s, Close to stop cache cleaner
+func NewMySQLCredentialStore(db *gorm.DB, cacheTTL time.Duration) *MySQLCredentialStore {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCredentialCacheTTL
+	}
+	return &MySQLCredentialStore{
+		db:  db,
+		dao: daot.NewDao[CredentialPO](db),
+		cache: collect.NewCache[string, *Credential](
+			collect.WithCacheTTL[string, *Credential](cacheTTL),
+			collect.WithCacheMaxEntries[string, *Credential](defaultCredentialCacheEntries),
+		),
+	}
+}
+
+// Get implements CredentialStore
+func (m *MySQLCredentialStore) Get(ak string) (*Credential, error) {
+	if ak == "" {
+		return nil, nil
+	}
+	return m.cache.GetOrLoad(context.Background(), ak, m.load)
+}
+
+// Close - stop cache cleaner
+func (m *MySQLCredentialStore) Close() {
+	m.cache.Close()
+}
+
+// load - query credential, nil when not exist
+func (m *MySQLCredentialStore) load(_ context.Context, ak string) (*Credential, error) {
+	pos := make([]*CredentialPO, 0, 1)
+	rst := m.db.Where(" ak = ? ", ak).Limit(1).Find(&pos)
+	if rst.Error != nil {
+		return nil, rst.Error
+	}
+	if rst.RowsAffected < 1 {
+		return nil, nil
+	}
+	po := pos[0]
+	cred := &Credential{
+		AK:       po.AK,
+		SK:       po.SK,
+		Owner:    po.Owner,
+		Disabled: po.Disabled,
+	}
+	if po.ExpireAt != nil {
+		cred.ExpireAt = *po.ExpireAt
+	}
+	for _, prefix := range strings.Split(po.RoutePrefixes, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" {
+			cred.RoutePrefixes = append(cred.RoutePrefixes, prefix)
+		}
+	}
+	return cred, nil
+}
+
+// Save - add or update credential
+func (m *MySQLCredentialStore) Save(cred *Credential) error {
+	if cred.AK == "" {
+		return fmt.Errorf("ak can not blank")
+	}
+	po, err := m.dao.Get(&CredentialPO{AK: cred.AK})
+	if err != nil {
+		return err
+	}
+	po.AK = cred.AK
+	po.SK = cred.SK
+	po.Owner = cred.Owner
+	po.RoutePrefixes = strings.Join(cred.RoutePrefixes, ",")
+	po.ExpireAt = nil
+	if !cred.ExpireAt.IsZero() {
+		expireAt := cred.ExpireAt
+		po.ExpireAt = &expireAt
+	}
+	po.Disabled = cred.Disabled
+	po.UpdatedAt = time.Now()
+	if err = m.dao.Save(po); err != nil {
+		return err
+	}
+	m.cache.Delete(cred.AK)
+	return nil
+}
@@ -0,0 +1,155 @@
+package ctxt
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCredentialAllowRoute(t *testing.T) {
+	cred := &Credential{RoutePrefixes: []string{"/api/v1", "/admin/"}}
+	cases := map[string]bool{
+		"/api/v1":        true,
+		"/api/v1/":       true,
+		"/api/v1/user":   true,
+		"/api/v10":       false,
+		"/api/v10/user":  false,
+		"/api":           false,
+		"/admin/":        true,
+		"/admin/x":       true,
+		"/administrator": false,
+	}
+	for path, want := range cases {
+		if got := cred.AllowRoute(path); got != want {
+			t.Fatalf("allow %s = %v, want %v", path, got, want)
+		}
+	}
+	if !(&Credential{}).AllowRoute("/any") {
+		t.Fatal("empty prefixes should allow all routes")
+	}
+}
+
+func TestCredentialUsable(t *testing.T) {
+	if err := (&Credential{}).Usable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Credential{Disabled: true}).Usable(); err == nil {
+		t.Fatal("disabled credential usable")
+	}
+	if err := (&Credential{ExpireAt: time.Now().Add(-time.Second)}).Usable(); err == nil {
+		t.Fatal("expired credential usable")
+	}
+	if err := (&Credential{ExpireAt: time.Now().Add(time.Hour)}).Usable(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryCredentialStore(t *testing.T) {
+	store := NewMemoryCredentialStore()
+	store.Put(&Credential{AK: "ak1", SK: "sk1"})
+
+	cred, err := store.Get("ak1")
+	if err != nil || cred == nil || cred.SK != "sk1" {
+		t.Fatalf("get ak1 %+v %v", cred, err)
+	}
+
+	store.SetDisabled("ak1", true)
+	if got, _ := store.Get("ak1"); !got.Disabled {
+		t.Fatal("ak1 not disabled")
+	}
+	if cred.Disabled {
+		t.Fatal("credential got before disable modified")
+	}
+	store.SetDisabled("ak2", true)
+	if got, _ := store.Get("ak2"); got != nil {
+		t.Fatal("disable not exist ak added it")
+	}
+
+	store.Replace([]*Credential{{AK: "ak3", SK: "sk3"}})
+	if got, _ := store.Get("ak1"); got != nil {
+		t.Fatal("ak1 not removed by replace")
+	}
+	store.Revoke("ak3")
+	if got, _ := store.Get("ak3"); got != nil {
+		t.Fatal("ak3 not revoked")
+	}
+
+	// concurrent access, run with -race
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ak := "ak-" + strconv.Itoa(i)
+			for j := 0; j < 100; j++ {
+				store.Put(&Credential{AK: ak, SK: strconv.Itoa(j)})
+				store.SetDisabled(ak, j%2 == 0)
+				if _, err := store.Get(ak); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// writeCredentials - write credentials into json file
+func writeCredentials(t *testing.T, path string, creds []*Credential) {
+	t.Helper()
+	bts, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, bts, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	writeCredentials(t, path, []*Credential{{AK: "ak1", SK: "sk1", RoutePrefixes: []string{"/api"}}})
+
+	store, err := NewFileCredentialStore(path, time.Millisecond*10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cred, _ := store.Get("ak1")
+	if cred == nil || cred.SK != "sk1" || !cred.AllowRoute("/api/x") {
+		t.Fatalf("get ak1 %+v", cred)
+	}
+
+	writeCredentials(t, path, []*Credential{{AK: "ak2", SK: "sk2-rotated"}})
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		got, _ := store.Get("ak2")
+		if got != nil && got.SK == "sk2-rotated" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("credential file not reloaded")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if got, _ := store.Get("ak1"); got != nil {
+		t.Fatal("ak1 not removed after reload")
+	}
+
+	// broken file keep loaded credentials
+	if err = os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	if got, _ := store.Get("ak2"); got == nil {
+		t.Fatal("credentials dropped by broken file")
+	}
+
+	if _, err = NewFileCredentialStore(filepath.Join(t.TempDir(), "none.json"), 0); err == nil {
+		t.Fatal("not exist file should fail")
+	}
+}
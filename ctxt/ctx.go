@@ -3,6 +3,7 @@
 package ctxt
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -17,16 +18,34 @@ const (
 type RqstCtx struct {
 	RequestID string
 
+	TraceID      string // w3c trace id
+	SpanID       string // server span id
+	ParentSpanID string // remote parent span id
+	TraceState   string
+
+	AK    string // authorized ak
+	Owner string // authorized ak owner
+
 	Response *Response
 
 	HTTPCode int
 
 	TraceStack string
+
+	StreamMode  string // ndjson or sse when response streamed
+	StreamItems int    // items sent of streamed response
+	StreamCode  int    // http code of stream status frame, 0 status frame not written
+
+	stream *StreamWriter
+	ctx    context.Context // request context, trace ids of error log
 }
 
 // initRequestCTX - init request ctx
 func (r *RqstCtx) init(c *gin.Context) {
 	r.setRequestID(c)
+	if c.Request != nil {
+		r.ctx = c.Request.Context()
+	}
 	if r.Response == nil {
 		r.Response = &Response{
 			SCode:     http.StatusOK,
@@ -61,6 +80,24 @@ func (r *RqstCtx) SetErrorResponse(httpCode int, sCode int, msg string) {
 	r.Response.RequestID = r.RequestID
 }
 
+// SetError - set error response, err resolved by DefaultErrorRegistry with request context, details as data
+func (r *RqstCtx) SetError(err error) {
+	appErr := defaultErrorRegistry.ResolveContext(r.requestContext(), err)
+	if appErr == nil {
+		return
+	}
+	r.SetErrorResponse(appErr.HTTPCode, appErr.SCode, appErr.Msg)
+	r.Response.Data = appErr.Details
+}
+
+// requestContext - request context, background when not set
+func (r *RqstCtx) requestContext() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
 // SetStatusResponse - set status response
 func (r *RqstCtx) SetStatusResponse(sCode int, msg string, args ...any) {
 	r.SetHTTPCode(http.StatusOK)
@@ -85,6 +122,12 @@ func (r *RqstCtx) SetData(data any) {
 	r.Response.RequestID = r.RequestID
 }
 
+// SetPage - set data with page meta
+func (r *RqstCtx) SetPage(data any, page *PageMeta) {
+	r.SetData(data)
+	r.Response.Page = page
+}
+
 // GetCTX - get context
 func GetCTX(c *gin.Context) *RqstCtx {
 	reqCTXObj, have := c.Get(requestContext)
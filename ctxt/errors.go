@@ -0,0 +1,353 @@
+// typed application error, error registry and localized message
+// handler attach error by c.Error, ErrorMapFunc map it into response
+
+package ctxt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AppError struct {
+	HTTPCode int
+	SCode    int
+	Msg      string
+	Key      string // message key of localized template, empty no localization
+	Args     []any  // template args
+	Details  any    // response data, e.g. field errors
+	Cause    error
+}
+
+var (
+	ErrBadRequest      = NewError(http.StatusBadRequest, http.StatusBadRequest, "bad request").WithKey("bad_request")
+	ErrUnauthorized    = NewError(http.StatusUnauthorized, http.StatusUnauthorized, "no authorization").WithKey("unauthorized")
+	ErrForbidden       = NewError(http.StatusForbidden, http.StatusForbidden, "forbidden").WithKey("forbidden")
+	ErrNotFound        = NewError(http.StatusNotFound, http.StatusNotFound, "not found").WithKey("not_found")
+	ErrConflict        = NewError(http.StatusConflict, http.StatusConflict, "conflict").WithKey("conflict")
+	ErrTooManyRequests = NewError(http.StatusTooManyRequests, http.StatusTooManyRequests, "too many requests").WithKey("too_many_requests")
+	ErrInternal        = NewError(http.StatusInternalServerError, http.StatusInternalServerError, "internal error").WithKey("internal")
+)
+
+// NewError - create app error
+func NewError(httpCode, sCode int, msg string) *AppError {
+	return &AppError{
+		HTTPCode: httpCode,
+		SCode:    sCode,
+		Msg:      msg,
+	}
+}
+
+// Error implements error
+func (e *AppError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s, %v", e.Msg, e.Cause)
+	}
+	return e.Msg
+}
+
+// Unwrap - cause error
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// Is - same http code and business code, so errors.Is(err, ErrNotFound) works on copies
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	if !ok {
+		return false
+	}
+	return e.HTTPCode == t.HTTPCode && e.SCode == t.SCode
+}
+
+// clone - shallow copy, predefined errors not modified
+func (e *AppError) clone() *AppError {
+	c := *e
+	return &c
+}
+
+// WithMsg - copy with message, format by args
+func (e *AppError) WithMsg(msg string, args ...any) *AppError {
+	c := e.clone()
+	c.Msg = msg
+	if len(args) > 0 {
+		c.Msg = fmt.Sprintf(msg, args...)
+	}
+	return c
+}
+
+// WithKey - copy with localized message key
+func (e *AppError) WithKey(key string, args ...any) *AppError {
+	c := e.clone()
+	c.Key = key
+	c.Args = args
+	return c
+}
+
+// WithDetails - copy with details
+func (e *AppError) WithDetails(details any) *AppError {
+	c := e.clone()
+	c.Details = details
+	return c
+}
+
+// WithCause - copy with cause
+func (e *AppError) WithCause(err error) *AppError {
+	c := e.clone()
+	c.Cause = err
+	return c
+}
+
+// ErrorRegistry - map known errors into app error
+type ErrorRegistry struct {
+	mu      sync.RWMutex
+	targets []errorTarget
+	funcs   []func(err error) *AppError
+}
+
+type errorTarget struct {
+	target error
+	appErr *AppError
+}
+
+var defaultErrorRegistry = newDefaultErrorRegistry()
+
+// DefaultErrorRegistry - default registry, gorm.ErrRecordNotFound registered as not found
+func DefaultErrorRegistry() *ErrorRegistry {
+	return defaultErrorRegistry
+}
+
+// NewErrorRegistry - create empty registry
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{}
+}
+
+// newDefaultErrorRegistry - registry with common errors
+func newDefaultErrorRegistry() *ErrorRegistry {
+	r := NewErrorRegistry()
+	r.Register(gorm.ErrRecordNotFound, ErrNotFound.WithMsg("record not found"))
+	return r
+}
+
+// Register - map target error into app error, match by errors.Is, later registered first
+func (r *ErrorRegistry) Register(target error, appErr *AppError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.targets = append(r.targets, errorTarget{target: target, appErr: appErr})
+}
+
+// RegisterFunc - map error by func, return nil when not matched
+func (r *ErrorRegistry) RegisterFunc(fn func(err error) *AppError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs = append(r.funcs, fn)
+}
+
+// Resolve - app error of err, unknown error as internal error
+func (r *ErrorRegistry) Resolve(err error) *AppError {
+	return r.ResolveContext(context.Background(), err)
+}
+
+// ResolveContext - app error of err, unknown error as generic internal error
+// message of unknown error never returned to client, logged with ctx as cause
+func (r *ErrorRegistry) ResolveContext(ctx context.Context, err error) *AppError {
+	if err == nil {
+		return nil
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.targets) - 1; i >= 0; i-- {
+		if errors.Is(err, r.targets[i].target) {
+			return r.targets[i].appErr.WithCause(err)
+		}
+	}
+	for i := len(r.funcs) - 1; i >= 0; i-- {
+		if appErr = r.funcs[i](err); appErr != nil {
+			return appErr
+		}
+	}
+	slog.ErrorContext(ctx, "unknown error resolved as internal error", "error", err)
+	return ErrInternal.WithCause(err)
+}
+
+// MessageCatalog - localized message templates, fmt verbs filled by error args
+type MessageCatalog struct {
+	mu   sync.RWMutex
+	msgs map[string]map[string]string
+}
+
+var defaultMessageCatalog = NewMessageCatalog()
+
+// DefaultMessageCatalog - default catalog, used by ErrorMapFunc
+func DefaultMessageCatalog() *MessageCatalog {
+	return defaultMessageCatalog
+}
+
+// NewMessageCatalog - create catalog
+func NewMessageCatalog() *MessageCatalog {
+	return &MessageCatalog{
+		msgs: make(map[string]map[string]string),
+	}
+}
+
+// Add - add templates of language, e.g. zh-CN
+func (m *MessageCatalog) Add(lang string, msgs map[string]string) {
+	lang = strings.ToLower(lang)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lm, h := m.msgs[lang]
+	if !h {
+		lm = make(map[string]string, len(msgs))
+		m.msgs[lang] = lm
+	}
+	for k, v := range msgs {
+		lm[k] = v
+	}
+}
+
+// Message - localized message of key, try languages in order, zh-CN fall back to zh
+func (m *MessageCatalog) Message(langs []string, key string, args ...any) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, lang := range langs {
+		lang = strings.ToLower(lang)
+		for lang != "" {
+			if tmpl, h := m.msgs[lang][key]; h {
+				if len(args) > 0 {
+					return fmt.Sprintf(tmpl, args...), true
+				}
+				return tmpl, true
+			}
+			i := strings.LastIndex(lang, "-")
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	return "", false
+}
+
+// acceptLanguages - languages of Accept-Language header, order by position, q ignored
+func acceptLanguages(header string) []string {
+	langs := make([]string, 0)
+	for _, part := range strings.Split(header, ",") {
+		lang, _, _ := strings.Cut(part, ";")
+		lang = strings.TrimSpace(lang)
+		if lang != "" && lang != "*" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+const errorMapConfigKey = "ErrorMapConfig"
+
+type errorMapConfig struct {
+	registry    *ErrorRegistry
+	catalog     *MessageCatalog
+	defaultLang string
+}
+
+var defaultErrorMapConfig = &errorMapConfig{
+	registry: defaultErrorRegistry,
+	catalog:  defaultMessageCatalog,
+}
+
+type ErrorMapOption func(cfg *errorMapConfig)
+
+// WithErrorRegistry - config error registry, default DefaultErrorRegistry
+func WithErrorRegistry(registry *ErrorRegistry) ErrorMapOption {
+	return func(cfg *errorMapConfig) {
+		cfg.registry = registry
+	}
+}
+
+// WithMessageCatalog - config message catalog, default DefaultMessageCatalog
+func WithMessageCatalog(catalog *MessageCatalog) ErrorMapOption {
+	return func(cfg *errorMapConfig) {
+		cfg.catalog = catalog
+	}
+}
+
+// WithDefaultLang - language when Accept-Language not matched
+func WithDefaultLang(lang string) ErrorMapOption {
+	return func(cfg *errorMapConfig) {
+		cfg.defaultLang = lang
+	}
+}
+
+// ErrorMapFunc - map last error attached by c.Error into response, use after ResponseFunc
+// Handle, stream and idempotency in chain resolve error by same registry and catalog
+func ErrorMapFunc(opts ...ErrorMapOption) gin.HandlerFunc {
+	cfg := &errorMapConfig{
+		registry: defaultErrorRegistry,
+		catalog:  defaultMessageCatalog,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *gin.Context) {
+		c.Set(errorMapConfigKey, cfg)
+
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil {
+			return
+		}
+		GetCTX(c).SetError(cfg.mapError(c, last.Err))
+	}
+}
+
+// mapError - resolve err by registry and localize message by Accept-Language
+// unknown error logged once, app error returned as is
+func (cfg *errorMapConfig) mapError(c *gin.Context, err error) *AppError {
+	appErr := cfg.registry.ResolveContext(c.Request.Context(), err)
+	if appErr == nil {
+		return nil
+	}
+	langs := acceptLanguages(c.GetHeader("Accept-Language"))
+	if cfg.defaultLang != "" {
+		langs = append(langs, cfg.defaultLang)
+	}
+	if msg, ok := cfg.catalog.Message(langs, appErr.Key, appErr.Args...); ok && msg != appErr.Msg {
+		appErr = appErr.WithMsg(msg)
+	}
+	return appErr
+}
+
+// mapError - resolve err by config of ErrorMapFunc in chain, default registry and catalog without it
+func mapError(c *gin.Context, err error) *AppError {
+	cfg := defaultErrorMapConfig
+	if v, h := c.Get(errorMapConfigKey); h {
+		cfg = v.(*errorMapConfig)
+	}
+	return cfg.mapError(c, err)
+}
+
+// HandleErr - handler returning error, error attached by c.Error
+func HandleErr(fn func(c *gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := fn(c); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+		}
+	}
+}
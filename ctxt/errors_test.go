@@ -0,0 +1,165 @@
+package ctxt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/itoolkits/toolkit/tracet"
+)
+
+// captureSystemLog - default slog records written while fn run, trace ids added
+func captureSystemLog(t *testing.T, fn func()) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	old := slog.Default()
+	slog.SetDefault(slog.New(tracet.NewHandler(slog.NewJSONHandler(buf, nil))))
+	defer slog.SetDefault(old)
+	fn()
+	return buf.String()
+}
+
+func TestErrorRegistry(t *testing.T) {
+	errDup := errors.New("duplicate entry")
+	reg := NewErrorRegistry()
+	reg.Register(errDup, ErrConflict.WithMsg("already exists"))
+	reg.RegisterFunc(func(err error) *AppError {
+		if strings.Contains(err.Error(), "timeout") {
+			return NewError(http.StatusGatewayTimeout, 5040, "upstream timeout")
+		}
+		return nil
+	})
+
+	appErr := reg.Resolve(fmt.Errorf("insert user, %w", errDup))
+	if appErr.HTTPCode != http.StatusConflict || appErr.Msg != "already exists" || !errors.Is(appErr, errDup) {
+		t.Fatalf("registered error %+v", appErr)
+	}
+	if appErr = reg.Resolve(errors.New("read timeout")); appErr.SCode != 5040 {
+		t.Fatalf("func error %+v", appErr)
+	}
+	if appErr = reg.Resolve(ErrForbidden.WithMsg("no")); appErr.Msg != "no" || !errors.Is(appErr, ErrForbidden) {
+		t.Fatalf("app error %+v", appErr)
+	}
+	if reg.Resolve(nil) != nil {
+		t.Fatal("nil error resolved")
+	}
+
+	// later registered first
+	reg.Register(errDup, ErrBadRequest)
+	if appErr = reg.Resolve(errDup); appErr.HTTPCode != http.StatusBadRequest {
+		t.Fatalf("later registered error %+v", appErr)
+	}
+
+	if appErr = DefaultErrorRegistry().Resolve(gorm.ErrRecordNotFound); appErr.HTTPCode != http.StatusNotFound {
+		t.Fatalf("record not found %+v", appErr)
+	}
+
+	// unknown error message not returned, cause logged
+	sc := tracet.NewRoot(true)
+	var unknown *AppError
+	logs := captureSystemLog(t, func() {
+		unknown = reg.ResolveContext(tracet.NewContext(context.Background(), sc), errors.New("dial tcp 10.0.0.1:3306"))
+	})
+	if unknown.Msg != "internal error" || unknown.HTTPCode != http.StatusInternalServerError {
+		t.Fatalf("unknown error %+v", unknown)
+	}
+	if !strings.Contains(logs, "10.0.0.1:3306") || !strings.Contains(logs, sc.TraceID) {
+		t.Fatalf("unknown error log %s", logs)
+	}
+}
+
+func TestMessageCatalog(t *testing.T) {
+	catalog := NewMessageCatalog()
+	catalog.Add("zh", map[string]string{"not_found": "未找到 %s"})
+	catalog.Add("en-US", map[string]string{"not_found": "%s not found"})
+
+	if msg, ok := catalog.Message([]string{"zh-CN"}, "not_found", "user"); !ok || msg != "未找到 user" {
+		t.Fatalf("zh-CN fall back to zh, %s", msg)
+	}
+	if msg, ok := catalog.Message([]string{"fr", "EN-us"}, "not_found", "user"); !ok || msg != "user not found" {
+		t.Fatalf("second language, %s", msg)
+	}
+	if _, ok := catalog.Message([]string{"fr"}, "not_found"); ok {
+		t.Fatal("not exist language matched")
+	}
+	if langs := acceptLanguages("zh-CN;q=0.9, en;q=0.8, *"); len(langs) != 2 || langs[0] != "zh-CN" || langs[1] != "en" {
+		t.Fatalf("accept languages %v", langs)
+	}
+}
+
+// errorEngine - engine with error map chain, trace and routes returning errors
+func errorEngine(opts ...ErrorMapOption) *gin.Engine {
+	engine := gin.New()
+	engine.Use(TraceFunc(), ResponseFunc(), ErrorFunc(), ErrorMapFunc(opts...))
+	engine.GET("/err", HandleErr(func(c *gin.Context) error {
+		return errors.New(c.Query("err"))
+	}))
+	engine.GET("/set", func(c *gin.Context) {
+		GetCTX(c).SetError(errors.New("set error cause"))
+	})
+	engine.GET("/typed", Handle(func(ctx context.Context, req *struct{}) (*struct{}, error) {
+		return nil, errors.New("typed error cause")
+	}))
+	engine.GET("/panic", func(c *gin.Context) {
+		panic(ErrForbidden.WithMsg("panic forbidden"))
+	})
+	return engine
+}
+
+func TestErrorMapFunc(t *testing.T) {
+	reg := NewErrorRegistry()
+	reg.RegisterFunc(func(err error) *AppError {
+		if err.Error() == "quota" {
+			return ErrTooManyRequests.WithKey("quota_exceeded", 10).WithCause(err)
+		}
+		return nil
+	})
+	catalog := NewMessageCatalog()
+	catalog.Add("zh", map[string]string{"quota_exceeded": "超出配额 %d"})
+	engine := errorEngine(WithErrorRegistry(reg), WithMessageCatalog(catalog), WithDefaultLang("en"))
+
+	r := httptest.NewRequest(http.MethodGet, "/err?err=quota", nil)
+	r.Header.Set("Accept-Language", "zh-CN")
+	w, rsps := doRequest(t, engine, r)
+	if w.Code != http.StatusTooManyRequests || rsps.Msg != "超出配额 10" {
+		t.Fatalf("mapped error %d %+v", w.Code, rsps)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/err?err=quota", nil)
+	if w, rsps = doRequest(t, engine, r); rsps.Msg != "too many requests" {
+		t.Fatalf("not localized error %d %+v", w.Code, rsps)
+	}
+
+	w, rsps = doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusForbidden || rsps.Msg != "panic forbidden" {
+		t.Fatalf("panic app error %d %+v", w.Code, rsps)
+	}
+}
+
+func TestErrorLoggedOnceWithTrace(t *testing.T) {
+	engine := errorEngine()
+	for _, path := range []string{"/err?err=secret+dsn", "/set", "/typed"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(tracet.HeaderTraceParent, testTraceParent)
+		var w *httptest.ResponseRecorder
+		var rsps *Response
+		logs := captureSystemLog(t, func() {
+			w, rsps = doRequest(t, engine, r)
+		})
+		if w.Code != http.StatusInternalServerError || rsps.Msg != "internal error" {
+			t.Fatalf("%s response %d %+v", path, w.Code, rsps)
+		}
+		lines := strings.Split(strings.TrimSpace(logs), "\n")
+		if len(lines) != 1 || !strings.Contains(lines[0], "4bf92f3577b34da6a3ce929d0e0e4736") {
+			t.Fatalf("%s error logs %v", path, lines)
+		}
+	}
+}
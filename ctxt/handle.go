@@ -0,0 +1,261 @@
+// generic handler adapter, bind uri/query/header/body, validate, set data or error
+// tags: uri path param, form query or form body, header, json body, binding validator rule
+
+package ctxt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultMultipartMemory = 32 << 20
+
+type rqstCtxKey struct{}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	Msg   string `json:"msg"`
+}
+
+// HandlerFunc - typed handler, ctx carry RqstCtx and trace context
+type HandlerFunc[Req, Resp any] func(ctx context.Context, req *Req) (*Resp, error)
+
+// Handle - adapt typed handler into gin handler
+// bind failure and validation failure set 400, result set by SetData
+// error resolved once by ErrorMapFunc config in chain, app error set by c.Error and SetError
+func Handle[Req, Resp any](fn HandlerFunc[Req, Resp]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := GetCTX(c)
+
+		req := new(Req)
+		if err := Bind(c, req); err != nil {
+			setHandleError(c, reqCtx, err)
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), rqstCtxKey{}, reqCtx)
+		resp, err := fn(ctx, req)
+		if err != nil {
+			setHandleError(c, reqCtx, err)
+			return
+		}
+		if resp == nil {
+			reqCtx.SetOKResponse()
+			return
+		}
+		reqCtx.SetData(resp)
+	}
+}
+
+// setHandleError - resolve err once, ErrorMapFunc get app error and not log it again
+func setHandleError(c *gin.Context, reqCtx *RqstCtx, err error) {
+	appErr := mapError(c, err)
+	_ = c.Error(appErr)
+	reqCtx.SetError(appErr)
+	c.Abort()
+}
+
+// FromContext - RqstCtx of typed handler ctx, nil when absent
+func FromContext(ctx context.Context) *RqstCtx {
+	reqCtx, _ := ctx.Value(rqstCtxKey{}).(*RqstCtx)
+	return reqCtx
+}
+
+// Bind - bind body, query, uri params and headers into struct ptr, then validate
+func Bind(c *gin.Context, ptr any) error {
+	if err := bindBody(c, ptr); err != nil {
+		return ErrBadRequest.WithMsg("bind body error, %v", err).WithCause(err)
+	}
+	if err := binding.MapFormWithTag(ptr, c.Request.URL.Query(), "form"); err != nil {
+		return ErrBadRequest.WithMsg("bind query error, %v", err).WithCause(err)
+	}
+	if len(c.Params) > 0 {
+		params := make(map[string][]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		if err := binding.MapFormWithTag(ptr, params, "uri"); err != nil {
+			return ErrBadRequest.WithMsg("bind uri error, %v", err).WithCause(err)
+		}
+	}
+	if headers := headerValues(reflect.TypeOf(ptr), c.Request.Header); len(headers) > 0 {
+		if err := binding.MapFormWithTag(ptr, headers, "header"); err != nil {
+			return ErrBadRequest.WithMsg("bind header error, %v", err).WithCause(err)
+		}
+	}
+	return Validate(ptr)
+}
+
+// Validate - validate struct by binding tags, field errors as details
+func Validate(ptr any) error {
+	if binding.Validator == nil {
+		return nil
+	}
+	err := binding.Validator.ValidateStruct(ptr)
+	if err == nil {
+		return nil
+	}
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return ErrBadRequest.WithMsg("validate error, %v", err).WithCause(err)
+	}
+	t := reflect.TypeOf(ptr)
+	fes := make([]*FieldError, 0, len(ves))
+	for _, fe := range ves {
+		field := fieldPath(t, fe.StructNamespace())
+		fes = append(fes, &FieldError{
+			Field: field,
+			Rule:  fe.Tag(),
+			Param: fe.Param(),
+			Msg:   fieldErrorMsg(field, fe),
+		})
+	}
+	msg := "validate error"
+	if len(fes) > 0 {
+		msg = fmt.Sprintf("validate error, %s", fes[0].Msg)
+	}
+	return ErrBadRequest.WithMsg(msg).WithDetails(fes).WithCause(err)
+}
+
+// bindBody - bind json or form body, skip empty body
+func bindBody(c *gin.Context, ptr any) error {
+	r := c.Request
+	if r.Body == nil || r.Body == http.NoBody || r.Method == http.MethodGet {
+		return nil
+	}
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm:
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+		return binding.MapFormWithTag(ptr, r.PostForm, "form")
+	case binding.MIMEMultipartPOSTForm:
+		if err := r.ParseMultipartForm(defaultMultipartMemory); err != nil {
+			return err
+		}
+		return binding.MapFormWithTag(ptr, r.MultipartForm.Value, "form")
+	case binding.MIMEJSON, "":
+		bts, err := readBody(c)
+		if err != nil || len(bts) < 1 {
+			return err
+		}
+		return json.Unmarshal(bts, ptr)
+	default:
+		return fmt.Errorf("content type %s not support", c.ContentType())
+	}
+}
+
+// readBody - read body and reset it, ACLog and others can read again
+func readBody(c *gin.Context) ([]byte, error) {
+	bts, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(bts))
+	return bts, nil
+}
+
+// headerValues - header values of fields with header tag
+func headerValues(t reflect.Type, header http.Header) map[string][]string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	values := make(map[string][]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			for k, v := range headerValues(f.Type, header) {
+				values[k] = v
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("header"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if vs := header.Values(name); len(vs) > 0 {
+			values[name] = vs
+		}
+	}
+	return values
+}
+
+// fieldPath - request field path by struct namespace, e.g. Req.User.Name -> user.name
+func fieldPath(t reflect.Type, ns string) string {
+	segs := strings.Split(ns, ".")
+	if len(segs) > 1 {
+		segs = segs[1:]
+	}
+	names := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		name, idx, _ := strings.Cut(seg, "[")
+		if idx != "" {
+			idx = "[" + idx
+		}
+		for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice ||
+			t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			names = append(names, name+idx)
+			t = nil
+			continue
+		}
+		f, ok := t.FieldByName(name)
+		if !ok {
+			names = append(names, name+idx)
+			t = nil
+			continue
+		}
+		names = append(names, tagName(f)+idx)
+		t = f.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// tagName - field name of json, form, uri or header tag
+func tagName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// fieldErrorMsg - readable message of field error
+func fieldErrorMsg(field string, fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return field + " is required"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", field, fe.Param())
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "len":
+		return fmt.Sprintf("%s length must be %s", field, fe.Param())
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("%s failed on %s=%s", field, fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("%s failed on %s", field, fe.Tag())
+}
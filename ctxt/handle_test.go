@@ -0,0 +1,143 @@
+package ctxt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type handleAddress struct {
+	City string `json:"city" binding:"required"`
+}
+
+type handleReq struct {
+	ID      int64          `uri:"id" binding:"required,min=1"`
+	Page    int            `form:"page" binding:"omitempty,min=1"`
+	Tenant  string         `header:"X-Tenant" binding:"required"`
+	Name    string         `json:"name" form:"name" binding:"required,max=8"`
+	Role    string         `json:"role" binding:"omitempty,oneof=admin user"`
+	Address *handleAddress `json:"address" binding:"omitempty"`
+}
+
+type handleResp struct {
+	ID     int64  `json:"id"`
+	Page   int    `json:"page"`
+	Tenant string `json:"tenant"`
+	Name   string `json:"name"`
+	City   string `json:"city"`
+	ReqID  string `json:"req_id"`
+}
+
+// handleEngine - engine with typed handler route
+func handleEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc(), ErrorMapFunc())
+	engine.POST("/user/:id", Handle(func(ctx context.Context, req *handleReq) (*handleResp, error) {
+		rsp := &handleResp{ID: req.ID, Page: req.Page, Tenant: req.Tenant, Name: req.Name}
+		if req.Address != nil {
+			rsp.City = req.Address.City
+		}
+		if reqCtx := FromContext(ctx); reqCtx != nil {
+			rsp.ReqID = reqCtx.RequestID
+		}
+		if req.Name == "missing" {
+			return nil, ErrNotFound.WithMsg("user %s not found", req.Name)
+		}
+		if req.Name == "empty" {
+			return nil, nil
+		}
+		return rsp, nil
+	}))
+	return engine
+}
+
+// handleRequest - request of typed handler route
+func handleRequest(path, contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("X-Tenant", "t1")
+	return r
+}
+
+func TestHandleBind(t *testing.T) {
+	engine := handleEngine()
+
+	w, rsps := doRequest(t, engine, handleRequest("/user/7?page=2", "application/json",
+		`{"name":"bob","address":{"city":"sh"}}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("bind json %d %+v", w.Code, rsps)
+	}
+	bts, _ := json.Marshal(rsps.Data)
+	got := &handleResp{}
+	_ = json.Unmarshal(bts, got)
+	if got.ID != 7 || got.Page != 2 || got.Tenant != "t1" || got.Name != "bob" || got.City != "sh" || got.ReqID != rsps.RequestID {
+		t.Fatalf("bound request %+v", got)
+	}
+
+	// form body
+	w, rsps = doRequest(t, engine, handleRequest("/user/7", "application/x-www-form-urlencoded", "name=alice"))
+	if w.Code != http.StatusOK || rsps.Data.(map[string]any)["name"] != "alice" {
+		t.Fatalf("bind form %d %+v", w.Code, rsps)
+	}
+
+	// nil response
+	w, rsps = doRequest(t, engine, handleRequest("/user/7", "application/json", `{"name":"empty"}`))
+	if w.Code != http.StatusOK || rsps.Data != nil {
+		t.Fatalf("nil response %d %+v", w.Code, rsps)
+	}
+
+	// handler app error
+	w, rsps = doRequest(t, engine, handleRequest("/user/7", "application/json", `{"name":"missing"}`))
+	if w.Code != http.StatusNotFound || rsps.Msg != "user missing not found" {
+		t.Fatalf("handler error %d %+v", w.Code, rsps)
+	}
+}
+
+func TestHandleBindError(t *testing.T) {
+	engine := handleEngine()
+	cases := []struct {
+		name  string
+		r     *http.Request
+		field string
+		rule  string
+	}{
+		{"broken json", handleRequest("/user/7", "application/json", `{"name":`), "", ""},
+		{"bad query", handleRequest("/user/7?page=x", "application/json", `{"name":"bob"}`), "", ""},
+		{"bad uri", handleRequest("/user/x", "application/json", `{"name":"bob"}`), "", ""},
+		{"content type", handleRequest("/user/7", "text/plain", `name=bob`), "", ""},
+		{"required", handleRequest("/user/7", "application/json", `{}`), "name", "required"},
+		{"max", handleRequest("/user/7", "application/json", `{"name":"toolongname"}`), "name", "max"},
+		{"oneof", handleRequest("/user/7", "application/json", `{"name":"bob","role":"root"}`), "role", "oneof"},
+		{"min", handleRequest("/user/7?page=-1", "application/json", `{"name":"bob"}`), "page", "min"},
+		{"nested", handleRequest("/user/7", "application/json", `{"name":"bob","address":{}}`), "address.city", "required"},
+	}
+	for _, cs := range cases {
+		w, rsps := doRequest(t, engine, cs.r)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s code %d %+v", cs.name, w.Code, rsps)
+		}
+		if cs.field == "" {
+			continue
+		}
+		fes, _ := rsps.Data.([]any)
+		if len(fes) < 1 {
+			t.Fatalf("%s no field errors %+v", cs.name, rsps)
+		}
+		fe := fes[0].(map[string]any)
+		if fe["field"] != cs.field || fe["rule"] != cs.rule || !strings.Contains(rsps.Msg, cs.field) {
+			t.Fatalf("%s field error %+v %s", cs.name, fe, rsps.Msg)
+		}
+	}
+
+	// header required
+	r := handleRequest("/user/7", "application/json", `{"name":"bob"}`)
+	r.Header.Del("X-Tenant")
+	if w, rsps := doRequest(t, engine, r); w.Code != http.StatusBadRequest || !strings.Contains(rsps.Msg, "X-Tenant") {
+		t.Fatalf("header required %d %+v", w.Code, rsps)
+	}
+}
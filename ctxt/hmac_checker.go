@@ -0,0 +1,164 @@
+// hmac-sha256 checker, sign canonical request
+// canonical request:
+//   METHOD\n
+//   PATH\n
+//   SORTED QUERY\n
+//   HEX(SHA256(BODY))\n
+//   TIMESTAMP\n
+//   NONCE
+// Authorization: HMAC-SHA256 HEX(HMAC-SHA256(SK, CANONICAL REQUEST))
+
+package ctxt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderAK            = "AK"
+	HeaderAuthorization = "Authorization"
+	HeaderTimestamp     = "Timestamp"
+	HeaderNonce         = "Nonce"
+
+	HMACScheme = "HMAC-SHA256"
+
+	defaultSignSkew = time.Minute * 5
+)
+
+type NonceStore interface {
+	// Use mark nonce used, return false when nonce has been used in ttl
+	Use(ak, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore in memory nonce store, for single instance
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+
+	lastSweep time.Time
+}
+
+var _ NonceStore = (*MemoryNonceStore)(nil)
+
+// NewMemoryNonceStore create memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:    make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Use implements NonceStore
+func (m *MemoryNonceStore) Use(ak, nonce string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) > ttl {
+		for k, exp := range m.nonces {
+			if now.After(exp) {
+				delete(m.nonces, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	k := ak + "\n" + nonce
+	if exp, h := m.nonces[k]; h && now.Before(exp) {
+		return false, nil
+	}
+	m.nonces[k] = now.Add(ttl)
+	return true, nil
+}
+
+// HMACCheck check hmac-sha256 signature of canonical request, nonce can not reuse in skew window
+func HMACCheck(r *http.Request, body []byte, sk string, ts int64, nonceStore NonceStore, skew time.Duration) error {
+	ak := r.Header.Get(HeaderAK)
+	if ak == "" {
+		return fmt.Errorf("ak can not blank")
+	}
+	if sk == "" {
+		return fmt.Errorf("no authorization")
+	}
+	nonce := r.Header.Get(HeaderNonce)
+	if nonce == "" {
+		return fmt.Errorf("nonce can not blank")
+	}
+	if skew <= 0 {
+		skew = defaultSignSkew
+	}
+
+	cur := time.Now()
+	if ts > cur.Add(skew).Unix() || ts < cur.Add(-skew).Unix() {
+		return fmt.Errorf("timestamp out of range")
+	}
+
+	auth := r.Header.Get(HeaderAuthorization)
+	sig, h := strings.CutPrefix(auth, HMACScheme+" ")
+	if !h {
+		return fmt.Errorf("authorization scheme error")
+	}
+	got, err := hex.DecodeString(strings.TrimSpace(sig))
+	if err != nil {
+		return fmt.Errorf("no authorization")
+	}
+	if !hmac.Equal(got, hmacSign(sk, CanonicalRequest(r, body, ts, nonce))) {
+		return fmt.Errorf("no authorization")
+	}
+
+	if nonceStore != nil {
+		ok, err := nonceStore.Use(ak, nonce, skew*2)
+		if err != nil {
+			return fmt.Errorf("nonce check error, %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("nonce has been used")
+		}
+	}
+	return nil
+}
+
+// CanonicalRequest build canonical request string
+func CanonicalRequest(r *http.Request, body []byte, ts int64, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		strings.ToUpper(r.Method),
+		path,
+		canonicalQuery(r.URL.Query()),
+		hex.EncodeToString(bodyHash[:]),
+		fmt.Sprintf("%d", ts),
+		nonce,
+	}, "\n")
+}
+
+// canonicalQuery escaped key=value pairs, sorted
+func canonicalQuery(values url.Values) string {
+	pairs := make([]string, 0, len(values))
+	for k, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// hmacSign hmac-sha256 sign
+func hmacSign(sk, s string) []byte {
+	h := hmac.New(sha256.New, []byte(sk))
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
@@ -0,0 +1,525 @@
+// idempotency key middleware, first response of ak + key saved and replayed
+// repeat while first request in flight get 409, same key with different request get 422
+// 5xx response and panic release the key, client can retry
+// key scoped by authenticated ak, processing key held by short lease, saved response kept by ttl
+// lease renewed while handler running, complete and release only by owner token of the claim
+
+package ctxt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/itoolkits/toolkit/daot"
+	"github.com/itoolkits/toolkit/mylck"
+)
+
+const (
+	HeaderIdempotencyKey    = "Idempotency-Key"
+	HeaderIdempotentReplay  = "Idempotent-Replayed"
+	IdempotencyProcessing   = "processing"
+	IdempotencyDone         = "done"
+	maxIdempotencyKeyLength = 255
+
+	defaultIdempotencyTTL     = time.Hour * 24
+	defaultIdempotencyLockTTL = time.Minute
+)
+
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string // hash of method, path and body
+	Status      string // processing or done
+	Token       string // owner of processing claim
+	HTTPCode    int
+	Response    *Response
+	ExpireAt    time.Time
+}
+
+// ErrIdempotencyLeaseLost - processing claim expired and taken by other request
+var ErrIdempotencyLeaseLost = errors.New("idempotency lease lost")
+
+type IdempotencyStore interface {
+	// Begin claim key as processing, return existing record and false when key exists and not expired
+	Begin(rec *IdempotencyRecord) (*IdempotencyRecord, bool, error)
+	// Renew extend lease of processing key to rec.ExpireAt, ErrIdempotencyLeaseLost when token not owner
+	Renew(rec *IdempotencyRecord) error
+	// Complete save response of processing key, ErrIdempotencyLeaseLost when token not owner
+	Complete(rec *IdempotencyRecord) error
+	// Release remove processing key owned by token
+	Release(key, token string) error
+}
+
+type idempotencyConfig struct {
+	store    IdempotencyStore
+	ttl      time.Duration
+	lockTTL  time.Duration
+	required bool
+	methods  map[string]struct{}
+}
+
+type IdempotencyOption func(cfg *idempotencyConfig)
+
+// WithIdempotencyStore - config store, default in memory
+func WithIdempotencyStore(store IdempotencyStore) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.store = store
+	}
+}
+
+// WithIdempotencyTTL - saved response kept duration, default 24h
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithIdempotencyLockTTL - lease of processing key, default 1m, renewed every ttl/3 while handler running
+// key of crashed request claimable after lease
+func WithIdempotencyLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.lockTTL = ttl
+	}
+}
+
+// WithIdempotencyRequired - reject request without Idempotency-Key with 400
+func WithIdempotencyRequired(required bool) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.required = required
+	}
+}
+
+// WithIdempotencyMethods - methods checked, default POST, PUT, PATCH, DELETE
+func WithIdempotencyMethods(methods ...string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.methods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			cfg.methods[m] = struct{}{}
+		}
+	}
+}
+
+// IdempotencyFunc - idempotency middleware, use after AuthFunc on mutating routes
+func IdempotencyFunc(opts ...IdempotencyOption) gin.HandlerFunc {
+	cfg := &idempotencyConfig{
+		ttl:     defaultIdempotencyTTL,
+		lockTTL: defaultIdempotencyLockTTL,
+		methods: map[string]struct{}{
+			http.MethodPost:   {},
+			http.MethodPut:    {},
+			http.MethodPatch:  {},
+			http.MethodDelete: {},
+		},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryIdempotencyStore()
+	}
+
+	return func(c *gin.Context) {
+		if _, h := cfg.methods[c.Request.Method]; !h {
+			c.Next()
+			return
+		}
+		reqCtx := GetCTX(c)
+		idemKey := c.GetHeader(HeaderIdempotencyKey)
+		if idemKey == "" {
+			if cfg.required {
+				reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, "idempotency key can not blank")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(idemKey) > maxIdempotencyKeyLength {
+			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, "idempotency key too long")
+			c.Abort()
+			return
+		}
+
+		// authenticated ak only, header ak not verified, keys of route without AuthFunc shared
+		ak := reqCtx.AK
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, "read body error")
+			c.Abort()
+			return
+		}
+		rec := &IdempotencyRecord{
+			Key:         ak + ":" + idemKey,
+			Fingerprint: fingerprint,
+			Status:      IdempotencyProcessing,
+			Token:       uuid.New().String(),
+			ExpireAt:    time.Now().Add(cfg.lockTTL),
+		}
+
+		// serialize claim of same key in process, store decide across replicas
+		kl := mylck.NewKeyLocker("idempotency:" + rec.Key)
+		kl.Lock()
+		saved, claimed, err := cfg.store.Begin(rec)
+		kl.Unlock()
+		if err != nil {
+			reqCtx.SetErrorResponse(http.StatusInternalServerError, http.StatusInternalServerError,
+				"idempotency check error, "+err.Error())
+			c.Abort()
+			return
+		}
+		if !claimed {
+			replayIdempotency(c, reqCtx, rec, saved)
+			c.Abort()
+			return
+		}
+
+		stopRenew := renewIdempotency(c, cfg, *rec)
+		completed := false
+		defer func() {
+			stopRenew()
+			// panic or not saved, release key
+			if !completed {
+				_ = cfg.store.Release(rec.Key, rec.Token)
+			}
+		}()
+
+		c.Next()
+
+		stopRenew()
+		httpCode, rsps := finalResponse(c, reqCtx)
+		if httpCode >= http.StatusInternalServerError || reqCtx.StreamMode != "" {
+			return
+		}
+		rec.Status = IdempotencyDone
+		rec.HTTPCode = httpCode
+		rec.Response = rsps
+		rec.ExpireAt = time.Now().Add(cfg.ttl)
+		if err := cfg.store.Complete(rec); err != nil {
+			slog.WarnContext(c.Request.Context(), "idempotency response not saved", "key", rec.Key, "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// renewIdempotency - renew lease of claimed key every lockTTL/3 until stop, stop wait renew exited
+func renewIdempotency(c *gin.Context, cfg *idempotencyConfig, rec IdempotencyRecord) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(max(cfg.lockTTL/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			rec.ExpireAt = time.Now().Add(cfg.lockTTL)
+			err := cfg.store.Renew(&rec)
+			if errors.Is(err, ErrIdempotencyLeaseLost) {
+				slog.WarnContext(c.Request.Context(), "idempotency lease lost while handler running", "key", rec.Key)
+				return
+			}
+			if err != nil {
+				slog.WarnContext(c.Request.Context(), "idempotency lease renew error", "key", rec.Key, "error", err)
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
+
+// replayIdempotency - replay saved response, 409 in flight, 422 request changed
+func replayIdempotency(c *gin.Context, reqCtx *RqstCtx, rec, saved *IdempotencyRecord) {
+	switch {
+	case saved.Fingerprint != rec.Fingerprint:
+		reqCtx.SetErrorResponse(http.StatusUnprocessableEntity, http.StatusUnprocessableEntity,
+			"idempotency key reused with different request")
+	case saved.Status != IdempotencyDone || saved.Response == nil:
+		reqCtx.SetErrorResponse(http.StatusConflict, http.StatusConflict, "request with same idempotency key in flight")
+	default:
+		rsps := *saved.Response
+		reqCtx.Response = &rsps
+		reqCtx.HTTPCode = saved.HTTPCode
+		c.Header(HeaderIdempotentReplay, "true")
+	}
+}
+
+// finalResponse - http code and response as ResponseFunc render, c.Error resolved
+func finalResponse(c *gin.Context, reqCtx *RqstCtx) (int, *Response) {
+	rsps := NewOKResponse()
+	if reqCtx.Response != nil {
+		*rsps = *reqCtx.Response
+	}
+	if last := c.Errors.Last(); last != nil {
+		appErr := mapError(c, last.Err)
+		rsps.SCode = appErr.SCode
+		rsps.Msg = appErr.Msg
+		rsps.Data = appErr.Details
+		return appErr.HTTPCode, rsps
+	}
+	httpCode := reqCtx.HTTPCode
+	if httpCode == 0 {
+		httpCode = http.StatusOK
+	}
+	if rsps.SCode < 600 {
+		httpCode = rsps.SCode
+	}
+	return httpCode, rsps
+}
+
+// requestFingerprint - sha256 of method, path, query and body
+func requestFingerprint(c *gin.Context) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + "\n" + c.Request.URL.Path + "\n" + canonicalQuery(c.Request.URL.Query()) + "\n"))
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		bts, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(bts))
+		h.Write(bts)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MemoryIdempotencyStore - in memory store, for single instance
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*IdempotencyRecord
+
+	lastSweep time.Time
+}
+
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+// NewMemoryIdempotencyStore - create memory store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records:   make(map[string]*IdempotencyRecord),
+		lastSweep: time.Now(),
+	}
+}
+
+// Begin implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Begin(rec *IdempotencyRecord) (*IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, r := range m.records {
+			if now.After(r.ExpireAt) {
+				delete(m.records, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	if saved, h := m.records[rec.Key]; h && now.Before(saved.ExpireAt) {
+		return saved, false, nil
+	}
+	r := *rec
+	m.records[rec.Key] = &r
+	return nil, true, nil
+}
+
+// owned - processing record of key claimed by token
+func (m *MemoryIdempotencyStore) owned(key, token string) bool {
+	saved, h := m.records[key]
+	return h && saved.Status == IdempotencyProcessing && saved.Token == token
+}
+
+// Renew implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Renew(rec *IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.owned(rec.Key, rec.Token) {
+		return ErrIdempotencyLeaseLost
+	}
+	r := *m.records[rec.Key]
+	r.ExpireAt = rec.ExpireAt
+	m.records[rec.Key] = &r
+	return nil
+}
+
+// Complete implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Complete(rec *IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.owned(rec.Key, rec.Token) {
+		return ErrIdempotencyLeaseLost
+	}
+	r := *rec
+	m.records[rec.Key] = &r
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (m *MemoryIdempotencyStore) Release(key, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owned(key, token) {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+type IdempotencyRecordPO struct {
+	ID          int64     `gorm:"column:id;primary_key" json:"-"`
+	Name        string    `gorm:"column:name;unique" json:"name"`
+	Fingerprint string    `gorm:"column:fingerprint" json:"fingerprint"`
+	Status      string    `gorm:"column:status" json:"status"`
+	Token       string    `gorm:"column:token" json:"token"`
+	HTTPCode    int       `gorm:"column:http_code" json:"http_code"`
+	Response    string    `gorm:"column:response;type:mediumtext" json:"response"`
+	ExpireAt    time.Time `gorm:"column:expire_at" json:"expire_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (d *IdempotencyRecordPO) TableName() string {
+	return "idempotency_record"
+}
+
+// MySQLIdempotencyStore - mysql store, share keys across replicas
+type MySQLIdempotencyStore struct {
+	db  *gorm.DB
+	dao *daot.Dao[IdempotencyRecordPO]
+}
+
+var _ IdempotencyStore = (*MySQLIdempotencyStore)(nil)
+
+// NewMySQLIdempotencyStore - create mysql store
+func NewMySQLIdempotencyStore(db *gorm.DB) *MySQLIdempotencyStore {
+	return &MySQLIdempotencyStore{
+		db:  db,
+		dao: daot.NewDao[IdempotencyRecordPO](db),
+	}
+}
+
+// Begin implements IdempotencyStore, insert ignore, expired record replaced
+func (m *MySQLIdempotencyStore) Begin(rec *IdempotencyRecord) (*IdempotencyRecord, bool, error) {
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		po := &IdempotencyRecordPO{
+			Name:        rec.Key,
+			Fingerprint: rec.Fingerprint,
+			Status:      rec.Status,
+			Token:       rec.Token,
+			ExpireAt:    rec.ExpireAt,
+			UpdatedAt:   now,
+		}
+		rst := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(po)
+		if rst.Error != nil {
+			return nil, false, rst.Error
+		}
+		if rst.RowsAffected > 0 {
+			return nil, true, nil
+		}
+
+		saved, err := m.dao.Get(&IdempotencyRecordPO{Name: rec.Key})
+		if err != nil {
+			return nil, false, err
+		}
+		if saved.ID < 1 {
+			continue
+		}
+		if now.Before(saved.ExpireAt) {
+			return poToIdempotency(saved)
+		}
+		// delete only the expired claim read, lease renewed or replaced in between kept
+		err = m.db.Where(" name = ? AND token = ? AND expire_at < ? ", saved.Name, saved.Token, now).
+			Delete(&IdempotencyRecordPO{}).Error
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, false, errors.New("idempotency key claim conflict")
+}
+
+// owned - condition of processing record claimed by token
+func (m *MySQLIdempotencyStore) owned(key, token string) *gorm.DB {
+	return m.db.Model(&IdempotencyRecordPO{}).
+		Where(" name = ? AND token = ? AND status = ? ", key, token, IdempotencyProcessing)
+}
+
+// Renew implements IdempotencyStore
+func (m *MySQLIdempotencyStore) Renew(rec *IdempotencyRecord) error {
+	rst := m.owned(rec.Key, rec.Token).Updates(map[string]any{
+		"expire_at":  rec.ExpireAt,
+		"updated_at": time.Now(),
+	})
+	if rst.Error != nil {
+		return rst.Error
+	}
+	if rst.RowsAffected < 1 {
+		return ErrIdempotencyLeaseLost
+	}
+	return nil
+}
+
+// Complete implements IdempotencyStore
+func (m *MySQLIdempotencyStore) Complete(rec *IdempotencyRecord) error {
+	bts, err := json.Marshal(rec.Response)
+	if err != nil {
+		return err
+	}
+	rst := m.owned(rec.Key, rec.Token).Updates(map[string]any{
+		"status":     rec.Status,
+		"http_code":  rec.HTTPCode,
+		"response":   string(bts),
+		"expire_at":  rec.ExpireAt,
+		"updated_at": time.Now(),
+	})
+	if rst.Error != nil {
+		return rst.Error
+	}
+	if rst.RowsAffected < 1 {
+		return ErrIdempotencyLeaseLost
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (m *MySQLIdempotencyStore) Release(key, token string) error {
+	return m.db.Where(" name = ? AND token = ? AND status = ? ", key, token, IdempotencyProcessing).
+		Delete(&IdempotencyRecordPO{}).Error
+}
+
+// poToIdempotency - convert po into record
+func poToIdempotency(po *IdempotencyRecordPO) (*IdempotencyRecord, bool, error) {
+	rec := &IdempotencyRecord{
+		Key:         po.Name,
+		Fingerprint: po.Fingerprint,
+		Status:      po.Status,
+		Token:       po.Token,
+		HTTPCode:    po.HTTPCode,
+		ExpireAt:    po.ExpireAt,
+	}
+	if po.Response != "" {
+		rec.Response = &Response{}
+		if err := json.Unmarshal([]byte(po.Response), rec.Response); err != nil {
+			return nil, false, err
+		}
+	}
+	return rec, false, nil
+}
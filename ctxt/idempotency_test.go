@@ -0,0 +1,201 @@
+package ctxt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idemEngine - engine with idempotency on counting routes
+func idemEngine(calls *atomic.Int64, opts ...IdempotencyOption) *gin.Engine {
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc(), ErrorMapFunc(), IdempotencyFunc(opts...))
+	engine.POST("/order", func(c *gin.Context) {
+		n := calls.Add(1)
+		if d, err := time.ParseDuration(c.Query("sleep")); err == nil {
+			time.Sleep(d)
+		}
+		switch c.Query("fail") {
+		case "5xx":
+			GetCTX(c).SetErrorResponse(http.StatusServiceUnavailable, http.StatusServiceUnavailable, "busy")
+			return
+		case "panic":
+			panic("order broken")
+		}
+		GetCTX(c).SetData(n)
+	})
+	return engine
+}
+
+// idemRequest - order request with idempotency key
+func idemRequest(key, query, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/order"+query, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set(HeaderIdempotencyKey, key)
+	}
+	return r
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := &atomic.Int64{}
+	engine := idemEngine(calls)
+
+	w, first := doRequest(t, engine, idemRequest("k1", "", `{"a":1}`))
+	if w.Code != http.StatusOK || first.Data != float64(1) {
+		t.Fatalf("first %d %+v", w.Code, first)
+	}
+	w, replay := doRequest(t, engine, idemRequest("k1", "", `{"a":1}`))
+	if w.Code != http.StatusOK || replay.Data != float64(1) || w.Header().Get(HeaderIdempotentReplay) != "true" || calls.Load() != 1 {
+		t.Fatalf("replay %d %+v calls %d", w.Code, replay, calls.Load())
+	}
+
+	// same key different body
+	if w, _ = doRequest(t, engine, idemRequest("k1", "", `{"a":2}`)); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("changed request %d", w.Code)
+	}
+	// without key not checked, other method not checked
+	doRequest(t, engine, idemRequest("", "", `{"a":1}`))
+	if calls.Load() != 2 {
+		t.Fatalf("request without key calls %d", calls.Load())
+	}
+	if w, _ = doRequest(t, idemEngine(calls, WithIdempotencyRequired(true)), idemRequest("", "", "")); w.Code != http.StatusBadRequest {
+		t.Fatalf("required key %d", w.Code)
+	}
+	if w, _ = doRequest(t, engine, idemRequest(strings.Repeat("k", maxIdempotencyKeyLength+1), "", "")); w.Code != http.StatusBadRequest {
+		t.Fatalf("long key %d", w.Code)
+	}
+}
+
+func TestIdempotencyRelease(t *testing.T) {
+	calls := &atomic.Int64{}
+	engine := idemEngine(calls)
+	for _, fail := range []string{"5xx", "panic"} {
+		key := "k-" + fail
+		captureSystemLog(t, func() {
+			doRequest(t, engine, idemRequest(key, "?fail="+fail, ""))
+		})
+		// retry with same key run handler again
+		before := calls.Load()
+		if w, _ := doRequest(t, engine, idemRequest(key, "?fail="+fail, "")); w.Header().Get(HeaderIdempotentReplay) != "" ||
+			calls.Load() != before+1 {
+			t.Fatalf("%s not released, calls %d", fail, calls.Load())
+		}
+	}
+}
+
+func TestIdempotencyConflict(t *testing.T) {
+	calls := &atomic.Int64{}
+	engine := idemEngine(calls)
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		close(started)
+		doRequest(t, engine, idemRequest("k1", "?sleep=200ms", ""))
+	}()
+	<-started
+	time.Sleep(time.Millisecond * 50)
+	if w, _ := doRequest(t, engine, idemRequest("k1", "?sleep=200ms", "")); w.Code != http.StatusConflict {
+		t.Fatalf("in flight %d", w.Code)
+	}
+	<-done
+	if calls.Load() != 1 {
+		t.Fatalf("calls %d", calls.Load())
+	}
+}
+
+func TestIdempotencyLeaseRenew(t *testing.T) {
+	calls := &atomic.Int64{}
+	engine := idemEngine(calls, WithIdempotencyLockTTL(time.Millisecond*60))
+
+	// handler slower than lease, lease renewed, repeat rejected instead of run twice
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		doRequest(t, engine, idemRequest("k1", "?sleep=300ms", ""))
+	}()
+	time.Sleep(time.Millisecond * 200)
+	if w, _ := doRequest(t, engine, idemRequest("k1", "?sleep=300ms", "")); w.Code != http.StatusConflict {
+		t.Fatalf("repeat after lease ttl %d", w.Code)
+	}
+	wg.Wait()
+	w, rsps := doRequest(t, engine, idemRequest("k1", "?sleep=300ms", ""))
+	if w.Header().Get(HeaderIdempotentReplay) != "true" || rsps.Data != float64(1) || calls.Load() != 1 {
+		t.Fatalf("replay after slow handler %d %+v calls %d", w.Code, rsps, calls.Load())
+	}
+}
+
+func TestIdempotencyStoreOwner(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	rec := &IdempotencyRecord{Key: "k", Status: IdempotencyProcessing, Token: "t1", ExpireAt: time.Now().Add(-time.Second)}
+	if _, claimed, _ := store.Begin(rec); !claimed {
+		t.Fatal("first claim")
+	}
+	// expired claim taken by other request
+	other := &IdempotencyRecord{Key: "k", Status: IdempotencyProcessing, Token: "t2", ExpireAt: time.Now().Add(time.Minute)}
+	if _, claimed, _ := store.Begin(other); !claimed {
+		t.Fatal("expired claim not replaced")
+	}
+
+	rec.ExpireAt = time.Now().Add(time.Minute)
+	if err := store.Renew(rec); !errors.Is(err, ErrIdempotencyLeaseLost) {
+		t.Fatalf("renew lost lease, %v", err)
+	}
+	done := *rec
+	done.Status = IdempotencyDone
+	if err := store.Complete(&done); !errors.Is(err, ErrIdempotencyLeaseLost) {
+		t.Fatalf("complete lost lease, %v", err)
+	}
+	_ = store.Release("k", "t1")
+	if saved, claimed, _ := store.Begin(rec); claimed || saved.Token != "t2" {
+		t.Fatalf("claim of owner released by old token, %+v", saved)
+	}
+
+	if err := store.Renew(other); err != nil {
+		t.Fatal(err)
+	}
+	done = *other
+	done.Status = IdempotencyDone
+	if err := store.Complete(&done); err != nil {
+		t.Fatal(err)
+	}
+	// done record not released
+	_ = store.Release("k", "t2")
+	if saved, _, _ := store.Begin(rec); saved == nil || saved.Status != IdempotencyDone {
+		t.Fatalf("done record %+v", saved)
+	}
+}
+
+func TestIdempotencyErrorRegistry(t *testing.T) {
+	reg := NewErrorRegistry()
+	reg.RegisterFunc(func(err error) *AppError {
+		if err.Error() == "order conflict" {
+			return ErrConflict.WithMsg("order exists")
+		}
+		return nil
+	})
+	calls := &atomic.Int64{}
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc(), ErrorMapFunc(WithErrorRegistry(reg)), IdempotencyFunc())
+	engine.POST("/order", func(c *gin.Context) {
+		calls.Add(1)
+		_ = c.Error(errors.New("order conflict"))
+	})
+
+	// replay equal to first response mapped by configured registry
+	w1, first := doRequest(t, engine, idemRequest("k1", "", ""))
+	w2, replay := doRequest(t, engine, idemRequest("k1", "", ""))
+	if w1.Code != http.StatusConflict || w2.Code != w1.Code || replay.Msg != first.Msg || first.Msg != "order exists" ||
+		w2.Header().Get(HeaderIdempotentReplay) != "true" || calls.Load() != 1 {
+		t.Fatalf("first %d %+v, replay %d %+v", w1.Code, first, w2.Code, replay)
+	}
+}
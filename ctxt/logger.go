@@ -1,28 +1,190 @@
 // init logger
+// level adjustable at runtime, records routed by level into sinks
 
 package ctxt
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/itoolkits/toolkit/tracet"
+)
+
+const (
+	SystemLoggerName = "system"
+	AccessLoggerName = "access"
 )
 
 var AccessLogger = slog.Default()
 
-// InitSystemLogger - init system logger
+var (
+	systemLevel = new(slog.LevelVar)
+	accessLevel = new(slog.LevelVar)
+)
+
+type LogSink struct {
+	Writer io.Writer
+	Match  func(level slog.Level) bool // nil match all levels
+}
+
+// LevelAtLeast - match level >= min, e.g. error sink
+func LevelAtLeast(min slog.Level) func(level slog.Level) bool {
+	return func(level slog.Level) bool {
+		return level >= min
+	}
+}
+
+// LevelBelow - match level < max, e.g. sink without errors
+func LevelBelow(max slog.Level) func(level slog.Level) bool {
+	return func(level slog.Level) bool {
+		return level < max
+	}
+}
+
+// InitSystemLogger - init system logger, trace ids of context added
 func InitSystemLogger(w io.Writer) {
-	logHandler := slog.NewJSONHandler(w, &slog.HandlerOptions{
-		AddSource: true,
-		Level:     slog.LevelInfo,
-	})
-	logger := slog.New(logHandler)
-	slog.SetDefault(logger)
+	InitSystemLoggerSinks(LogSink{Writer: w})
 }
 
-// InitAccessLogger - init access logger
+// InitAccessLogger - init access logger, trace ids of context added
 func InitAccessLogger(w io.Writer) {
-	AccessLogger = slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+	InitAccessLoggerSinks(LogSink{Writer: w})
+}
+
+// InitSystemLoggerSinks - init system logger writing into sinks by level
+func InitSystemLoggerSinks(sinks ...LogSink) {
+	slog.SetDefault(slog.New(tracet.NewHandler(newSinkHandler(&slog.HandlerOptions{
+		AddSource: true,
+		Level:     systemLevel,
+	}, sinks))))
+}
+
+// InitAccessLoggerSinks - init access logger writing into sinks by level
+func InitAccessLoggerSinks(sinks ...LogSink) {
+	AccessLogger = slog.New(tracet.NewHandler(newSinkHandler(&slog.HandlerOptions{
 		AddSource: false,
-		Level:     slog.LevelInfo,
-	}))
+		Level:     accessLevel,
+	}, sinks)))
+}
+
+// SetLogLevel - set level of system or access logger, e.g. debug, info, warn, error
+func SetLogLevel(logger, level string) error {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	switch logger {
+	case SystemLoggerName:
+		systemLevel.Set(lvl)
+	case AccessLoggerName:
+		accessLevel.Set(lvl)
+	default:
+		return fmt.Errorf("logger %s not exist", logger)
+	}
+	return nil
+}
+
+// LogLevels - current levels of loggers
+func LogLevels() map[string]string {
+	return map[string]string{
+		SystemLoggerName: systemLevel.Level().String(),
+		AccessLoggerName: accessLevel.Level().String(),
+	}
+}
+
+// LogLevelFunc - admin handler, GET show levels, PUT/POST set levels by json {"system":"debug"}
+// mount it behind AuthFunc
+func LogLevelFunc() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := GetCTX(c)
+		if c.Request.Method == http.MethodGet {
+			reqCtx.SetData(LogLevels())
+			return
+		}
+		levels := make(map[string]string)
+		if err := c.ShouldBindJSON(&levels); err != nil {
+			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, fmt.Sprintf("param error, %v", err))
+			return
+		}
+		for logger, level := range levels {
+			if err := SetLogLevel(logger, level); err != nil {
+				reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		slog.WarnContext(c.Request.Context(), "log level changed", "levels", levels, "ak", reqCtx.AK)
+		reqCtx.SetData(LogLevels())
+	}
+}
+
+type sinkHandler struct {
+	level    slog.Leveler
+	handlers []slog.Handler
+	matches  []func(level slog.Level) bool
+}
+
+var _ slog.Handler = (*sinkHandler)(nil)
+
+// newSinkHandler - json handler per sink, single sink without routing
+func newSinkHandler(opts *slog.HandlerOptions, sinks []LogSink) slog.Handler {
+	if len(sinks) == 1 && sinks[0].Match == nil {
+		return slog.NewJSONHandler(sinks[0].Writer, opts)
+	}
+	h := &sinkHandler{level: opts.Level}
+	for _, sink := range sinks {
+		h.handlers = append(h.handlers, slog.NewJSONHandler(sink.Writer, opts))
+		h.matches = append(h.matches, sink.Match)
+	}
+	return h
+}
+
+// Enabled implements slog.Handler
+func (h *sinkHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *sinkHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for i, handler := range h.handlers {
+		if h.matches[i] != nil && !h.matches[i](r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler
+func (h *sinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := &sinkHandler{level: h.level, matches: h.matches}
+	for _, handler := range h.handlers {
+		c.handlers = append(c.handlers, handler.WithAttrs(attrs))
+	}
+	return c
+}
+
+// WithGroup implements slog.Handler
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+	c := &sinkHandler{level: h.level, matches: h.matches}
+	for _, handler := range h.handlers {
+		c.handlers = append(c.handlers, handler.WithGroup(name))
+	}
+	return c
+}
+
+// parseLevel - level name, case insensitive
+func parseLevel(s string) (slog.Level, error) {
+	var lvl slog.Level
+	err := lvl.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return lvl, err
 }
@@ -0,0 +1,92 @@
+package ctxt
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/itoolkits/toolkit/tracet"
+)
+
+// restoreLoggers - reset loggers and levels changed by test
+func restoreLoggers(t *testing.T) {
+	sys, access := slog.Default(), AccessLogger
+	sysLevel, acLevel := systemLevel.Level(), accessLevel.Level()
+	t.Cleanup(func() {
+		slog.SetDefault(sys)
+		AccessLogger = access
+		systemLevel.Set(sysLevel)
+		accessLevel.Set(acLevel)
+	})
+}
+
+func TestLoggerSinks(t *testing.T) {
+	restoreLoggers(t)
+	info, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	InitSystemLoggerSinks(LogSink{Writer: info, Match: LevelBelow(slog.LevelError)},
+		LogSink{Writer: errs, Match: LevelAtLeast(slog.LevelError)})
+
+	sc := tracet.NewRoot(true)
+	ctx := tracet.NewContext(context.Background(), sc)
+	logger := slog.Default().With("module", "m1").WithGroup("g")
+	logger.InfoContext(ctx, "info record", "k", 1)
+	logger.ErrorContext(ctx, "error record")
+	slog.Debug("debug record")
+
+	if !strings.Contains(info.String(), "info record") || strings.Contains(info.String(), "error record") ||
+		!strings.Contains(info.String(), sc.TraceID) || !strings.Contains(info.String(), `"module":"m1"`) ||
+		!strings.Contains(info.String(), `"g":{"k":1`) {
+		t.Fatalf("info sink %s", info.String())
+	}
+	if !strings.Contains(errs.String(), "error record") || strings.Contains(errs.String(), "info record") {
+		t.Fatalf("error sink %s", errs.String())
+	}
+	if strings.Contains(info.String(), "debug record") {
+		t.Fatal("debug record written at info level")
+	}
+
+	// level changed at runtime
+	if err := SetLogLevel(SystemLoggerName, "DEBUG"); err != nil {
+		t.Fatal(err)
+	}
+	slog.Debug("debug record")
+	if !strings.Contains(info.String(), "debug record") {
+		t.Fatal("debug record not written at debug level")
+	}
+	if SetLogLevel("other", "debug") == nil || SetLogLevel(AccessLoggerName, "verbose") == nil {
+		t.Fatal("bad logger or level accepted")
+	}
+}
+
+func TestLogLevelFunc(t *testing.T) {
+	restoreLoggers(t)
+	InitSystemLogger(&bytes.Buffer{})
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc())
+	engine.Any("/log/level", LogLevelFunc())
+
+	w, rsps := doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	if w.Code != http.StatusOK || rsps.Data.(map[string]any)[AccessLoggerName] != "INFO" {
+		t.Fatalf("get levels %d %+v", w.Code, rsps)
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"access":"warn"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if w, rsps = doRequest(t, engine, r); w.Code != http.StatusOK || accessLevel.Level() != slog.LevelWarn {
+		t.Fatalf("set level %d %+v", w.Code, rsps)
+	}
+
+	for _, body := range []string{`{"access":"verbose"}`, `{"other":"warn"}`, `[`} {
+		r = httptest.NewRequest(http.MethodPost, "/log/level", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if w, rsps = doRequest(t, engine, r); w.Code != http.StatusBadRequest {
+			t.Fatalf("bad levels %s %d %+v", body, w.Code, rsps)
+		}
+	}
+}
@@ -4,41 +4,61 @@ package ctxt
 
 import (
 	"crypto/md5"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"time"
 )
 
-var akSKMap = make(map[string]string)
-
-// AddAKSKCache add ak sk key
+// AddAKSKCache add ak sk key into default credential store
 func AddAKSKCache(ak, sk string) {
-	akSKMap[ak] = sk
+	defaultCredentialStore.Put(&Credential{
+		AK: ak,
+		SK: sk,
+	})
 }
 
-// MD5Check check md5
+// MD5Check check md5, use default credential store
 func MD5Check(ak, sk string, ts int64) error {
 	if ak == "" {
 		return fmt.Errorf("ak can not blank")
 	}
+	cred, err := defaultCredentialStore.Get(ak)
+	if err != nil {
+		return err
+	}
+	if cred == nil {
+		return fmt.Errorf("no authorization")
+	}
+	return md5Verify(ak, sk, cred.SK, ts, defaultSignSkew)
+}
+
+// md5Verify check md5 auth with the real sk, timestamp in skew window
+func md5Verify(ak, sk, rsk string, ts int64, skew time.Duration) error {
+	if ak == "" {
+		return fmt.Errorf("ak can not blank")
+	}
 
 	if sk == "" {
 		return fmt.Errorf("sk can not blank")
 	}
 
+	if skew <= 0 {
+		skew = defaultSignSkew
+	}
+
 	cur := time.Now()
-	endTime := cur.Add(time.Minute * 5).Unix()
-	startTime := cur.Add(-time.Minute * 5).Unix()
+	endTime := cur.Add(skew).Unix()
+	startTime := cur.Add(-skew).Unix()
 
 	if ts > endTime || ts < startTime {
 		return fmt.Errorf("timestamp out of range")
 	}
-	rsk, h := akSKMap[ak]
-	if !h {
+	if rsk == "" {
 		return fmt.Errorf("no authorization")
 	}
 	hs := hopeAuth(ak, rsk, ts)
-	if hs != sk {
+	if subtle.ConstantTimeCompare([]byte(hs), []byte(sk)) != 1 {
 		return fmt.Errorf("no authorization")
 	}
 	return nil
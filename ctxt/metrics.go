@@ -0,0 +1,185 @@
+// http metrics middleware, prometheus
+// labels: method, route template, http code, response code
+// method not standard http method labeled OTHER, label cardinality bounded
+
+package ctxt
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsSubsystem = "http"
+	unmatchedRoute   = "unmatched"
+	otherMethod      = "OTHER"
+)
+
+var standardMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodPatch:   {},
+	http.MethodDelete:  {},
+	http.MethodConnect: {},
+	http.MethodOptions: {},
+	http.MethodTrace:   {},
+}
+
+var defaultSizeBuckets = prometheus.ExponentialBuckets(64, 4, 8)
+
+type metricsConfig struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     []float64
+	sizeBuckets []float64
+	skipUnmatch bool
+}
+
+type MetricsOption func(cfg *metricsConfig)
+
+// WithMetricsNamespace - config metrics namespace, default no namespace
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(cfg *metricsConfig) {
+		cfg.namespace = namespace
+	}
+}
+
+// WithMetricsConstLabels - config const labels, e.g. service name
+func WithMetricsConstLabels(labels prometheus.Labels) MetricsOption {
+	return func(cfg *metricsConfig) {
+		cfg.constLabels = labels
+	}
+}
+
+// WithMetricsBuckets - config latency histogram buckets in seconds
+func WithMetricsBuckets(buckets []float64) MetricsOption {
+	return func(cfg *metricsConfig) {
+		cfg.buckets = buckets
+	}
+}
+
+// WithMetricsSizeBuckets - config request/response size histogram buckets in bytes
+func WithMetricsSizeBuckets(buckets []float64) MetricsOption {
+	return func(cfg *metricsConfig) {
+		cfg.sizeBuckets = buckets
+	}
+}
+
+// WithMetricsSkipUnmatched - skip requests no route matched, e.g. scanner 404
+func WithMetricsSkipUnmatched(skip bool) MetricsOption {
+	return func(cfg *metricsConfig) {
+		cfg.skipUnmatch = skip
+	}
+}
+
+type httpMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	reqSize  *prometheus.HistogramVec
+	respSize *prometheus.HistogramVec
+}
+
+// MetricsFunc - http metrics middleware, register metrics with reg, use before ResponseFunc
+func MetricsFunc(reg prometheus.Registerer, opts ...MetricsOption) (gin.HandlerFunc, error) {
+	cfg := &metricsConfig{
+		buckets:     prometheus.DefBuckets,
+		sizeBuckets: defaultSizeBuckets,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	labels := []string{"method", "route", "code", "scode"}
+	m := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   metricsSubsystem,
+			Name:        "requests_total",
+			Help:        "Number of http requests.",
+			ConstLabels: cfg.constLabels,
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   metricsSubsystem,
+			Name:        "request_duration_seconds",
+			Help:        "Latency of http requests in seconds.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     cfg.buckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   metricsSubsystem,
+			Name:        "requests_in_flight",
+			Help:        "Number of http requests in flight.",
+			ConstLabels: cfg.constLabels,
+		}),
+		reqSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   metricsSubsystem,
+			Name:        "request_size_bytes",
+			Help:        "Size of http request body in bytes.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     cfg.sizeBuckets,
+		}, []string{"method", "route"}),
+		respSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   metricsSubsystem,
+			Name:        "response_size_bytes",
+			Help:        "Size of http response body in bytes.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     cfg.sizeBuckets,
+		}, []string{"method", "route", "code"}),
+	}
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.inFlight, m.reqSize, m.respSize} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			if cfg.skipUnmatch {
+				c.Next()
+				return
+			}
+			route = unmatchedRoute
+		}
+
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		method := metricsMethod(c.Request.Method)
+		code := strconv.Itoa(c.Writer.Status())
+		scode := ""
+		if reqCtx := GetCTX(c); reqCtx.Response != nil {
+			scode = strconv.Itoa(reqCtx.Response.SCode)
+		}
+
+		m.requests.WithLabelValues(method, route, code, scode).Inc()
+		m.duration.WithLabelValues(method, route, code, scode).Observe(time.Since(start).Seconds())
+		m.reqSize.WithLabelValues(method, route).Observe(float64(max(c.Request.ContentLength, 0)))
+		m.respSize.WithLabelValues(method, route, code).Observe(float64(max(c.Writer.Size(), 0)))
+	}, nil
+}
+
+// metricsMethod - method label, OTHER for not standard method
+func metricsMethod(method string) string {
+	if _, h := standardMethods[method]; h {
+		return method
+	}
+	return otherMethod
+}
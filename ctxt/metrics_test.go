@@ -0,0 +1,117 @@
+package ctxt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestSeries - label sets of http_requests_total with counter value, e.g. GET /a/:id 200 200 -> 1
+func requestSeries(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rst := make(map[string]float64)
+	for _, mf := range mfs {
+		if mf.GetName() != "test_http_requests_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			if labels["service"] != "svc" {
+				t.Fatalf("const label missing, %v", labels)
+			}
+			k := strings.Join([]string{labels["method"], labels["route"], labels["code"], labels["scode"]}, " ")
+			rst[k] = m.GetCounter().GetValue()
+		}
+	}
+	return rst
+}
+
+func TestMetricsLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := MetricsFunc(reg, WithMetricsNamespace("test"),
+		WithMetricsConstLabels(prometheus.Labels{"service": "svc"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.Use(metrics, ResponseFunc())
+	engine.GET("/a/:id", func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		GetCTX(c).SetErrorResponse(http.StatusOK, 1001, "business error")
+	})
+	engine.Handle("PURGE", "/a/:id", func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	})
+	engine.NoRoute(func(c *gin.Context) {
+		GetCTX(c).SetErrorResponse(http.StatusNotFound, http.StatusNotFound, "not found")
+	})
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/a/1", nil),
+		httptest.NewRequest(http.MethodGet, "/a/2", nil),
+		httptest.NewRequest(http.MethodGet, "/fail", nil),
+		httptest.NewRequest(http.MethodGet, "/not/exist/1", nil),
+		httptest.NewRequest(http.MethodGet, "/not/exist/2", nil),
+		httptest.NewRequest("PURGE", "/a/1", nil),
+		httptest.NewRequest("X-RANDOM-1", "/not/exist", nil),
+	} {
+		doRequest(t, engine, r)
+	}
+
+	got := requestSeries(t, reg)
+	want := map[string]float64{
+		"GET /a/:id 200 200":      2,
+		"GET /fail 200 1001":      1,
+		"GET unmatched 404 404":   2,
+		"OTHER /a/:id 200 200":    1,
+		"OTHER unmatched 404 404": 1,
+	}
+	if len(got) != len(want) {
+		keys := make([]string, 0, len(got))
+		for k := range got {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		t.Fatalf("series %v, want %v", keys, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("series %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	// same registry twice
+	if _, err = MetricsFunc(reg, WithMetricsNamespace("test"),
+		WithMetricsConstLabels(prometheus.Labels{"service": "svc"})); err == nil {
+		t.Fatal("duplicate register should fail")
+	}
+}
+
+func TestMetricsSkipUnmatched(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := MetricsFunc(reg, WithMetricsNamespace("test"),
+		WithMetricsConstLabels(prometheus.Labels{"service": "svc"}), WithMetricsSkipUnmatched(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.Use(metrics, ResponseFunc())
+	doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/none", nil))
+	if got := requestSeries(t, reg); len(got) != 0 {
+		t.Fatalf("unmatched request recorded, %v", got)
+	}
+}
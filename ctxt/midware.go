@@ -5,48 +5,19 @@ package ctxt
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthFunc - auth func
-func AuthFunc() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		reqCtx := GetCTX(ctx)
-		ak := ctx.GetHeader("AK")
-		auth := ctx.GetHeader("Authorization")
-		tsStr := ctx.GetHeader("Timestamp")
-		if tsStr == "" {
-			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, fmt.Sprintf("timestamp can not blank"))
-			ctx.Abort()
-			return
-		}
-		ts, err := strconv.ParseInt(tsStr, 10, 64)
-		if err != nil {
-			reqCtx.SetErrorResponse(http.StatusBadRequest, http.StatusBadRequest, fmt.Sprintf("timestamp format error,  %v", err))
-			ctx.Abort()
-			return
-		}
-
-		err = MD5Check(ak, auth, ts)
-		if err != nil {
-			reqCtx.SetErrorResponse(http.StatusUnauthorized, http.StatusUnauthorized, err.Error())
-			ctx.Abort()
-			return
-		}
-		ctx.Next()
-	}
-}
-
 // ErrorFunc - error func
 func ErrorFunc() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -69,6 +40,17 @@ func ResponseFunc() gin.HandlerFunc {
 		c.Next()
 
 		reqCtx := GetCTX(c)
+
+		// handler write response itself, e.g. file, openapi document
+		if c.Writer.Written() {
+			reqCtx.HTTPCode = c.Writer.Status()
+			if reqCtx.StreamMode != "" && reqCtx.StreamCode == 0 {
+				// stream returned without status frame
+				reqCtx.StreamCode = http.StatusInternalServerError
+			}
+			return
+		}
+
 		if reqCtx.HTTPCode == 0 {
 			reqCtx.HTTPCode = http.StatusOK
 		}
@@ -93,7 +75,27 @@ func recoverPanic(c *gin.Context, err any) {
 
 	reqCtx.TraceStack = fmt.Sprintf("[Error Stack]  [%s]  [%v] \n%s\n", reqCtx.RequestID, err, gatherStackInfo())
 
-	slog.Error("find unexpect error, when handle the request", "requestID", reqCtx.RequestID, "error", err)
+	slog.ErrorContext(c.Request.Context(), "find unexpect error, when handle the request", "requestID", reqCtx.RequestID, "error", err)
+
+	// panic while streaming, header written, end stream by error status frame
+	if s := reqCtx.stream; s != nil {
+		var appErr *AppError
+		if e, ok := err.(error); !ok || !errors.As(e, &appErr) {
+			appErr = ErrInternal.WithCause(fmt.Errorf("panic, %v", err))
+		}
+		_ = s.Close(appErr)
+		reqCtx.HTTPCode = c.Writer.Status()
+		return
+	}
+
+	// panic with app error, e.g. panic(ErrForbidden)
+	if e, ok := err.(error); ok {
+		var appErr *AppError
+		if errors.As(e, &appErr) {
+			reqCtx.SetError(appErr)
+			return
+		}
+	}
 
 	msg := fmt.Sprintf("unexpect error, %v", err)
 
@@ -134,6 +136,9 @@ type AccessLogVO struct {
 	ErrorMsg      string    `json:"error_msg"`
 	ErrorTrace    string    `json:"error_trace"`
 	DurationMS    int64     `json:"duration_ms"`
+	StreamMode    string    `json:"stream_mode"`
+	StreamItems   int       `json:"stream_items"`
+	StreamCode    int       `json:"stream_code"`
 }
 
 // LogAttr - log attr
@@ -153,12 +158,23 @@ func (a *AccessLogVO) LogAttr() []any {
 	rst = append(rst, "error_msg", a.ErrorMsg)
 	rst = append(rst, "error_trace", a.ErrorTrace)
 	rst = append(rst, "duration_ms", a.DurationMS)
+	if a.StreamMode != "" {
+		rst = append(rst, "stream_mode", a.StreamMode)
+		rst = append(rst, "stream_items", a.StreamItems)
+		rst = append(rst, "stream_code", a.StreamCode)
+	}
 	return rst
 }
 
-// ACLog - access log
-func ACLog() gin.HandlerFunc {
+// ACLog - access log, redact sensitive data by options
+func ACLog(opts ...ACLogOption) gin.HandlerFunc {
+	cfg := newACLogConfig(opts...)
 	return func(c *gin.Context) {
+		if cfg.skip(c) {
+			c.Next()
+			return
+		}
+
 		// API context
 		reqCtx := GetCTX(c)
 
@@ -168,14 +184,16 @@ func ACLog() gin.HandlerFunc {
 		startTime := time.Now()
 
 		// Body bytes
-		bts, err := io.ReadAll(c.Request.Body)
-		var cbts []byte
-		if len(bts) > 0 && err == nil {
-			cbts = make([]byte, len(bts))
-			copy(cbts, bts)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(bts))
+		var body string
+		if cfg.logRequestBody && c.Request.Body != nil {
+			bts, err := io.ReadAll(c.Request.Body)
+			if len(bts) > 0 && err == nil {
+				body = cfg.requestBody(c.GetHeader("Content-Type"), bts)
+			}
+			// read error, e.g. body over BodyLimitFunc limit, kept for next reader
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(bts), &errReader{err: err}))
 		}
-		requestParams := getParams(c, cbts)
+		requestParams := cfg.params(getParams(c, body))
 		if len(requestParams) > 0 {
 			requestParamStr, err := json.Marshal(requestParams)
 			if err == nil && len(requestParamStr) > 0 {
@@ -191,11 +209,16 @@ func ACLog() gin.HandlerFunc {
 		accessLog.HTTPURL = c.Request.URL.Path
 		accessLog.StartTime = startTime
 
-		accessLog.HTTPHeader = getHeaderParam(c)
+		accessLog.HTTPHeader = cfg.headers(c.Request.Header)
 
 		// Process request
 		c.Next()
 
+		// Route opt-out by SkipACLog
+		if cfg.skip(c) {
+			return
+		}
+
 		// Collect log info
 		cost := time.Since(startTime)
 
@@ -209,35 +232,57 @@ func ACLog() gin.HandlerFunc {
 
 		accessLog.ErrorTrace = reqCtx.TraceStack
 
+		// streamed response, items not logged, http code always 200, level by status frame code
+		code := accessLog.HTTPCode
+		if reqCtx.StreamMode != "" {
+			accessLog.StreamMode = reqCtx.StreamMode
+			accessLog.StreamItems = reqCtx.StreamItems
+			accessLog.StreamCode = reqCtx.StreamCode
+			code = reqCtx.StreamCode
+		}
+
+		if !cfg.sampled(code) {
+			return
+		}
+
 		retData := rsps.Data
-		if retData != nil {
+		if cfg.logResponse && retData != nil && reqCtx.StreamMode == "" {
 			retBts, _ := json.Marshal(retData)
-			resBitsLen := len(retBts)
-			if resBitsLen > AcLogMaxLen {
-				resBitsLen = AcLogMaxLen
-			}
-			accessLog.ResponseParam = string(retBts[:resBitsLen])
+			accessLog.ResponseParam = cfg.body(retBts, cfg.maxResponseSize)
 		}
 
 		switch {
-		case accessLog.HTTPCode >= 400 && accessLog.HTTPCode <= 499:
+		case code >= 400 && code <= 499:
 			{
-				AccessLogger.Warn("request error", accessLog.LogAttr()...)
+				AccessLogger.WarnContext(c.Request.Context(), "request error", accessLog.LogAttr()...)
 			}
-		case accessLog.HTTPCode >= 500:
+		case code >= 500:
 			{
-				AccessLogger.Error("server error", accessLog.LogAttr()...)
+				AccessLogger.ErrorContext(c.Request.Context(), "server error", accessLog.LogAttr()...)
 			}
 		default:
 			{
-				AccessLogger.Info("request success", accessLog.LogAttr()...)
+				AccessLogger.InfoContext(c.Request.Context(), "request success", accessLog.LogAttr()...)
 			}
 		}
 	}
 }
 
+// errReader - reader return err, io.EOF when err nil
+type errReader struct {
+	err error
+}
+
+// Read implements io.Reader
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.err == nil {
+		return 0, io.EOF
+	}
+	return 0, r.err
+}
+
 // getParams - get params from context, support query/params/body
-func getParams(c *gin.Context, body []byte) map[string]string {
+func getParams(c *gin.Context, body string) map[string]string {
 	params := make(map[string]string)
 	// query data
 	values := c.Request.URL.Query()
@@ -258,23 +303,12 @@ func getParams(c *gin.Context, body []byte) map[string]string {
 
 	// body data
 	if len(body) > 0 {
-		params[RequestBodyParam] = string(body)
+		params[RequestBodyParam] = body
 	}
 
 	return params
 }
 
-// getHeaderParam - get header parma as json string
-func getHeaderParam(c *gin.Context) string {
-	if len(c.Request.Header) > 0 {
-		hj, err := json.Marshal(c.Request.Header)
-		if err == nil && len(hj) > 0 {
-			return string(hj)
-		}
-	}
-	return ""
-}
-
 // getClientIP - get client ip
 func getClientIP(c *gin.Context) string {
 	clientIP := c.ClientIP()
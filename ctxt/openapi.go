@@ -0,0 +1,585 @@
+// openapi 3 document of typed routes, response wrapped in Response envelope
+// request fields: uri path param, form query param, header header param, others json body
+
+package ctxt
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	openAPIVersion   = "3.0.3"
+	schemaRefPrefix  = "#/components/schemas/"
+	paramRefPrefix   = "#/components/parameters/"
+	responseSchema   = "Response"
+	fieldErrorSchema = "FieldError"
+
+	swaggerUIVersion     = "5.17.14"
+	defaultSwaggerAssets = "https://unpkg.com/swagger-ui-dist@" + swaggerUIVersion
+	swaggerUIBundle      = "swagger-ui-bundle.js"
+)
+
+//go:generate sh swaggerui/vendor.sh 5.17.14
+
+// swaggerUIFS - optional vendored swagger-ui-dist assets, not vendored by default, see swaggerui/README.md
+//
+//go:embed swaggerui
+var swaggerUIFS embed.FS
+
+var (
+	ginParamRegex   = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+	schemaNameRegex = regexp.MustCompile(`[^A-Za-z0-9_.]+`)
+	timeType        = reflect.TypeOf(time.Time{})
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type Operation struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Public      bool // no auth headers
+	Deprecated  bool
+
+	reqType  reflect.Type
+	respType reflect.Type
+}
+
+type OperationOption func(op *Operation)
+
+// WithSummary - operation summary
+func WithSummary(summary string) OperationOption {
+	return func(op *Operation) {
+		op.Summary = summary
+	}
+}
+
+// WithDescription - operation description
+func WithDescription(desc string) OperationOption {
+	return func(op *Operation) {
+		op.Description = desc
+	}
+}
+
+// WithTags - operation tags
+func WithTags(tags ...string) OperationOption {
+	return func(op *Operation) {
+		op.Tags = tags
+	}
+}
+
+// WithOperationID - unique operation id
+func WithOperationID(id string) OperationOption {
+	return func(op *Operation) {
+		op.OperationID = id
+	}
+}
+
+// WithPublic - route not protected by AuthFunc, no auth headers
+func WithPublic() OperationOption {
+	return func(op *Operation) {
+		op.Public = true
+	}
+}
+
+// WithDeprecated - mark operation deprecated
+func WithDeprecated() OperationOption {
+	return func(op *Operation) {
+		op.Deprecated = true
+	}
+}
+
+type OpenAPI struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	AuthMode    string // default AuthModeMD5 as AuthFunc, AuthModeHMAC/AuthModeAny with Nonce header
+
+	mu  sync.RWMutex
+	ops []*Operation
+}
+
+// NewOpenAPI - create document
+func NewOpenAPI(title, version string) *OpenAPI {
+	return &OpenAPI{
+		Title:    title,
+		Version:  version,
+		AuthMode: AuthModeMD5,
+	}
+}
+
+// Route - register typed handler into router and document
+func Route[Req, Resp any](doc *OpenAPI, r gin.IRoutes, method, path string, fn HandlerFunc[Req, Resp],
+	opts ...OperationOption) {
+	fullPath := path
+	if g, ok := r.(interface{ BasePath() string }); ok {
+		fullPath = joinPath(g.BasePath(), path)
+	}
+	op := &Operation{
+		Method:   strings.ToUpper(method),
+		Path:     fullPath,
+		reqType:  reflect.TypeOf((*Req)(nil)).Elem(),
+		respType: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	r.Handle(op.Method, path, Handle(fn))
+	doc.Add(op)
+}
+
+// Add - add operation
+func (d *OpenAPI) Add(op *Operation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ops = append(d.ops, op)
+}
+
+// Serve - serve document json at path, swagger ui page at uiPath when not blank
+// swagger ui assets loaded from assets url when set, otherwise from pinned unpkg swagger-ui-dist
+// assets vendored by go generate are embedded and served at uiPath/assets instead of unpkg
+func (d *OpenAPI) Serve(r gin.IRoutes, path, uiPath string, assets ...string) {
+	r.GET(path, func(c *gin.Context) {
+		c.JSON(http.StatusOK, d.Document())
+	})
+	if uiPath == "" {
+		return
+	}
+	docURL, pageURL := path, uiPath
+	if g, ok := r.(interface{ BasePath() string }); ok {
+		docURL = joinPath(g.BasePath(), path)
+		pageURL = joinPath(g.BasePath(), uiPath)
+	}
+	assetURL := defaultSwaggerAssets
+	switch {
+	case len(assets) > 0 && assets[0] != "":
+		assetURL = strings.TrimSuffix(assets[0], "/")
+	case swaggerUIVendored():
+		assetURL = joinPath(pageURL, "assets")
+		sub, _ := fs.Sub(swaggerUIFS, "swaggerui")
+		r.GET(joinPath(uiPath, "assets/*filepath"), func(c *gin.Context) {
+			c.Header("Cache-Control", "public, max-age=86400")
+			c.FileFromFS(c.Param("filepath"), http.FS(sub))
+		})
+	}
+	r.GET(uiPath, func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = swaggerUITmpl.Execute(c.Writer, map[string]string{
+			"Title":  d.Title,
+			"Assets": assetURL,
+			"DocURL": docURL,
+		})
+	})
+}
+
+// swaggerUIVendored - swagger ui assets vendored by go generate
+func swaggerUIVendored() bool {
+	_, err := fs.Stat(swaggerUIFS, "swaggerui/"+swaggerUIBundle)
+	return err == nil
+}
+
+// MarshalJSON - document json
+func (d *OpenAPI) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Document())
+}
+
+// Document - build openapi document
+func (d *OpenAPI) Document() map[string]any {
+	d.mu.RLock()
+	ops := make([]*Operation, len(d.ops))
+	copy(ops, d.ops)
+	d.mu.RUnlock()
+
+	sb := newSchemaBuilder()
+	sb.schemas[responseSchema] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":       {Type: "integer", Description: "business code, 200 success"},
+			"msg":        {Type: "string"},
+			"data":       {Description: "payload"},
+			"page":       sb.schema(reflect.TypeOf(PageMeta{}), true),
+			"request_id": {Type: "string"},
+			"cost":       {Type: "integer", Format: "int64", Description: "cost in milliseconds"},
+		},
+		Required: []string{"code", "msg", "request_id", "cost"},
+	}
+	sb.schemas[fieldErrorSchema] = sb.schema(reflect.TypeOf(FieldError{}), false)
+
+	paths := make(map[string]map[string]any)
+	for _, op := range ops {
+		path := ginParamRegex.ReplaceAllString(op.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(op.Method)] = d.operation(sb, op)
+	}
+
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       d.Title,
+			"version":     d.Version,
+			"description": d.Description,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas":    sb.schemas,
+			"parameters": d.authParameters(),
+		},
+	}
+	if len(d.Servers) > 0 {
+		servers := make([]map[string]string, 0, len(d.Servers))
+		for _, s := range d.Servers {
+			servers = append(servers, map[string]string{"url": s})
+		}
+		doc["servers"] = servers
+	}
+	return doc
+}
+
+// authParameters - headers required by AuthFunc
+func (d *OpenAPI) authParameters() map[string]*Parameter {
+	params := map[string]*Parameter{
+		HeaderAK: {Name: HeaderAK, In: "header", Required: true, Schema: &Schema{Type: "string"},
+			Description: "access key"},
+		HeaderTimestamp: {Name: HeaderTimestamp, In: "header", Required: true,
+			Schema: &Schema{Type: "integer", Format: "int64"}, Description: "unix timestamp in seconds"},
+		HeaderAuthorization: {Name: HeaderAuthorization, In: "header", Required: true, Schema: &Schema{Type: "string"},
+			Description: HMACScheme + " hex signature of canonical request"},
+	}
+	if d.AuthMode == AuthModeMD5 {
+		params[HeaderAuthorization].Description = "md5 signature of ak, sk and timestamp"
+	} else {
+		params[HeaderNonce] = &Parameter{Name: HeaderNonce, In: "header", Required: true, Schema: &Schema{Type: "string"},
+			Description: "unique nonce in timestamp window"}
+	}
+	return params
+}
+
+// operation - build operation object
+func (d *OpenAPI) operation(sb *schemaBuilder, op *Operation) map[string]any {
+	rst := map[string]any{}
+	if op.OperationID != "" {
+		rst["operationId"] = op.OperationID
+	}
+	if op.Summary != "" {
+		rst["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		rst["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		rst["tags"] = op.Tags
+	}
+	if op.Deprecated {
+		rst["deprecated"] = true
+	}
+
+	params := make([]*Parameter, 0)
+	if !op.Public {
+		names := []string{HeaderAK, HeaderTimestamp, HeaderAuthorization}
+		if d.AuthMode != AuthModeMD5 {
+			names = append(names, HeaderNonce)
+		}
+		for _, name := range names {
+			params = append(params, &Parameter{Ref: paramRefPrefix + name})
+		}
+	}
+	hasBody := op.Method != http.MethodGet && op.Method != http.MethodDelete && op.Method != http.MethodHead
+	reqParams, body := sb.request(op.reqType, hasBody)
+	params = append(params, reqParams...)
+	if len(params) > 0 {
+		rst["parameters"] = params
+	}
+	if body != nil {
+		rst["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": body}},
+		}
+	}
+
+	responses := map[string]any{
+		"200": envelope("success", sb.schema(op.respType, true)),
+		"400": envelope("bad request, field errors as data",
+			&Schema{Type: "array", Items: &Schema{Ref: schemaRefPrefix + fieldErrorSchema}}),
+		"404": envelope("not found", nil),
+		"429": envelope("too many requests", nil),
+		"500": envelope("internal error", nil),
+	}
+	if !op.Public {
+		responses["401"] = envelope("no authorization", nil)
+		responses["403"] = envelope("route not allowed", nil)
+	}
+	rst["responses"] = responses
+	return rst
+}
+
+// envelope - response wrapped in Response, data schema nil without data
+func envelope(desc string, data *Schema) map[string]any {
+	schema := &Schema{Ref: schemaRefPrefix + responseSchema}
+	if data != nil {
+		schema = &Schema{AllOf: []*Schema{
+			schema,
+			{Type: "object", Properties: map[string]*Schema{"data": data}},
+		}}
+	}
+	return map[string]any{
+		"description": desc,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+type schemaBuilder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// newSchemaBuilder - create schema builder
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// request - parameters and json body schema of request type
+func (sb *schemaBuilder) request(t reflect.Type, hasBody bool) ([]*Parameter, *Schema) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	params := make([]*Parameter, 0)
+	body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range structFields(t) {
+		required := hasRule(f, "required")
+		schema := sb.schema(f.Type, true)
+		addRuleEnum(schema, f)
+		uri, header, form, jsonName := tagValue(f, "uri"), tagValue(f, "header"), tagValue(f, "form"), tagValue(f, "json")
+		switch {
+		case uri != "":
+			params = append(params, &Parameter{Name: uri, In: "path", Required: true, Schema: schema})
+		case header != "":
+			params = append(params, &Parameter{Name: header, In: "header", Required: required, Schema: schema})
+		case form != "" && (jsonName == "" || !hasBody):
+			params = append(params, &Parameter{Name: form, In: "query", Required: required, Schema: schema})
+		case hasBody && f.Tag.Get("json") != "-":
+			name := jsonName
+			if name == "" {
+				name = f.Name
+			}
+			body.Properties[name] = schema
+			if required {
+				body.Required = append(body.Required, name)
+			}
+		}
+	}
+	if len(body.Properties) < 1 {
+		return params, nil
+	}
+	sort.Strings(body.Required)
+	return params, body
+}
+
+// schema - schema of type, named struct as component ref
+func (sb *schemaBuilder) schema(t reflect.Type, ref bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int32, t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case t.Kind() == reflect.Int64, t.Kind() == reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case t.Kind() == reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case t.Kind() == reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: sb.schema(t.Elem(), true)}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sb.schema(t.Elem(), true)}
+	case t.Kind() == reflect.Struct:
+		if !ref || t.Name() == "" {
+			return sb.structSchema(t)
+		}
+		return &Schema{Ref: schemaRefPrefix + sb.component(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component - register named struct schema, return component name
+func (sb *schemaBuilder) component(t reflect.Type) string {
+	if name, h := sb.names[t]; h {
+		return name
+	}
+	name := schemaNameRegex.ReplaceAllString(t.Name(), "_")
+	if _, h := sb.schemas[name]; h {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+	for i := 2; ; i++ {
+		if _, h := sb.schemas[name]; !h {
+			break
+		}
+		name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+	}
+	sb.names[t] = name
+	// placeholder for recursive type
+	sb.schemas[name] = &Schema{}
+	*sb.schemas[name] = *sb.structSchema(t)
+	return name
+}
+
+// structSchema - inline object schema by json tags
+func (sb *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range structFields(t) {
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := tagValue(f, "json")
+		if name == "" {
+			name = f.Name
+		}
+		fs := sb.schema(f.Type, true)
+		addRuleEnum(fs, f)
+		s.Properties[name] = fs
+		if hasRule(f, "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// structFields - exported fields, embedded struct flattened
+func structFields(t reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && tagValue(f, "json") == "" {
+			fields = append(fields, structFields(ft)...)
+			continue
+		}
+		if f.IsExported() {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// tagValue - name part of tag
+func tagValue(f reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// hasRule - binding tag has rule
+func hasRule(f reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(f.Tag.Get("binding"), ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// addRuleEnum - oneof rule as enum
+func addRuleEnum(s *Schema, f reflect.StructField) {
+	for _, r := range strings.Split(f.Tag.Get("binding"), ",") {
+		if vs, h := strings.CutPrefix(r, "oneof="); h {
+			for _, v := range strings.Fields(vs) {
+				if s.Type == "integer" {
+					if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+					}
+					continue
+				}
+				s.Enum = append(s.Enum, v)
+			}
+		}
+	}
+}
+
+// joinPath - join base path and relative path
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+var swaggerUITmpl = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: "{{.DocURL}}", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`))
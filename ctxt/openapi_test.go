@@ -0,0 +1,169 @@
+package ctxt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type openAPIReq struct {
+	ID     int64  `uri:"id" binding:"required"`
+	Page   int    `form:"page"`
+	Tenant string `header:"X-Tenant" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+	Role   string `json:"role" binding:"omitempty,oneof=admin user"`
+	Secret string `json:"-"`
+}
+
+type openAPIUser struct {
+	ID      int64          `json:"id"`
+	Name    string         `json:"name"`
+	Friends []*openAPIUser `json:"friends"`
+}
+
+// openAPIDoc - decoded document json
+func openAPIDoc(t *testing.T, doc *OpenAPI) map[string]any {
+	t.Helper()
+	bts, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rst := make(map[string]any)
+	if err = json.Unmarshal(bts, &rst); err != nil {
+		t.Fatal(err)
+	}
+	return rst
+}
+
+// jsonPath - value at keys of decoded json
+func jsonPath(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := NewOpenAPI("users", "v1")
+	engine := gin.New()
+	g := engine.Group("/api")
+	Route(doc, g, http.MethodPost, "/user/:id", func(ctx context.Context, req *openAPIReq) (*openAPIUser, error) {
+		return &openAPIUser{ID: req.ID}, nil
+	}, WithSummary("update user"), WithTags("user"), WithOperationID("updateUser"))
+	Route(doc, g, http.MethodGet, "/health", func(ctx context.Context, req *struct{}) (*struct{}, error) {
+		return nil, nil
+	}, WithPublic(), WithDeprecated())
+
+	rst := openAPIDoc(t, doc)
+	if rst["openapi"] != openAPIVersion || jsonPath(rst, "info", "title") != "users" {
+		t.Fatalf("document header %v", rst)
+	}
+	op, _ := jsonPath(rst, "paths", "/api/user/{id}", "post").(map[string]any)
+	if op == nil || op["operationId"] != "updateUser" || op["summary"] != "update user" {
+		t.Fatalf("operation %v", jsonPath(rst, "paths"))
+	}
+
+	params := make(map[string]any)
+	for _, p := range op["parameters"].([]any) {
+		pm := p.(map[string]any)
+		if ref, ok := pm["$ref"].(string); ok {
+			params[ref] = pm
+			continue
+		}
+		params[pm["in"].(string)+":"+pm["name"].(string)] = pm
+	}
+	for _, name := range []string{paramRefPrefix + HeaderAK, paramRefPrefix + HeaderAuthorization, "path:id", "query:page", "header:X-Tenant"} {
+		if params[name] == nil {
+			t.Fatalf("parameter %s missing, %v", name, params)
+		}
+	}
+	if params[paramRefPrefix+HeaderNonce] != nil {
+		t.Fatal("md5 auth mode documented nonce header")
+	}
+
+	body := jsonPath(op, "requestBody", "content", "application/json", "schema")
+	props, _ := jsonPath(body, "properties").(map[string]any)
+	if len(props) != 2 || jsonPath(props, "role", "enum") == nil {
+		t.Fatalf("request body %v", body)
+	}
+	if req, _ := jsonPath(body, "required").([]any); len(req) != 1 || req[0] != "name" {
+		t.Fatalf("request body required %v", body)
+	}
+
+	// recursive response type as component
+	user := jsonPath(rst, "components", "schemas", "openAPIUser")
+	if jsonPath(user, "properties", "friends", "items", "$ref") != schemaRefPrefix+"openAPIUser" {
+		t.Fatalf("recursive component %v", user)
+	}
+	if jsonPath(op, "responses", "401") == nil || jsonPath(op, "responses", "400") == nil {
+		t.Fatalf("responses %v", op["responses"])
+	}
+
+	public, _ := jsonPath(rst, "paths", "/api/health", "get").(map[string]any)
+	if public == nil || public["deprecated"] != true || public["parameters"] != nil || public["requestBody"] != nil ||
+		jsonPath(public, "responses", "401") != nil {
+		t.Fatalf("public operation %v", public)
+	}
+
+	doc.AuthMode = AuthModeHMAC
+	rst = openAPIDoc(t, doc)
+	if jsonPath(rst, "components", "parameters", HeaderNonce) == nil {
+		t.Fatal("hmac auth mode without nonce header")
+	}
+
+	// routes registered into router
+	routes := make(map[string]bool)
+	for _, ri := range engine.Routes() {
+		routes[ri.Method+" "+ri.Path] = true
+	}
+	if !routes["POST /api/user/:id"] || !routes["GET /api/health"] {
+		t.Fatalf("typed routes not registered, %v", routes)
+	}
+}
+
+func TestOpenAPIServe(t *testing.T) {
+	doc := NewOpenAPI("users", "v1")
+	engine := gin.New()
+	g := engine.Group("/api")
+	doc.Serve(g, "/openapi.json", "/docs")
+	doc.Serve(engine.Group("/cdn"), "/openapi.json", "/docs", "https://assets.example.com/swagger/")
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"openapi":"3.0.3"`) {
+		t.Fatalf("document %d %s", w.Code, w.Body.String())
+	}
+
+	// assets url set
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cdn/docs", nil))
+	if !strings.Contains(w.Body.String(), "https://assets.example.com/swagger/swagger-ui.css") {
+		t.Fatalf("assets url page %s", w.Body.String())
+	}
+
+	// not vendored, pinned unpkg version
+	if swaggerUIVendored() {
+		t.Skip("swagger ui assets vendored")
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	page := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(page, defaultSwaggerAssets+"/"+swaggerUIBundle) ||
+		!strings.Contains(page, `url: "\/api\/openapi.json"`) {
+		t.Fatalf("ui page %d %s", w.Code, page)
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/assets/"+swaggerUIBundle, nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("assets served without vendoring, %d", w.Code)
+	}
+}
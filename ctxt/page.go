@@ -0,0 +1,81 @@
+// pagination, offset and cursor page metadata of Response
+
+package ctxt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 1000
+)
+
+type PageMeta struct {
+	Total      int64  `json:"total,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// OffsetQuery - offset pagination query params, embed into request struct
+type OffsetQuery struct {
+	Offset int `form:"offset" json:"offset" binding:"omitempty,min=0"`
+	Limit  int `form:"limit" json:"limit" binding:"omitempty,min=1"`
+}
+
+// CursorQuery - cursor pagination query params, embed into request struct
+type CursorQuery struct {
+	Cursor string `form:"cursor" json:"cursor"`
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,min=1"`
+}
+
+// PageLimit - limit in [1, MaxPageLimit], default DefaultPageLimit
+func PageLimit(limit int) int {
+	if limit < 1 {
+		return DefaultPageLimit
+	}
+	return min(limit, MaxPageLimit)
+}
+
+// NewOffsetPage - offset page meta, n is item num of current page
+func NewOffsetPage(q OffsetQuery, total int64, n int) *PageMeta {
+	return &PageMeta{
+		Total:   total,
+		Offset:  q.Offset,
+		Limit:   PageLimit(q.Limit),
+		HasMore: int64(q.Offset+n) < total,
+	}
+}
+
+// NewCursorPage - cursor page meta, blank next cursor means no more
+func NewCursorPage(q CursorQuery, nextCursor string) *PageMeta {
+	return &PageMeta{
+		Limit:      PageLimit(q.Limit),
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+	}
+}
+
+// EncodeCursor - opaque cursor of value, base64 url of json
+func EncodeCursor(v any) (string, error) {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bts), nil
+}
+
+// DecodeCursor - decode cursor into value ptr
+func DecodeCursor(cursor string, v any) error {
+	bts, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrBadRequest.WithMsg("cursor format error").WithCause(err)
+	}
+	if err = json.Unmarshal(bts, v); err != nil {
+		return ErrBadRequest.WithMsg("cursor format error").WithCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package ctxt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageMeta(t *testing.T) {
+	for _, cs := range []struct{ limit, want int }{{0, DefaultPageLimit}, {-1, DefaultPageLimit}, {5, 5}, {MaxPageLimit + 1, MaxPageLimit}} {
+		if got := PageLimit(cs.limit); got != cs.want {
+			t.Fatalf("page limit %d got %d", cs.limit, got)
+		}
+	}
+
+	page := NewOffsetPage(OffsetQuery{Offset: 20, Limit: 10}, 35, 10)
+	if page.Total != 35 || page.Limit != 10 || !page.HasMore {
+		t.Fatalf("offset page %+v", page)
+	}
+	if page = NewOffsetPage(OffsetQuery{Offset: 30, Limit: 10}, 35, 5); page.HasMore {
+		t.Fatalf("last offset page %+v", page)
+	}
+	if page = NewCursorPage(CursorQuery{}, ""); page.HasMore || page.Limit != DefaultPageLimit {
+		t.Fatalf("last cursor page %+v", page)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	type pos struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	cursor, err := EncodeCursor(pos{ID: 7, Name: "a/b+c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := pos{}
+	if err = DecodeCursor(cursor, &got); err != nil || got.ID != 7 || got.Name != "a/b+c" {
+		t.Fatalf("decode cursor %+v %v", got, err)
+	}
+	for _, bad := range []string{"!!", "bm90IGpzb24"} {
+		if err = DecodeCursor(bad, &got); !errors.Is(err, ErrBadRequest) {
+			t.Fatalf("bad cursor %q, %v", bad, err)
+		}
+	}
+}
+
+func TestSetPage(t *testing.T) {
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc())
+	engine.GET("/users", func(c *gin.Context) {
+		q := OffsetQuery{}
+		if err := c.ShouldBindQuery(&q); err != nil {
+			GetCTX(c).SetError(ErrBadRequest.WithCause(err))
+			return
+		}
+		GetCTX(c).SetPage([]string{"a", "b"}, NewOffsetPage(q, 3, 2))
+	})
+	w, rsps := doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/users?limit=2", nil))
+	if w.Code != http.StatusOK || rsps.Page == nil || rsps.Page.Total != 3 || !rsps.Page.HasMore || len(rsps.Data.([]any)) != 2 {
+		t.Fatalf("page response %d %+v", w.Code, rsps)
+	}
+}
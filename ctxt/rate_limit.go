@@ -0,0 +1,280 @@
+// rate limit middleware, token bucket by ak, client ip or route
+// in memory store for single instance, mysql store share limits across replicas
+
+package ctxt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RateLimitByAK    = "ak"
+	RateLimitByIP    = "ip"
+	RateLimitByRoute = "route"
+
+	rateLimitSweepInterval = time.Minute
+)
+
+type RateLimit struct {
+	Rate  float64 // tokens per second, <= 0 unlimited
+	Burst int     // bucket size, at least 1
+}
+
+type RateLimitStore interface {
+	// Take take one token from bucket, return allowed and wait duration for next token when not allowed
+	Take(key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+type rateLimitConfig struct {
+	by      string
+	def     RateLimit
+	keys    map[string]RateLimit
+	routes  map[string]RateLimit
+	store   RateLimitStore
+	onError bool // allow request when store error
+}
+
+type RateLimitOption func(cfg *rateLimitConfig)
+
+// WithKeyLimit - config limit of one ak or client ip
+func WithKeyLimit(key string, limit RateLimit) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.keys[key] = limit
+	}
+}
+
+// WithRouteLimit - config limit of route template, e.g. /api/v1/user/:id
+func WithRouteLimit(route string, limit RateLimit) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.routes[route] = limit
+	}
+}
+
+// WithRateLimitStore - config bucket store, default in memory
+func WithRateLimitStore(store RateLimitStore) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.store = store
+	}
+}
+
+// WithRateLimitFailOpen - allow request when store error, default reject with 500
+func WithRateLimitFailOpen(open bool) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.onError = open
+	}
+}
+
+// RateLimitFunc - rate limit middleware, by ak/ip/route, use after AuthFunc when by ak
+// by ak limit request without authenticated ak by client ip
+// limit priority: key limit, route limit, default limit
+func RateLimitFunc(by string, def RateLimit, opts ...RateLimitOption) gin.HandlerFunc {
+	cfg := &rateLimitConfig{
+		by:     by,
+		def:    def,
+		keys:   make(map[string]RateLimit),
+		routes: make(map[string]RateLimit),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryRateLimitStore()
+	}
+
+	return func(c *gin.Context) {
+		reqCtx := GetCTX(c)
+		route := c.FullPath()
+
+		// authenticated ak only, header ak not verified, limit by client ip without it
+		by := cfg.by
+		if by == RateLimitByAK && reqCtx.AK == "" {
+			by = RateLimitByIP
+		}
+		var key string
+		switch by {
+		case RateLimitByAK:
+			key = reqCtx.AK
+		case RateLimitByIP:
+			key = getClientIP(c)
+		default:
+			key = route
+		}
+
+		limit, h := cfg.keys[key]
+		bucket := by + ":" + key
+		if !h {
+			limit, h = cfg.routes[route]
+			if h && by != RateLimitByRoute {
+				bucket = bucket + ":" + route
+			}
+		}
+		if !h {
+			limit = cfg.def
+		}
+		if limit.Rate <= 0 {
+			c.Next()
+			return
+		}
+
+		ok, wait, err := cfg.store.Take(bucket, limit)
+		if err != nil && !cfg.onError {
+			reqCtx.SetErrorResponse(http.StatusInternalServerError, http.StatusInternalServerError,
+				fmt.Sprintf("rate limit error, %v", err))
+			c.Abort()
+			return
+		}
+		if err == nil && !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			reqCtx.SetErrorResponse(http.StatusTooManyRequests, http.StatusTooManyRequests, "too many requests")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// takeToken - refill bucket and take one token, return left tokens, allowed and wait duration
+func takeToken(tokens float64, last, now time.Time, limit RateLimit) (float64, bool, time.Duration) {
+	burst := float64(max(limit.Burst, 1))
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed*limit.Rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	wait := time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	return tokens, false, wait
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // refilled to burst at, zero never
+}
+
+// MemoryRateLimitStore - in memory token buckets
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+
+	lastSweep time.Time
+}
+
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+// NewMemoryRateLimitStore - create memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take implements RateLimitStore
+func (m *MemoryRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	// remove refilled buckets, same as new bucket
+	if now.Sub(m.lastSweep) > rateLimitSweepInterval {
+		for k, b := range m.buckets {
+			if !b.full.IsZero() && !now.Before(b.full) {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, h := m.buckets[key]
+	if !h {
+		b = &tokenBucket{
+			tokens: float64(max(limit.Burst, 1)),
+			last:   now,
+		}
+		m.buckets[key] = b
+	}
+	tokens, ok, wait := takeToken(b.tokens, b.last, now, limit)
+	b.tokens = tokens
+	b.last = now
+	b.full = time.Time{}
+	if limit.Rate > 0 {
+		refill := (float64(max(limit.Burst, 1)) - tokens) / limit.Rate
+		b.full = now.Add(time.Duration(refill * float64(time.Second)))
+	}
+	return ok, wait, nil
+}
+
+type RateLimitBucket struct {
+	ID        int64     `gorm:"column:id;primary_key" json:"-"`
+	Name      string    `gorm:"column:name;unique" json:"name"`
+	Tokens    float64   `gorm:"column:tokens" json:"tokens"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:datetime(6)" json:"updated_at"`
+}
+
+func (d *RateLimitBucket) TableName() string {
+	return "rate_limit_bucket"
+}
+
+// MySQLRateLimitStore - mysql token buckets, row lock in transaction
+type MySQLRateLimitStore struct {
+	db *gorm.DB
+}
+
+var _ RateLimitStore = (*MySQLRateLimitStore)(nil)
+
+// NewMySQLRateLimitStore - create mysql store
+func NewMySQLRateLimitStore(db *gorm.DB) *MySQLRateLimitStore {
+	return &MySQLRateLimitStore{db: db}
+}
+
+// Take implements RateLimitStore
+// first request of bucket insert it, concurrent inserts lost the race take token from inserted row
+func (m *MySQLRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration, error) {
+	var ok bool
+	var wait time.Duration
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		b := &RateLimitBucket{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(" name = ? ", key).First(b).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			b = &RateLimitBucket{
+				Name:      key,
+				Tokens:    float64(max(limit.Burst, 1)) - 1,
+				UpdatedAt: now,
+			}
+			rst := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(b)
+			if rst.Error != nil {
+				return rst.Error
+			}
+			if rst.RowsAffected > 0 {
+				ok = true
+				return nil
+			}
+			b = &RateLimitBucket{}
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(" name = ? ", key).First(b).Error
+		}
+		if err != nil {
+			return err
+		}
+		b.Tokens, ok, wait = takeToken(b.Tokens, b.UpdatedAt, now, limit)
+		return tx.Model(b).Updates(map[string]any{
+			"tokens":     b.Tokens,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return ok, wait, nil
+}
@@ -0,0 +1,147 @@
+package ctxt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitEngine - engine with fake auth by X-Test-AK header and rate limited routes
+func rateLimitEngine(by string, def RateLimit, opts ...RateLimitOption) *gin.Engine {
+	engine := gin.New()
+	engine.Use(ResponseFunc(), func(c *gin.Context) {
+		GetCTX(c).AK = c.GetHeader("X-Test-AK")
+		c.Next()
+	}, RateLimitFunc(by, def, opts...))
+	ok := func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	}
+	engine.GET("/a", ok)
+	engine.GET("/b/:id", ok)
+	return engine
+}
+
+// rateLimitRequest - request from ip with authenticated ak and header ak
+func rateLimitRequest(path, ip, ak, headerAK string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = ip + ":1234"
+	if ak != "" {
+		r.Header.Set("X-Test-AK", ak)
+	}
+	if headerAK != "" {
+		r.Header.Set(HeaderAK, headerAK)
+	}
+	return r
+}
+
+// allowed - number of allowed requests in n tries
+func allowed(t *testing.T, engine *gin.Engine, n int, req func() *http.Request) int {
+	t.Helper()
+	rst := 0
+	for i := 0; i < n; i++ {
+		w, _ := doRequest(t, engine, req())
+		switch w.Code {
+		case http.StatusOK:
+			rst++
+		case http.StatusTooManyRequests:
+			if w.Header().Get("Retry-After") == "" {
+				t.Fatal("429 without Retry-After")
+			}
+		default:
+			t.Fatalf("unexpected code %d", w.Code)
+		}
+	}
+	return rst
+}
+
+func TestRateLimitByAK(t *testing.T) {
+	engine := rateLimitEngine(RateLimitByAK, RateLimit{Rate: 0.001, Burst: 2},
+		WithKeyLimit("vip", RateLimit{Rate: 0.001, Burst: 5}))
+
+	if n := allowed(t, engine, 4, func() *http.Request { return rateLimitRequest("/a", "10.0.0.1", "ak1", "") }); n != 2 {
+		t.Fatalf("ak1 allowed %d, want 2", n)
+	}
+	if n := allowed(t, engine, 6, func() *http.Request { return rateLimitRequest("/a", "10.0.0.1", "vip", "") }); n != 5 {
+		t.Fatalf("vip allowed %d, want 5", n)
+	}
+
+	// spoofed header ak neither drain ak1 bucket nor dodge own ip limit
+	if n := allowed(t, engine, 4, func() *http.Request { return rateLimitRequest("/a", "10.0.0.2", "", "ak2") }); n != 2 {
+		t.Fatalf("unauthenticated allowed %d, want 2", n)
+	}
+	rotated := 0
+	for _, ak := range []string{"x1", "x2", "x3"} {
+		rotated += allowed(t, engine, 1, func() *http.Request { return rateLimitRequest("/a", "10.0.0.2", "", ak) })
+	}
+	if rotated != 0 {
+		t.Fatalf("rotating header ak allowed %d", rotated)
+	}
+	if n := allowed(t, engine, 1, func() *http.Request { return rateLimitRequest("/a", "10.0.0.3", "ak2", "") }); n != 1 {
+		t.Fatal("ak2 drained by spoofed header")
+	}
+}
+
+func TestRateLimitByRoute(t *testing.T) {
+	engine := rateLimitEngine(RateLimitByIP, RateLimit{},
+		WithRouteLimit("/b/:id", RateLimit{Rate: 0.001, Burst: 1}))
+
+	if n := allowed(t, engine, 3, func() *http.Request { return rateLimitRequest("/a", "10.0.0.1", "", "") }); n != 3 {
+		t.Fatalf("unlimited route allowed %d", n)
+	}
+	// route template share bucket of ip
+	if n := allowed(t, engine, 1, func() *http.Request { return rateLimitRequest("/b/1", "10.0.0.1", "", "") }); n != 1 {
+		t.Fatal("first request of route rejected")
+	}
+	if n := allowed(t, engine, 1, func() *http.Request { return rateLimitRequest("/b/2", "10.0.0.1", "", "") }); n != 0 {
+		t.Fatal("route limit not applied by template")
+	}
+	if n := allowed(t, engine, 1, func() *http.Request { return rateLimitRequest("/b/2", "10.0.0.2", "", "") }); n != 1 {
+		t.Fatal("route limit shared across ips")
+	}
+}
+
+func TestTakeToken(t *testing.T) {
+	limit := RateLimit{Rate: 10, Burst: 3}
+	now := time.Now()
+	tokens, ok, _ := takeToken(3, now, now, limit)
+	if !ok || tokens != 2 {
+		t.Fatalf("take from full bucket %v %v", tokens, ok)
+	}
+	tokens, ok, wait := takeToken(0.5, now, now, limit)
+	if ok || tokens != 0.5 || wait != time.Millisecond*50 {
+		t.Fatalf("take from empty bucket %v %v %v", tokens, ok, wait)
+	}
+	// refill capped by burst
+	tokens, ok, _ = takeToken(0, now.Add(-time.Hour), now, limit)
+	if !ok || tokens != 2 {
+		t.Fatalf("take after refill %v %v", tokens, ok)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Rate: 1000, Burst: 1}
+	if ok, _, _ := store.Take("k", limit); !ok {
+		t.Fatal("first take rejected")
+	}
+	if ok, wait, _ := store.Take("k", limit); ok || wait <= 0 {
+		t.Fatal("second take allowed")
+	}
+	time.Sleep(time.Millisecond * 5)
+	if ok, _, _ := store.Take("k", limit); !ok {
+		t.Fatal("take after refill rejected")
+	}
+
+	// refilled bucket swept
+	store.lastSweep = time.Now().Add(-rateLimitSweepInterval * 2)
+	time.Sleep(time.Millisecond * 5)
+	if ok, _, _ := store.Take("other", limit); !ok {
+		t.Fatal("take of other key rejected")
+	}
+	if _, h := store.buckets["k"]; h {
+		t.Fatal("refilled bucket not swept")
+	}
+}
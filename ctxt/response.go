@@ -7,11 +7,12 @@ import (
 )
 
 type Response struct {
-	SCode     int    `json:"code"`
-	Msg       string `json:"msg"`
-	Data      any    `json:"data,omitempty"`
-	RequestID string `json:"request_id"`
-	Cost      int64  `json:"cost"`
+	SCode     int       `json:"code"`
+	Msg       string    `json:"msg"`
+	Data      any       `json:"data,omitempty"`
+	Page      *PageMeta `json:"page,omitempty"`
+	RequestID string    `json:"request_id"`
+	Cost      int64     `json:"cost"`
 }
 
 // NewOKResponse create ok response
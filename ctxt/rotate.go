@@ -0,0 +1,358 @@
+// rotate file writer, rotate by size or time, keep backups by count and age, gzip backups
+// backup name: DIR/NAME-20060102T150405.000[-SEQ].EXT[.gz], seq when name of same time exists
+// rotate failure keep writing current file, error returned by the write
+
+package ctxt
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "20060102T150405.000"
+	compressSuffix   = ".gz"
+)
+
+type rotateConfig struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+}
+
+type RotateOption func(cfg *rotateConfig)
+
+// WithMaxSize - rotate when file size over max bytes
+func WithMaxSize(size int64) RotateOption {
+	return func(cfg *rotateConfig) {
+		cfg.maxSize = size
+	}
+}
+
+// WithRotateInterval - rotate every interval, aligned to interval in UTC, e.g. 24h at midnight
+func WithRotateInterval(interval time.Duration) RotateOption {
+	return func(cfg *rotateConfig) {
+		cfg.interval = interval
+	}
+}
+
+// WithMaxBackups - max backups kept, 0 keep all
+func WithMaxBackups(n int) RotateOption {
+	return func(cfg *rotateConfig) {
+		cfg.maxBackups = n
+	}
+}
+
+// WithMaxAge - remove backups older than age, 0 keep all
+func WithMaxAge(age time.Duration) RotateOption {
+	return func(cfg *rotateConfig) {
+		cfg.maxAge = age
+	}
+}
+
+// WithCompress - gzip backups
+func WithCompress(compress bool) RotateOption {
+	return func(cfg *rotateConfig) {
+		cfg.compress = compress
+	}
+}
+
+// RotateWriter - concurrency safe rotate file writer
+type RotateWriter struct {
+	path string
+	cfg  *rotateConfig
+
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	nextRotate time.Time
+
+	millCh chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+var _ io.WriteCloser = (*RotateWriter)(nil)
+
+// NewRotateWriter - create rotate writer, file opened in append mode
+func NewRotateWriter(path string, opts ...RotateOption) (*RotateWriter, error) {
+	cfg := &rotateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	w := &RotateWriter{
+		path:   path,
+		cfg:    cfg,
+		millCh: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if err := w.open(time.Now()); err != nil {
+		return nil, err
+	}
+	w.wg.Add(1)
+	go w.mill()
+	w.triggerMill()
+	return w, nil
+}
+
+// Write implements io.Writer
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := time.Now()
+	var rotateErr error
+	if (w.cfg.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.cfg.maxSize) ||
+		(w.cfg.interval > 0 && !now.Before(w.nextRotate)) {
+		rotateErr = w.rotate(now)
+		if w.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Rotate - rotate file now, e.g. on SIGHUP
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate(time.Now())
+}
+
+// Close - close file, stop backup cleaning
+func (w *RotateWriter) Close() error {
+	var err error
+	w.once.Do(func() {
+		w.mu.Lock()
+		if w.file != nil {
+			err = w.file.Close()
+			w.file = nil
+		}
+		w.mu.Unlock()
+		close(w.done)
+		w.wg.Wait()
+	})
+	return err
+}
+
+// open - open file in append mode
+func (w *RotateWriter) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file = f
+	w.size = fi.Size()
+	if w.cfg.interval > 0 {
+		w.nextRotate = now.Truncate(w.cfg.interval).Add(w.cfg.interval)
+	}
+	return nil
+}
+
+// rotate - rename current file into backup, open new file
+// failed rotate reopen current file, file nil only when it can not reopen
+func (w *RotateWriter) rotate(now time.Time) error {
+	backup := w.backupName(now)
+	if err := w.file.Close(); err != nil {
+		w.file = nil
+		return errors.Join(err, w.open(now))
+	}
+	w.file = nil
+	if err := os.Rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		return errors.Join(err, w.open(now))
+	}
+	if err := w.open(now); err != nil {
+		// new file can not open, move backup back
+		if rerr := os.Rename(backup, w.path); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return errors.Join(err, w.open(now))
+	}
+	w.triggerMill()
+	return nil
+}
+
+// backupName - backup file path of rotate time, seq suffix when name or its gzip exists
+func (w *RotateWriter) backupName(t time.Time) string {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext)
+	name := filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext))
+	for seq := 1; fileExists(name) || fileExists(name+compressSuffix); seq++ {
+		name = filepath.Join(dir, fmt.Sprintf("%s-%s-%d%s", prefix, t.Format(backupTimeFormat), seq, ext))
+	}
+	return name
+}
+
+// fileExists - path exists
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// triggerMill - notify mill without blocking
+func (w *RotateWriter) triggerMill() {
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+// mill - compress and clean backups in background
+func (w *RotateWriter) mill() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.millCh:
+			if err := w.millOnce(); err != nil {
+				slog.Error("rotate log backups error", "path", w.path, "error", err)
+			}
+		}
+	}
+}
+
+type logBackup struct {
+	path string
+	t    time.Time
+	seq  int
+}
+
+// millOnce - compress backups, remove backups over count or age
+func (w *RotateWriter) millOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	remove := make([]logBackup, 0)
+	keep := make([]logBackup, 0, len(backups))
+	cutoff := time.Now().Add(-w.cfg.maxAge)
+	for i, b := range backups {
+		if (w.cfg.maxBackups > 0 && i >= w.cfg.maxBackups) || (w.cfg.maxAge > 0 && b.t.Before(cutoff)) {
+			remove = append(remove, b)
+			continue
+		}
+		keep = append(keep, b)
+	}
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if !w.cfg.compress {
+		return nil
+	}
+	for _, b := range keep {
+		if strings.HasSuffix(b.path, compressSuffix) {
+			continue
+		}
+		if err := gzipFile(b.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backups - backups sorted by time desc
+func (w *RotateWriter) backups() ([]logBackup, error) {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]logBackup, 0)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), compressSuffix)
+		ts, h := strings.CutPrefix(name, prefix)
+		if !h || !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		ts = strings.TrimSuffix(ts, ext)
+		seq := 0
+		if len(ts) > len(backupTimeFormat) {
+			seqStr, h := strings.CutPrefix(ts[len(backupTimeFormat):], "-")
+			n, err := strconv.Atoi(seqStr)
+			if !h || err != nil || n < 1 {
+				continue
+			}
+			ts, seq = ts[:len(backupTimeFormat)], n
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{path: filepath.Join(dir, e.Name()), t: t, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].t.Equal(backups[j].t) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].t.After(backups[j].t)
+	})
+	return backups, nil
+}
+
+// gzipFile - compress file into .gz and remove it
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path + compressSuffix)
+		return err
+	}
+	return os.Remove(path)
+}
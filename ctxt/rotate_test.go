@@ -0,0 +1,212 @@
+package ctxt
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor - poll cond until true or timeout, mill runs in background
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 2)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// backupFiles - sorted backup file names of log path
+func backupFiles(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ".log") + "-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, filepath.Base(m))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	w, err := NewRotateWriter(path, WithMaxSize(10), WithMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err = w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// write over max size into empty file not rotated
+	if _, err = w.Write([]byte("eeeeeeeeeeeeeeee\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("f\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	bts, _ := os.ReadFile(path)
+	if string(bts) != "f\n" {
+		t.Fatalf("current file %q", bts)
+	}
+	// 5 rotated, 2 newest kept
+	waitFor(t, func() bool { return len(backupFiles(t, path)) == 2 })
+	kept := make([]string, 0, 2)
+	for _, name := range backupFiles(t, path) {
+		bts, _ := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		kept = append(kept, string(bts))
+	}
+	sort.Strings(kept)
+	if strings.Join(kept, "") != "dddddddd\neeeeeeeeeeeeeeee\n" {
+		t.Fatalf("kept backups %q", kept)
+	}
+}
+
+func TestRotateCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotateWriter(path, WithCompress(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	_, _ = w.Write([]byte("first\n"))
+	if err = w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("second\n"))
+	// rotate twice in same millisecond, seq suffix
+	if err = w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		names := backupFiles(t, path)
+		for _, name := range names {
+			if !strings.HasSuffix(name, ".log.gz") {
+				return false
+			}
+		}
+		return len(names) == 3
+	})
+	contents := make([]string, 0)
+	for _, name := range backupFiles(t, path) {
+		f, err := os.Open(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bts, _ := io.ReadAll(gz)
+		_ = f.Close()
+		contents = append(contents, string(bts))
+	}
+	sort.Strings(contents)
+	if strings.Join(contents, "") != "first\nsecond\n" {
+		t.Fatalf("compressed backups %q", contents)
+	}
+}
+
+func TestRotateByInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotateWriter(path, WithRotateInterval(time.Millisecond*100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	_, _ = w.Write([]byte("a\n"))
+	time.Sleep(time.Millisecond * 150)
+	_, _ = w.Write([]byte("b\n"))
+	// newest backup hold a, empty backup when first write crossed interval
+	names := backupFiles(t, path)
+	if len(names) < 1 || len(names) > 2 {
+		t.Fatalf("interval backups %v", names)
+	}
+	rotated := ""
+	for _, name := range names {
+		bts, _ := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		rotated += string(bts)
+	}
+	if rotated != "a\n" {
+		t.Fatalf("rotated %q", rotated)
+	}
+	if bts, _ := os.ReadFile(path); string(bts) != "b\n" {
+		t.Fatalf("current file %q", bts)
+	}
+}
+
+func TestRotateMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	old := filepath.Join(dir, "app-"+time.Now().Add(-time.Hour*48).Format(backupTimeFormat)+".log")
+	recent := filepath.Join(dir, "app-"+time.Now().Add(-time.Hour).Format(backupTimeFormat)+"-2.log.gz")
+	other := filepath.Join(dir, "app-notatime.log")
+	for _, p := range []string{old, recent, other} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewRotateWriter(path, WithMaxAge(time.Hour*24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return !fileExists(old) })
+	if !fileExists(recent) || !fileExists(other) {
+		t.Fatal("recent backup or not backup file removed")
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("a")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("write after close, %v", err)
+	}
+	if err = w.Rotate(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("rotate after close, %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("close twice, %v", err)
+	}
+}
+
+func TestRotateAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("12345678"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRotateWriter(path, WithMaxSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// existing size counted
+	_, _ = w.Write([]byte("abc"))
+	if bts, _ := os.ReadFile(path); string(bts) != "abc" {
+		t.Fatalf("current file %q", bts)
+	}
+	if names := backupFiles(t, path); len(names) != 1 {
+		t.Fatalf("backups %v", names)
+	}
+}
@@ -0,0 +1,456 @@
+// http server builder, standard middleware chain, health check, pprof, graceful shutdown
+// middleware order: TraceFunc, MetricsFunc, BodyLimitFunc, ACLog, ResponseFunc, ErrorFunc, ErrorMapFunc
+
+package ctxt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+	PprofPath   = "/debug/pprof"
+
+	defaultDrainTimeout = time.Second * 30
+	defaultCheckTimeout = time.Second * 3
+)
+
+// ReadyCheck - readiness check, return error when not ready
+type ReadyCheck func(ctx context.Context) error
+
+// DBCheck - ping db
+func DBCheck(db *gorm.DB) ReadyCheck {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// BoolCheck - bool state check, e.g. BoolCheck(mylck.IsMaster, "not master")
+func BoolCheck(fn func() bool, msg string) ReadyCheck {
+	return func(ctx context.Context) error {
+		if !fn() {
+			return errors.New(msg)
+		}
+		return nil
+	}
+}
+
+type serverConfig struct {
+	sysLogWriter    io.Writer
+	accessLogWriter io.Writer
+
+	traceOpts    []TraceOption
+	acLogOpts    []ACLogOption
+	errorMapOpts []ErrorMapOption
+	authOpts     []AuthOption
+
+	metricsReg  prometheus.Registerer
+	metricsOpts []MetricsOption
+
+	middlewares []gin.HandlerFunc
+
+	checks       map[string]ReadyCheck
+	checkTimeout time.Duration
+
+	pprof        bool
+	logLevelPath string
+	drainTimeout time.Duration
+	drainDelay   time.Duration
+
+	certFile  string
+	keyFile   string
+	clientCA  string
+	tlsConfig *tls.Config
+
+	httpServer func(srv *http.Server)
+}
+
+type ServerOption func(cfg *serverConfig)
+
+// WithLogWriter - init system logger and access logger with writers, nil writer skipped
+func WithLogWriter(sys, access io.Writer) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.sysLogWriter = sys
+		cfg.accessLogWriter = access
+	}
+}
+
+// WithServerTrace - trace middleware options
+func WithServerTrace(opts ...TraceOption) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.traceOpts = opts
+	}
+}
+
+// WithServerACLog - access log options, health check routes always skipped
+func WithServerACLog(opts ...ACLogOption) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.acLogOpts = opts
+	}
+}
+
+// WithServerErrorMap - error mapping options
+func WithServerErrorMap(opts ...ErrorMapOption) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.errorMapOpts = opts
+	}
+}
+
+// WithServerAuth - auth options of API group and pprof
+func WithServerAuth(opts ...AuthOption) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.authOpts = opts
+	}
+}
+
+// WithServerMetrics - enable http metrics, nil reg use prometheus default registerer
+func WithServerMetrics(reg prometheus.Registerer, opts ...MetricsOption) ServerOption {
+	return func(cfg *serverConfig) {
+		if reg == nil {
+			reg = prometheus.DefaultRegisterer
+		}
+		cfg.metricsReg = reg
+		cfg.metricsOpts = opts
+	}
+}
+
+// WithMiddleware - append middleware after standard chain
+func WithMiddleware(middlewares ...gin.HandlerFunc) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.middlewares = append(cfg.middlewares, middlewares...)
+	}
+}
+
+// WithReadyCheck - add readiness check of /readyz
+func WithReadyCheck(name string, check ReadyCheck) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.checks[name] = check
+	}
+}
+
+// WithReadyCheckTimeout - timeout of all readiness checks, default 3s
+func WithReadyCheckTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.checkTimeout = timeout
+	}
+}
+
+// WithPprof - serve pprof at /debug/pprof behind auth
+func WithPprof(enable bool) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.pprof = enable
+	}
+}
+
+// WithLogLevelEndpoint - serve log level admin endpoint at path behind auth
+func WithLogLevelEndpoint(path string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.logLevelPath = path
+	}
+}
+
+// WithDrainTimeout - max wait of in flight requests when shutdown, default 30s
+func WithDrainTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.drainTimeout = timeout
+	}
+}
+
+// WithDrainDelay - keep serving with /readyz failed before shutdown, let load balancer remove node
+func WithDrainDelay(delay time.Duration) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.drainDelay = delay
+	}
+}
+
+// WithTLS - serve https with cert and key file
+func WithTLS(certFile, keyFile string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.certFile = certFile
+		cfg.keyFile = keyFile
+	}
+}
+
+// WithClientCA - mutual tls, require client cert signed by ca file
+func WithClientCA(caFile string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.clientCA = caFile
+	}
+}
+
+// WithTLSConfig - custom tls config, cert and client ca options added into it
+func WithTLSConfig(tlsConfig *tls.Config) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.tlsConfig = tlsConfig
+	}
+}
+
+// WithHTTPServer - customize http server, e.g. timeouts
+func WithHTTPServer(fn func(srv *http.Server)) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.httpServer = fn
+	}
+}
+
+type Server struct {
+	cfg    *serverConfig
+	engine *gin.Engine
+	srv    *http.Server
+	auth   gin.HandlerFunc
+
+	draining atomic.Bool
+	once     sync.Once
+}
+
+// NewServer - create server listen on addr with standard middleware chain
+func NewServer(addr string, opts ...ServerOption) (*Server, error) {
+	cfg := &serverConfig{
+		checks:       make(map[string]ReadyCheck),
+		checkTimeout: defaultCheckTimeout,
+		drainTimeout: defaultDrainTimeout,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.sysLogWriter != nil {
+		InitSystemLogger(cfg.sysLogWriter)
+	}
+	if cfg.accessLogWriter != nil {
+		InitAccessLogger(cfg.accessLogWriter)
+	}
+
+	engine := gin.New()
+	// gin context value fall back to request context, e.g. trace context
+	engine.ContextWithFallback = true
+
+	engine.Use(TraceFunc(cfg.traceOpts...))
+	if cfg.metricsReg != nil {
+		metrics, err := MetricsFunc(cfg.metricsReg, cfg.metricsOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("register http metrics error, %w", err)
+		}
+		engine.Use(metrics)
+	}
+	engine.Use(BodyLimitFunc(newAuthConfig(cfg.authOpts...).maxBody))
+	acLogOpts := append([]ACLogOption{WithSkipRoutes(HealthzPath, ReadyzPath)}, cfg.acLogOpts...)
+	engine.Use(ACLog(acLogOpts...), ResponseFunc(), ErrorFunc(), ErrorMapFunc(cfg.errorMapOpts...))
+	engine.Use(cfg.middlewares...)
+	// unmatched route and method through chain, error response instead of empty 200
+	engine.HandleMethodNotAllowed = true
+	engine.NoRoute(func(c *gin.Context) {
+		GetCTX(c).SetErrorResponse(http.StatusNotFound, http.StatusNotFound, "route not found")
+	})
+	engine.NoMethod(func(c *gin.Context) {
+		GetCTX(c).SetErrorResponse(http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "method not allowed")
+	})
+
+	s := &Server{
+		cfg:    cfg,
+		engine: engine,
+		auth:   AuthFunc(cfg.authOpts...),
+	}
+
+	engine.GET(HealthzPath, func(c *gin.Context) {
+		GetCTX(c).SetData("ok")
+	})
+	engine.GET(ReadyzPath, s.readyz)
+	if cfg.pprof {
+		s.servePprof()
+	}
+	if cfg.logLevelPath != "" {
+		engine.GET(cfg.logLevelPath, s.auth, LogLevelFunc())
+		engine.PUT(cfg.logLevelPath, s.auth, LogLevelFunc())
+		engine.POST(cfg.logLevelPath, s.auth, LogLevelFunc())
+	}
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: engine,
+	}
+	if cfg.httpServer != nil {
+		cfg.httpServer(srv)
+	}
+	if err := s.setupTLS(srv); err != nil {
+		return nil, err
+	}
+	s.srv = srv
+	return s, nil
+}
+
+// Engine - gin engine, for routes without auth
+func (s *Server) Engine() *gin.Engine {
+	return s.engine
+}
+
+// API - route group protected by AuthFunc
+func (s *Server) API(path string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	return s.engine.Group(path, append([]gin.HandlerFunc{s.auth}, handlers...)...)
+}
+
+// Run - serve until SIGINT/SIGTERM, then drain in flight requests
+func (s *Server) Run() error {
+	// tls config read before serve, http2 setup of serve modify it
+	useTLS := s.srv.TLSConfig != nil
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if useTLS {
+			err = s.srv.ListenAndServeTLS(s.cfg.certFile, s.cfg.keyFile)
+		} else {
+			err = s.srv.ListenAndServe()
+		}
+		errCh <- err
+	}()
+	slog.Info("http server started", "addr", s.srv.Addr, "tls", useTLS)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-sigCh:
+		slog.Info("http server shutting down", "signal", sig.String(), "drain_timeout", s.cfg.drainTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.drainDelay+s.cfg.drainTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown - mark not ready, wait drain delay, then shutdown gracefully
+func (s *Server) Shutdown(ctx context.Context) error {
+	var err error
+	s.once.Do(func() {
+		s.draining.Store(true)
+		if s.cfg.drainDelay > 0 {
+			select {
+			case <-time.After(s.cfg.drainDelay):
+			case <-ctx.Done():
+			}
+		}
+		err = s.srv.Shutdown(ctx)
+		if err != nil {
+			slog.Error("http server shutdown error", "error", err)
+			return
+		}
+		slog.Info("http server stopped")
+	})
+	return err
+}
+
+// readyz - run readiness checks concurrently
+func (s *Server) readyz(c *gin.Context) {
+	reqCtx := GetCTX(c)
+	if s.draining.Load() {
+		reqCtx.SetErrorResponse(http.StatusServiceUnavailable, http.StatusServiceUnavailable, "server draining")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), s.cfg.checkTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(s.cfg.checks))
+	for name := range s.cfg.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, len(names))
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check ReadyCheck) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, s.cfg.checks[name])
+	}
+	wg.Wait()
+
+	rst := make(map[string]string, len(names))
+	failed := false
+	for i, name := range names {
+		rst[name] = "ok"
+		if errs[i] != nil {
+			rst[name] = errs[i].Error()
+			failed = true
+		}
+	}
+	if failed {
+		reqCtx.SetErrorResponse(http.StatusServiceUnavailable, http.StatusServiceUnavailable, "not ready")
+		reqCtx.Response.Data = rst
+		return
+	}
+	reqCtx.SetData(rst)
+}
+
+// servePprof - pprof handlers behind auth
+func (s *Server) servePprof() {
+	g := s.engine.Group(PprofPath, s.auth)
+	g.GET("/", gin.WrapF(pprof.Index))
+	g.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	g.GET("/profile", gin.WrapF(pprof.Profile))
+	g.POST("/symbol", gin.WrapF(pprof.Symbol))
+	g.GET("/symbol", gin.WrapF(pprof.Symbol))
+	g.GET("/trace", gin.WrapF(pprof.Trace))
+	g.GET("/:name", func(c *gin.Context) {
+		pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// setupTLS - tls and mutual tls config
+func (s *Server) setupTLS(srv *http.Server) error {
+	if s.cfg.certFile == "" && s.cfg.tlsConfig == nil {
+		if s.cfg.clientCA != "" {
+			return fmt.Errorf("client ca require tls")
+		}
+		return nil
+	}
+	tlsConfig := s.cfg.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if s.cfg.clientCA != "" {
+		pem, err := os.ReadFile(s.cfg.clientCA)
+		if err != nil {
+			return fmt.Errorf("read client ca error, %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client ca no certificate")
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	srv.TLSConfig = tlsConfig
+	return nil
+}
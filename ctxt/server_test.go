@@ -0,0 +1,131 @@
+package ctxt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServerRoutes(t *testing.T) {
+	var ready atomic.Bool
+	s, err := NewServer("127.0.0.1:0",
+		WithServerAuth(WithCredentialStore(testCredentialStore()), WithAuthMode(AuthModeHMAC)),
+		WithReadyCheck("db", BoolCheck(ready.Load, "db down")),
+		WithReadyCheck("cache", func(ctx context.Context) error { return nil }),
+		WithPprof(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.API("/api/v1").GET("/user", func(c *gin.Context) {
+		GetCTX(c).SetData(GetCTX(c).AK)
+	})
+
+	if w, rsps := doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, HealthzPath, nil)); w.Code != http.StatusOK || rsps.Data != "ok" {
+		t.Fatalf("healthz %d %+v", w.Code, rsps)
+	}
+
+	w, rsps := doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, ReadyzPath, nil))
+	checks, _ := rsps.Data.(map[string]any)
+	if w.Code != http.StatusServiceUnavailable || checks["db"] != "db down" || checks["cache"] != "ok" {
+		t.Fatalf("not ready %d %+v", w.Code, rsps)
+	}
+	ready.Store(true)
+	if w, rsps = doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, ReadyzPath, nil)); w.Code != http.StatusOK {
+		t.Fatalf("ready %d %+v", w.Code, rsps)
+	}
+
+	// unmatched route and method
+	w, rsps = doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, "/not/exist", nil))
+	if w.Code != http.StatusNotFound || rsps.SCode != http.StatusNotFound || rsps.RequestID == "" {
+		t.Fatalf("no route %d %+v", w.Code, rsps)
+	}
+	w, rsps = doRequest(t, s.Engine(), httptest.NewRequest(http.MethodDelete, HealthzPath, nil))
+	if w.Code != http.StatusMethodNotAllowed || rsps.SCode != http.StatusMethodNotAllowed {
+		t.Fatalf("no method %d %+v", w.Code, rsps)
+	}
+
+	// api and pprof behind auth
+	for _, path := range []string{"/api/v1/user", PprofPath + "/"} {
+		if w, _ = doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s without auth %d", path, w.Code)
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/user", nil)
+	if err = NewSigner("ak1", "sk1").Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	if w, rsps = doRequest(t, s.Engine(), r); w.Code != http.StatusOK || rsps.Data != "ak1" {
+		t.Fatalf("signed api %d %+v", w.Code, rsps)
+	}
+	r = httptest.NewRequest(http.MethodGet, PprofPath+"/", nil)
+	if err = NewSigner("ak1", "sk1").Sign(r); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	s.Engine().ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Fatalf("signed pprof %d", w.Code)
+	}
+}
+
+func TestServerShutdownDrain(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0", WithDrainDelay(time.Millisecond*200), WithDrainTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run()
+	}()
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	time.Sleep(time.Millisecond * 50)
+	// not ready while draining, still serving
+	w, rsps := doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, ReadyzPath, nil))
+	if w.Code != http.StatusServiceUnavailable || rsps.Msg != "server draining" {
+		t.Fatalf("draining readyz %d %+v", w.Code, rsps)
+	}
+	if w, _ = doRequest(t, s.Engine(), httptest.NewRequest(http.MethodGet, HealthzPath, nil)); w.Code != http.StatusOK {
+		t.Fatalf("draining healthz %d", w.Code)
+	}
+
+	if err = <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("run not returned after shutdown")
+	}
+	// shutdown once
+	if err = s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	if _, err := NewServer("127.0.0.1:0", WithClientCA("ca.pem")); err == nil {
+		t.Fatal("client ca without tls")
+	}
+	if _, err := NewServer("127.0.0.1:0", WithTLS("cert.pem", "key.pem"), WithClientCA("not-exist.pem")); err == nil {
+		t.Fatal("not exist client ca")
+	}
+	s, err := NewServer("127.0.0.1:0", WithTLS("cert.pem", "key.pem"), WithHTTPServer(func(srv *http.Server) {
+		srv.ReadHeaderTimeout = time.Second
+	}))
+	if err != nil || s.srv.TLSConfig == nil || s.srv.ReadHeaderTimeout != time.Second {
+		t.Fatalf("tls server %+v %v", s, err)
+	}
+}
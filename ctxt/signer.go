@@ -0,0 +1,90 @@
+// client side signer, sign http request for AuthFunc
+
+package ctxt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/itoolkits/toolkit/tracet"
+)
+
+type Signer struct {
+	AK string
+	SK string
+}
+
+// NewSigner create signer
+func NewSigner(ak, sk string) *Signer {
+	return &Signer{AK: ak, SK: sk}
+}
+
+// Sign sign request with hmac-sha256, set AK/Timestamp/Nonce/Authorization header
+func (s *Signer) Sign(r *http.Request) error {
+	body, err := readRequestBody(r)
+	if err != nil {
+		return err
+	}
+	ts := time.Now().Unix()
+	nonce := uuid.New().String()
+
+	sig := hmacSign(s.SK, CanonicalRequest(r, body, ts, nonce))
+
+	r.Header.Set(HeaderAK, s.AK)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderAuthorization, HMACScheme+" "+hex.EncodeToString(sig))
+	return nil
+}
+
+// SignMD5 sign request with legacy md5 mode, for migration
+func (s *Signer) SignMD5(r *http.Request) {
+	ts := time.Now().Unix()
+	r.Header.Set(HeaderAK, s.AK)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	r.Header.Set(HeaderAuthorization, hopeAuth(s.AK, s.SK, ts))
+}
+
+// Do sign request and send by client, use http.DefaultClient when client nil
+// trace context of request context forwarded by traceparent/tracestate header
+func (s *Signer) Do(client *http.Client, r *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	tracet.Inject(r.Context(), r.Header)
+	if err := s.Sign(r); err != nil {
+		return nil, err
+	}
+	return client.Do(r)
+}
+
+// readRequestBody read body and reset it, prefer GetBody
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
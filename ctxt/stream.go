@@ -0,0 +1,175 @@
+// streamed response, ndjson or sse, written before ResponseFunc
+// ndjson: {"data":ITEM} per line, last line status frame
+// sse: event data with ITEM, last event status with status frame
+// status frame: {"code":200,"msg":"","request_id":"...","cost":1,"count":10}
+// http status always 200, terminal code of status frame kept in RqstCtx.StreamCode for ACLog
+// panic while streaming recovered by ErrorFunc, error status frame written
+
+package ctxt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StreamNDJSON = "ndjson"
+	StreamSSE    = "sse"
+
+	sseEventData   = "data"
+	sseEventStatus = "status"
+)
+
+// errStreamClientGone - status of stream stopped by client gone
+var errStreamClientGone = NewError(499, 499, "client closed request")
+
+type StreamStatus struct {
+	SCode     int    `json:"code"`
+	Msg       string `json:"msg"`
+	RequestID string `json:"request_id"`
+	Cost      int64  `json:"cost"`
+	Count     int    `json:"count"`
+}
+
+type streamItem struct {
+	Data any `json:"data"`
+}
+
+// StreamWriter - write items of streamed response, not concurrency safe
+type StreamWriter struct {
+	c      *gin.Context
+	mode   string
+	reqCtx *RqstCtx
+	start  time.Time
+	closed bool
+}
+
+// NewStream - start streamed response with mode, header written
+func NewStream(c *gin.Context, mode string) *StreamWriter {
+	reqCtx := GetCTX(c)
+	reqCtx.StreamMode = mode
+
+	c.Header("Cache-Control", "no-cache")
+	switch mode {
+	case StreamSSE:
+		c.Header("Content-Type", "text/event-stream; charset=utf-8")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+	default:
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	s := &StreamWriter{
+		c:      c,
+		mode:   mode,
+		reqCtx: reqCtx,
+		start:  time.Now(),
+	}
+	reqCtx.stream = s
+	return s
+}
+
+// Send - write one item and flush, error when client gone
+func (s *StreamWriter) Send(item any) error {
+	if s.closed {
+		return fmt.Errorf("stream closed")
+	}
+	if err := s.c.Request.Context().Err(); err != nil {
+		return err
+	}
+	var err error
+	if s.mode == StreamSSE {
+		err = s.writeEvent(sseEventData, item)
+	} else {
+		err = s.writeLine(&streamItem{Data: item})
+	}
+	if err != nil {
+		return err
+	}
+	s.reqCtx.StreamItems++
+	return nil
+}
+
+// Close - write status frame of err, err resolved as SetError, response code kept for ACLog
+func (s *StreamWriter) Close(err error) error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	rsps := s.reqCtx.Response
+	if err != nil {
+		appErr := s.resolve(err)
+		rsps.SCode = appErr.SCode
+		rsps.Msg = appErr.Msg
+		s.reqCtx.StreamCode = appErr.HTTPCode
+	} else {
+		rsps.SCode = http.StatusOK
+		rsps.Msg = ""
+		s.reqCtx.StreamCode = http.StatusOK
+	}
+	rsps.RequestID = s.reqCtx.RequestID
+
+	status := &StreamStatus{
+		SCode:     rsps.SCode,
+		Msg:       rsps.Msg,
+		RequestID: rsps.RequestID,
+		Cost:      time.Since(s.start).Milliseconds(),
+		Count:     s.reqCtx.StreamItems,
+	}
+	if s.mode == StreamSSE {
+		return s.writeEvent(sseEventStatus, status)
+	}
+	return s.writeLine(status)
+}
+
+// resolve - app error of stream error, client gone as 499
+func (s *StreamWriter) resolve(err error) *AppError {
+	ctx := s.c.Request.Context()
+	if cerr := ctx.Err(); cerr != nil && errors.Is(err, cerr) {
+		return errStreamClientGone.WithCause(err)
+	}
+	return mapError(s.c, err)
+}
+
+// writeLine - write ndjson line
+func (s *StreamWriter) writeLine(v any) error {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	bts = append(bts, '\n')
+	if _, err = s.c.Writer.Write(bts); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+// writeEvent - write sse event, id is item sequence
+func (s *StreamWriter) writeEvent(event string, v any) error {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", s.reqCtx.StreamItems, event, bts)
+	if err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+// Stream - stream items sent by fn, status frame written by fn result
+func Stream(c *gin.Context, mode string, fn func(s *StreamWriter) error) {
+	s := NewStream(c, mode)
+	_ = s.Close(fn(s))
+}
@@ -0,0 +1,169 @@
+package ctxt
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamEngine - engine with full chain and streamed routes
+func streamEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(ACLog(), ResponseFunc(), ErrorFunc(), ErrorMapFunc())
+	engine.GET("/stream/:mode", func(c *gin.Context) {
+		Stream(c, c.Param("mode"), func(s *StreamWriter) error {
+			for i := 1; i <= 3; i++ {
+				if err := s.Send(map[string]int{"n": i}); err != nil {
+					return err
+				}
+			}
+			if c.Query("fail") != "" {
+				return ErrConflict.WithMsg("stream conflict")
+			}
+			return nil
+		})
+	})
+	engine.GET("/panic", func(c *gin.Context) {
+		s := NewStream(c, StreamNDJSON)
+		_ = s.Send(1)
+		panic("stream broken")
+	})
+	engine.GET("/unclosed", func(c *gin.Context) {
+		_ = NewStream(c, StreamNDJSON).Send(1)
+	})
+	return engine
+}
+
+// ndjsonLines - decoded ndjson lines
+func ndjsonLines(t *testing.T, body string) []map[string]any {
+	t.Helper()
+	rst := make([]map[string]any, 0)
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		m := make(map[string]any)
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("ndjson line %q, %v", line, err)
+		}
+		rst = append(rst, m)
+	}
+	return rst
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	engine := streamEngine()
+	var w *httptest.ResponseRecorder
+	recs := captureAccessLog(t, func() {
+		w, _ = doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/stream/ndjson", nil))
+	})
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/x-ndjson") {
+		t.Fatalf("ndjson response %d %v", w.Code, w.Header())
+	}
+	lines := ndjsonLines(t, w.Body.String())
+	if len(lines) != 4 || jsonPath(lines[2], "data", "n") != float64(3) {
+		t.Fatalf("ndjson lines %v", lines)
+	}
+	status := lines[3]
+	if status["code"] != float64(http.StatusOK) || status["count"] != float64(3) || status["request_id"] == "" {
+		t.Fatalf("status frame %v", status)
+	}
+	if len(recs) != 1 || recs[0]["stream_mode"] != StreamNDJSON || recs[0]["stream_items"] != float64(3) ||
+		recs[0]["stream_code"] != float64(http.StatusOK) || recs[0]["request_id"] != status["request_id"] {
+		t.Fatalf("stream access log %v", recs)
+	}
+
+	// error status frame, http code still 200, access log by status code
+	recs = captureAccessLog(t, func() {
+		w, _ = doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/stream/ndjson?fail=1", nil))
+	})
+	lines = ndjsonLines(t, w.Body.String())
+	status = lines[len(lines)-1]
+	if w.Code != http.StatusOK || status["code"] != float64(http.StatusConflict) || status["msg"] != "stream conflict" {
+		t.Fatalf("error status frame %d %v", w.Code, status)
+	}
+	if len(recs) != 1 || recs[0]["stream_code"] != float64(http.StatusConflict) {
+		t.Fatalf("error stream access log %v", recs)
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	w, _ := doRequest(t, streamEngine(), httptest.NewRequest(http.MethodGet, "/stream/sse", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("sse headers %v", w.Header())
+	}
+
+	events := make([]map[string]string, 0)
+	event := make(map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for sc.Scan() {
+		if sc.Text() == "" {
+			events = append(events, event)
+			event = make(map[string]string)
+			continue
+		}
+		k, v, _ := strings.Cut(sc.Text(), ": ")
+		event[k] = v
+	}
+	if len(events) != 4 || events[0]["event"] != sseEventData || events[0]["data"] != `{"n":1}` || events[0]["id"] != "0" {
+		t.Fatalf("sse events %v", events)
+	}
+	last := events[3]
+	if last["event"] != sseEventStatus || last["id"] != "3" || !strings.Contains(last["data"], `"count":3`) {
+		t.Fatalf("sse status event %v", last)
+	}
+}
+
+func TestStreamPanic(t *testing.T) {
+	engine := streamEngine()
+	var w *httptest.ResponseRecorder
+	recs := captureAccessLog(t, func() {
+		captureSystemLog(t, func() {
+			w, _ = doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		})
+	})
+	lines := ndjsonLines(t, w.Body.String())
+	status := lines[len(lines)-1]
+	if len(lines) != 2 || status["code"] != float64(http.StatusInternalServerError) || status["count"] != float64(1) {
+		t.Fatalf("panic stream %v", lines)
+	}
+	if len(recs) != 1 || recs[0]["stream_code"] != float64(http.StatusInternalServerError) {
+		t.Fatalf("panic stream access log %v", recs)
+	}
+
+	// handler returned without status frame
+	recs = captureAccessLog(t, func() {
+		w, _ = doRequest(t, engine, httptest.NewRequest(http.MethodGet, "/unclosed", nil))
+	})
+	if lines = ndjsonLines(t, w.Body.String()); len(lines) != 1 {
+		t.Fatalf("unclosed stream %v", lines)
+	}
+	if len(recs) != 1 || recs[0]["stream_code"] != float64(http.StatusInternalServerError) {
+		t.Fatalf("unclosed stream access log %v", recs)
+	}
+}
+
+func TestStreamClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var sendErr error
+	engine := gin.New()
+	engine.Use(ResponseFunc(), ErrorFunc())
+	engine.GET("/gone", func(c *gin.Context) {
+		Stream(c, StreamNDJSON, func(s *StreamWriter) error {
+			_ = s.Send(1)
+			cancel()
+			sendErr = s.Send(2)
+			return sendErr
+		})
+	})
+	r := httptest.NewRequest(http.MethodGet, "/gone", nil).WithContext(ctx)
+	w, _ := doRequest(t, engine, r)
+	lines := ndjsonLines(t, w.Body.String())
+	if !errors.Is(sendErr, context.Canceled) || len(lines) != 2 || lines[1]["code"] != float64(499) {
+		t.Fatalf("client gone %v %v", sendErr, lines)
+	}
+}
@@ -0,0 +1,21 @@
+#!/bin/sh
+# vendor swagger-ui-dist assets of version $1, verified against SHA256SUMS
+# SHA256SUMS recorded on first vendoring, review and commit it with the assets
+set -eu
+
+version="$1"
+dir="$(cd "$(dirname "$0")" && pwd)"
+tmp="$(mktemp -d)"
+trap 'rm -rf "$tmp"' EXIT
+
+for f in swagger-ui.css swagger-ui-bundle.js; do
+	curl -fsSL -o "$tmp/$f" "https://unpkg.com/swagger-ui-dist@$version/$f"
+done
+
+if [ -f "$dir/SHA256SUMS" ]; then
+	(cd "$tmp" && sha256sum -c "$dir/SHA256SUMS")
+else
+	(cd "$tmp" && sha256sum swagger-ui.css swagger-ui-bundle.js) > "$dir/SHA256SUMS"
+	echo "recorded $dir/SHA256SUMS, review it before commit" >&2
+fi
+cp "$tmp/swagger-ui.css" "$tmp/swagger-ui-bundle.js" "$dir/"
@@ -0,0 +1,95 @@
+// trace middleware, w3c traceparent/tracestate propagation
+// span context put into request context, log with xxxContext to record trace ids
+
+package ctxt
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/itoolkits/toolkit/tracet"
+)
+
+type traceConfig struct {
+	sampled  bool
+	exporter tracet.SpanExporter
+}
+
+type TraceOption func(cfg *traceConfig)
+
+// WithTraceSampled - sampled flag of new root trace, default true
+func WithTraceSampled(sampled bool) TraceOption {
+	return func(cfg *traceConfig) {
+		cfg.sampled = sampled
+	}
+}
+
+// WithSpanExporter - export server span of sampled request, e.g. bridge to opentelemetry
+func WithSpanExporter(exporter tracet.SpanExporter) TraceOption {
+	return func(cfg *traceConfig) {
+		cfg.exporter = exporter
+	}
+}
+
+// TraceFunc - trace middleware, continue remote trace or start new one, use as first middleware
+func TraceFunc(opts ...TraceOption) gin.HandlerFunc {
+	cfg := &traceConfig{
+		sampled: true,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *gin.Context) {
+		var sc *tracet.SpanContext
+		if remote := tracet.Extract(c.Request.Header); remote != nil {
+			sc = remote.Child()
+		} else {
+			sc = tracet.NewRoot(cfg.sampled)
+		}
+
+		reqCtx := GetCTX(c)
+		reqCtx.TraceID = sc.TraceID
+		reqCtx.SpanID = sc.SpanID
+		reqCtx.ParentSpanID = sc.ParentSpanID
+		reqCtx.TraceState = sc.TraceState
+
+		c.Request = c.Request.WithContext(tracet.NewContext(c.Request.Context(), sc))
+		reqCtx.ctx = c.Request.Context()
+		c.Header(tracet.HeaderTraceParent, sc.TraceParent())
+		if sc.TraceState != "" {
+			c.Header(tracet.HeaderTraceState, sc.TraceState)
+		}
+
+		start := time.Now()
+
+		c.Next()
+
+		if cfg.exporter == nil || !sc.Sampled() {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		span := &tracet.Span{
+			SpanContext: *sc,
+			Name:        c.Request.Method + " " + route,
+			Kind:        "server",
+			Start:       start,
+			End:         time.Now(),
+			Attributes: map[string]any{
+				"http.method":      c.Request.Method,
+				"http.route":       route,
+				"http.status_code": c.Writer.Status(),
+				"request_id":       reqCtx.RequestID,
+			},
+		}
+		if reqCtx.Response != nil && reqCtx.Response.SCode >= 500 {
+			span.Error = strconv.Itoa(reqCtx.Response.SCode) + " " + reqCtx.Response.Msg
+		}
+		cfg.exporter.ExportSpan(span)
+	}
+}
@@ -0,0 +1,119 @@
+package ctxt
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/itoolkits/toolkit/tracet"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceFunc(t *testing.T) {
+	spans := make([]*tracet.Span, 0)
+	var handlerCtx context.Context
+	var reqCtx *RqstCtx
+	engine := gin.New()
+	engine.Use(TraceFunc(WithSpanExporter(tracet.ExporterFunc(func(span *tracet.Span) {
+		spans = append(spans, span)
+	}))), ResponseFunc())
+	engine.GET("/a/:id", func(c *gin.Context) {
+		handlerCtx = c.Request.Context()
+		reqCtx = GetCTX(c)
+		reqCtx.SetOKResponse()
+	})
+
+	// continue remote trace
+	r := httptest.NewRequest(http.MethodGet, "/a/1", nil)
+	r.Header.Set(tracet.HeaderTraceParent, testTraceParent)
+	r.Header.Set(tracet.HeaderTraceState, "vendor=1")
+	w, _ := doRequest(t, engine, r)
+
+	sc := tracet.FromContext(handlerCtx)
+	if sc == nil || sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.ParentSpanID != "00f067aa0ba902b7" ||
+		sc.SpanID == sc.ParentSpanID || !sc.Sampled() || sc.TraceState != "vendor=1" {
+		t.Fatalf("span context %+v", sc)
+	}
+	if reqCtx.TraceID != sc.TraceID || reqCtx.SpanID != sc.SpanID || reqCtx.ParentSpanID != sc.ParentSpanID {
+		t.Fatalf("request context trace ids %+v", reqCtx)
+	}
+	if w.Header().Get(tracet.HeaderTraceParent) != sc.TraceParent() || w.Header().Get(tracet.HeaderTraceState) != "vendor=1" {
+		t.Fatalf("response trace headers %v", w.Header())
+	}
+	if len(spans) != 1 || spans[0].Name != "GET /a/:id" || spans[0].Kind != "server" ||
+		spans[0].Attributes["http.status_code"] != http.StatusOK {
+		t.Fatalf("exported spans %+v", spans)
+	}
+
+	// invalid traceparent start new root trace
+	for _, tp := range []string{"", "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		r = httptest.NewRequest(http.MethodGet, "/a/1", nil)
+		r.Header.Set(tracet.HeaderTraceParent, tp)
+		doRequest(t, engine, r)
+		sc = tracet.FromContext(handlerCtx)
+		if sc == nil || sc.TraceID == "4bf92f3577b34da6a3ce929d0e0e4736" || sc.ParentSpanID != "" || len(sc.TraceID) != 32 {
+			t.Fatalf("traceparent %q span context %+v", tp, sc)
+		}
+	}
+
+	// not sampled remote trace not exported
+	spans = spans[:0]
+	r = httptest.NewRequest(http.MethodGet, "/a/1", nil)
+	r.Header.Set(tracet.HeaderTraceParent, strings.TrimSuffix(testTraceParent, "01")+"00")
+	doRequest(t, engine, r)
+	if len(spans) != 0 {
+		t.Fatalf("not sampled span exported, %+v", spans)
+	}
+}
+
+func TestTraceLogIDs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	old := AccessLogger
+	InitAccessLogger(buf)
+	defer func() {
+		AccessLogger = old
+	}()
+
+	engine := gin.New()
+	engine.Use(TraceFunc(), ACLog(), ResponseFunc())
+	engine.GET("/a", func(c *gin.Context) {
+		GetCTX(c).SetOKResponse()
+	})
+	r := httptest.NewRequest(http.MethodGet, "/a", nil)
+	r.Header.Set(tracet.HeaderTraceParent, testTraceParent)
+	doRequest(t, engine, r)
+	if !strings.Contains(buf.String(), "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Fatalf("access log without trace id, %s", buf.String())
+	}
+}
+
+func TestSignerInjectTrace(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	sc, err := tracet.ParseTraceParent(testTraceParent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tracet.NewContext(context.Background(), sc.Child())
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	rsp, err := NewSigner("ak1", "sk1").Do(nil, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rsp.Body.Close()
+	remote := tracet.Extract(got)
+	if remote == nil || remote.TraceID != sc.TraceID || remote.SpanID == sc.SpanID {
+		t.Fatalf("forwarded traceparent %v", got)
+	}
+}
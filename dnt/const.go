@@ -25,6 +25,8 @@ const (
 	defaultUDPPkgSize = 4096 // From dig cmd pkg
 
 	defaultTimeout = time.Second * 10 //time
+
+	dumpDBMaxLineSize = 1024 * 1024 // long txt record in dump db
 )
 
 const (
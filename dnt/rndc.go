@@ -14,18 +14,43 @@ import (
 
 // ParseDumpDB parse dump db
 func ParseDumpDB(path string) (map[string]map[string][]*RR, error) {
-	f, err := os.Open(path)
+	rst := make(map[string]map[string][]*RR)
+
+	err := ScanDumpDB(path, func(z, v string, rr *RR) error {
+		vrs, h := rst[z]
+		if !h {
+			rst[z] = map[string][]*RR{
+				v: {rr},
+			}
+		} else {
+			rs, h := vrs[v]
+			if !h {
+				vrs[v] = []*RR{rr}
+			} else {
+				vrs[v] = append(rs, rr)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return rst, nil
+}
 
-	rst := make(map[string]map[string][]*RR)
+// ScanDumpDB scan dump db line by line, call fn with zone, view and record, stop when fn return error
+func ScanDumpDB(path string, fn func(zone, view string, rr *RR) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
 	var z string
 	var v string
 	var ok bool
 	scan := bufio.NewScanner(f)
+	scan.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), dumpDBMaxLineSize)
 	for scan.Scan() {
 		line := scan.Text()
 		line = strings.Trim(line, " ")
@@ -49,24 +74,14 @@ func ParseDumpDB(path string) (map[string]map[string][]*RR, error) {
 		rr := &RR{}
 		err := rr.Unmarshal(z, v, line)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		vrs, h := rst[z]
-		if !h {
-			rst[z] = map[string][]*RR{
-				v: {rr},
-			}
-		} else {
-			rs, h := vrs[v]
-			if !h {
-				vrs[v] = []*RR{rr}
-			} else {
-				vrs[v] = append(rs, rr)
-			}
+		err = fn(z, v, rr)
+		if err != nil {
+			return err
 		}
 	}
-	return rst, nil
+	return scan.Err()
 }
 
 // extractZone extract zone from line
@@ -136,14 +151,32 @@ func ParseTextZoneFile(zone string, view string, path string) ([]*RR, error) {
 
 // ParseZoneFile parse bind zone file
 func ParseZoneFile(zone string, view string, path string) ([]*RR, error) {
-	f, err := os.Open(path)
+	rrList := make([]*RR, 0)
+	err := ScanZoneFile(zone, view, path, func(rr *RR) error {
+		rrList = append(rrList, rr)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return rrList, nil
+}
+
+// ScanZoneFile scan zone file record by record, stop when fn return error
+func ScanZoneFile(zone string, view string, path string, fn func(rr *RR) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
 
-	rrList := make([]*RR, 0)
-	zp := dns.NewZoneParser(f, "", "")
+	// zone as default origin, relative name work without $ORIGIN
+	origin := ""
+	if zone != "" {
+		origin = dns.Fqdn(zone)
+	}
+
+	zp := dns.NewZoneParser(f, origin, path)
 	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
 		rrConv := &RecordConv{
 			Zone:   zone,
@@ -152,15 +185,13 @@ func ParseZoneFile(zone string, view string, path string) ([]*RR, error) {
 		}
 		record, err := rrConv.ConvRR()
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err = fn(record); err != nil {
+			return err
 		}
-		rrList = append(rrList, record)
-	}
-	err = zp.Err()
-	if err != nil {
-		return nil, err
 	}
-	return rrList, nil
+	return zp.Err()
 }
 
 type RecordConv struct {
@@ -96,7 +96,7 @@ func (x *Xfr) Query() ([]dns.RR, error) {
 		break
 	}
 
-	return rst, nil
+	return rst, err
 }
 
 // ixfr - ixfr transfer
@@ -137,8 +137,8 @@ func (x *Xfr) xfr(msg *dns.Msg) ([]dns.RR, error) {
 	for c := range ch {
 		if c.Error != nil {
 			slog.Error("dnt xfr channel error",
-				"server", x.ns, "port", x.port, "msg", msg, "msg", c.Error)
-			return nil, err
+				"server", x.ns, "port", x.port, "msg", msg, "error", c.Error)
+			return nil, c.Error
 		}
 		rst = append(rst, c.RR...)
 	}
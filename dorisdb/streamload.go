@@ -4,6 +4,7 @@ package dorisdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/itoolkits/toolkit/retry"
+	"github.com/itoolkits/toolkit/tracet"
 )
 
 type StreamLoad struct {
@@ -36,6 +38,11 @@ func (s *StreamLoad) auth() string {
 
 // LoadData - doris stream load
 func (s *StreamLoad) LoadData(data []byte) error {
+	return s.LoadDataContext(context.Background(), data)
+}
+
+// LoadDataContext - doris stream load, forward trace context of ctx
+func (s *StreamLoad) LoadDataContext(ctx context.Context, data []byte) error {
 	client := &http.Client{
 		Timeout: s.Timeout,
 	}
@@ -44,7 +51,7 @@ func (s *StreamLoad) LoadData(data []byte) error {
 
 	reader := bytes.NewReader(data)
 
-	request, err := http.NewRequest(http.MethodPut, url, reader)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, url, reader)
 	if err != nil {
 		return err
 	}
@@ -65,6 +72,7 @@ func (s *StreamLoad) LoadData(data []byte) error {
 	for k, v := range s.Headers {
 		request.Header.Set(k, v)
 	}
+	tracet.Inject(ctx, request.Header)
 
 	response, err := client.Do(request)
 	if err != nil {
@@ -108,6 +116,13 @@ func (s *StreamLoad) LoadDataWithRetry(data []byte, times int) error {
 	}, times)
 }
 
+// LoadDataWithRetryContext - load data with retry, forward trace context of ctx
+func (s *StreamLoad) LoadDataWithRetryContext(ctx context.Context, data []byte, times int) error {
+	return retry.Do(func() error {
+		return s.LoadDataContext(ctx, data)
+	}, times)
+}
+
 type DorisResponseBody struct {
 	TxnID                  int    `json:"TxnId"`
 	Label                  string `json:"Label"`
@@ -0,0 +1,70 @@
+// cache stats as prometheus metrics, label cache by name
+// e.g. prometheus.MustRegister(cachestat.NewStatsCollector("dig", digCache))
+
+package cachestat
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/itoolkits/toolkit/collect"
+)
+
+const cacheMetricsSubsystem = "cache"
+
+// StatsProvider - cache with stats, e.g. *collect.Cache[K, V]
+type StatsProvider interface {
+	Stats() collect.CacheStats
+}
+
+type StatsCollector struct {
+	cache StatsProvider
+
+	entries     *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	evictions   *prometheus.Desc
+	expirations *prometheus.Desc
+	loads       *prometheus.Desc
+	loadErrors  *prometheus.Desc
+}
+
+// NewStatsCollector - create prometheus collector of cache stats, name as const label cache
+func NewStatsCollector(name string, cache StatsProvider) *StatsCollector {
+	labels := prometheus.Labels{"cache": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("", cacheMetricsSubsystem, metric), help, nil, labels)
+	}
+	return &StatsCollector{
+		cache:       cache,
+		entries:     desc("entries", "Entry num of cache."),
+		hits:        desc("hits_total", "Get hit num of cache."),
+		misses:      desc("misses_total", "Get miss num of cache."),
+		evictions:   desc("evictions_total", "Entry num evicted by max entries."),
+		expirations: desc("expirations_total", "Entry num removed by ttl."),
+		loads:       desc("loads_total", "Loader call num."),
+		loadErrors:  desc("load_errors_total", "Loader call num returned error."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.expirations
+	ch <- c.loads
+	ch <- c.loadErrors
+}
+
+// Collect implements prometheus.Collector.
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.Expirations))
+	ch <- prometheus.MustNewConstMetric(c.loads, prometheus.CounterValue, float64(stats.Loads))
+	ch <- prometheus.MustNewConstMetric(c.loadErrors, prometheus.CounterValue, float64(stats.LoadErrors))
+}
@@ -0,0 +1,45 @@
+// slog handler, add trace id and span id of context into record
+
+package tracet
+
+import (
+	"context"
+	"log/slog"
+)
+
+const (
+	LogTraceID = "trace_id"
+	LogSpanID  = "span_id"
+)
+
+type Handler struct {
+	slog.Handler
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler - wrap handler, use InfoContext/ErrorContext... to log trace ids
+func NewHandler(h slog.Handler) *Handler {
+	if th, ok := h.(*Handler); ok {
+		return th
+	}
+	return &Handler{Handler: h}
+}
+
+// Handle implements slog.Handler
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := FromContext(ctx); sc != nil {
+		r.AddAttrs(slog.String(LogTraceID, sc.TraceID), slog.String(LogSpanID, sc.SpanID))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
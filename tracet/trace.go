@@ -0,0 +1,206 @@
+// w3c trace context, traceparent/tracestate parse, emit and propagate
+// traceparent: VERSION-TRACEID-PARENTID-FLAGS, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+
+package tracet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+
+	traceVersion   = "00"
+	flagSampled    = 0x01
+	traceParentLen = 55
+	maxTraceState  = 512
+)
+
+type traceKey struct{}
+
+type SpanContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Flags        byte
+	TraceState   string
+}
+
+// Sampled - check sampled flag
+func (s *SpanContext) Sampled() bool {
+	return s.Flags&flagSampled != 0
+}
+
+// TraceParent - format traceparent header value
+func (s *SpanContext) TraceParent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceVersion, s.TraceID, s.SpanID, s.Flags)
+}
+
+// Child - new span context in same trace, parent is current span
+func (s *SpanContext) Child() *SpanContext {
+	return &SpanContext{
+		TraceID:      s.TraceID,
+		SpanID:       NewSpanID(),
+		ParentSpanID: s.SpanID,
+		Flags:        s.Flags,
+		TraceState:   s.TraceState,
+	}
+}
+
+// NewRoot - new span context of new trace
+func NewRoot(sampled bool) *SpanContext {
+	s := &SpanContext{
+		TraceID: NewTraceID(),
+		SpanID:  NewSpanID(),
+	}
+	if sampled {
+		s.Flags = flagSampled
+	}
+	return s
+}
+
+// ParseTraceParent - parse traceparent header value, span id of result is remote parent span id
+func ParseTraceParent(v string) (*SpanContext, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < traceParentLen {
+		return nil, fmt.Errorf("traceparent length error")
+	}
+	parts := strings.Split(v[:traceParentLen], "-")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("traceparent format error")
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return nil, fmt.Errorf("traceparent version error")
+	}
+	// version 00 has fixed length, future version may append fields
+	if version == traceVersion && len(v) != traceParentLen {
+		return nil, fmt.Errorf("traceparent length error")
+	}
+	if len(v) > traceParentLen && v[traceParentLen] != '-' {
+		return nil, fmt.Errorf("traceparent format error")
+	}
+	if len(traceID) != 32 || !isLowerHex(traceID) || isZero(traceID) {
+		return nil, fmt.Errorf("trace id error")
+	}
+	if len(spanID) != 16 || !isLowerHex(spanID) || isZero(spanID) {
+		return nil, fmt.Errorf("parent id error")
+	}
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return nil, fmt.Errorf("trace flags error")
+	}
+	fb, _ := hex.DecodeString(flags)
+	return &SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Flags:   fb[0],
+	}, nil
+}
+
+// Extract - extract remote span context from header, nil when absent or invalid
+func Extract(header http.Header) *SpanContext {
+	sc, err := ParseTraceParent(header.Get(HeaderTraceParent))
+	if err != nil {
+		return nil
+	}
+	state := strings.Join(header.Values(HeaderTraceState), ",")
+	if len(state) <= maxTraceState {
+		sc.TraceState = state
+	}
+	return sc
+}
+
+// Inject - set traceparent/tracestate header from span context of ctx
+func Inject(ctx context.Context, header http.Header) {
+	sc := FromContext(ctx)
+	if sc == nil {
+		return
+	}
+	header.Set(HeaderTraceParent, sc.TraceParent())
+	if sc.TraceState != "" {
+		header.Set(HeaderTraceState, sc.TraceState)
+	}
+}
+
+// NewContext - context with span context
+func NewContext(ctx context.Context, sc *SpanContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, sc)
+}
+
+// FromContext - span context of ctx, nil when absent
+func FromContext(ctx context.Context) *SpanContext {
+	if ctx == nil {
+		return nil
+	}
+	sc, _ := ctx.Value(traceKey{}).(*SpanContext)
+	return sc
+}
+
+// NewTraceID - random 16 bytes trace id
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID - random 8 bytes span id
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+// randomHex - random non-zero hex string of n bytes
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		_, _ = rand.Read(b)
+		s := hex.EncodeToString(b)
+		if !isZero(s) {
+			return s
+		}
+	}
+}
+
+// isLowerHex - check lowercase hex string
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZero - check all zero
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+type Span struct {
+	SpanContext
+
+	Name       string
+	Kind       string // server, client
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]any
+	Error      string
+}
+
+type SpanExporter interface {
+	// ExportSpan export finished span, e.g. bridge to opentelemetry sdk, must not block
+	ExportSpan(span *Span)
+}
+
+// ExporterFunc - func as SpanExporter
+type ExporterFunc func(span *Span)
+
+// ExportSpan implements SpanExporter
+func (f ExporterFunc) ExportSpan(span *Span) {
+	f(span)
+}